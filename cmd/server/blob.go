@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/blob/filesystem"
+	"github.com/x0tf/server/internal/blob/s3"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+)
+
+// openBlobStore opens the blob store specified by the scheme of the given DSN
+func openBlobStore(dsn string) (shared.BlobStore, error) {
+	switch {
+	case strings.HasPrefix(dsn, "file://"):
+		return filesystem.NewStore(strings.TrimPrefix(dsn, "file://"))
+	case strings.HasPrefix(dsn, "s3://"):
+		config, err := s3.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return s3.NewStore(config)
+	default:
+		return nil, fmt.Errorf("unsupported blob store DSN '%s' (expected 'file://<directory>' or 's3://<access key>:<secret key>@<host>/<bucket>')", dsn)
+	}
+}
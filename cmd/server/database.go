@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/database/migration"
+	"github.com/x0tf/server/internal/database/postgres"
+	"github.com/x0tf/server/internal/database/sqlite"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+)
+
+// store represents a storage backend
+type store interface {
+	shared.Transactor
+	Services() *shared.Services
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// databaseDriver determines the database driver to use out of the scheme of the given DSN and returns it alongside the driver-specific target
+func databaseDriver(dsn string) (string, string) {
+	switch {
+	case strings.HasPrefix(dsn, "memory://"):
+		return "memory", strings.TrimPrefix(dsn, "memory://")
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return "sqlite", strings.TrimPrefix(dsn, "sqlite://")
+	default:
+		return "postgres", dsn
+	}
+}
+
+// openStore opens the storage backend of the database driver specified by the given DSN
+func openStore(dsn string) (store, error) {
+	driver, target := databaseDriver(dsn)
+	switch driver {
+	case "memory":
+		log.Warn("The in-memory database driver is used; no data will be persisted.")
+		return memory.NewStore(), nil
+	case "sqlite":
+		return sqlite.NewStore(target)
+	default:
+		return postgres.NewStore(target)
+	}
+}
+
+// prepareSchema applies every pending migration and refuses to continue if the schema is newer than the supported one
+func prepareSchema(st store) error {
+	driver, ok := st.(migration.Driver)
+	if !ok {
+		return nil
+	}
+
+	applied, err := migration.Up(driver)
+	for _, appliedMigration := range applied {
+		log.WithFields(log.Fields{
+			"version":     appliedMigration.Version,
+			"description": appliedMigration.Description,
+		}).Info("Applied a database schema migration")
+	}
+	return err
+}
+
+// runMigrateCommand handles the 'migrate [up|status]' subcommand
+func runMigrateCommand(st store, args []string) {
+	driver, ok := st.(migration.Driver)
+	if !ok {
+		log.Info("The in-memory database driver does not use a schema; there is nothing to migrate.")
+		return
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "up":
+		if err := prepareSchema(st); err != nil {
+			log.Fatal(err)
+		}
+		log.WithField("version", migration.Latest(driver)).Info("The database schema is up to date")
+	case "status":
+		current, err := driver.SchemaVersion()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("current schema version: %d\n", current)
+		fmt.Printf("latest schema version:  %d\n", migration.Latest(driver))
+		for _, pending := range driver.Migrations() {
+			if pending.Version > current {
+				fmt.Printf("pending: %d (%s)\n", pending.Version, pending.Description)
+			}
+		}
+	default:
+		log.WithField("action", action).Fatal("Unknown migrate action (expected 'up' or 'status')")
+	}
+}
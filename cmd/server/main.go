@@ -1,15 +1,30 @@
 package main
 
 import (
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/access"
+	"github.com/x0tf/server/internal/analytics"
 	"github.com/x0tf/server/internal/api"
+	"github.com/x0tf/server/internal/blob"
 	"github.com/x0tf/server/internal/config"
+	"github.com/x0tf/server/internal/database/migration"
 	"github.com/x0tf/server/internal/database/postgres"
 	"github.com/x0tf/server/internal/gateway"
+	"github.com/x0tf/server/internal/health"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/metrics"
+	"github.com/x0tf/server/internal/reaper"
+	"github.com/x0tf/server/internal/render"
+	"github.com/x0tf/server/internal/shared"
 	"github.com/x0tf/server/internal/static"
+	"github.com/x0tf/server/internal/token"
+	"github.com/x0tf/server/internal/webhook"
+	"html/template"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -19,51 +34,137 @@ func main() {
 		log.Info("NOTE: No .env file was found. This is no error and the application will use the systems environment variables.")
 	}
 
-	// Initialize the namespace service
-	namespaces, err := postgres.NewNamespaceService(cfg.DatabaseDSN)
+	// Digest the tokens using the configured secret key
+	if cfg.TokenDigestKey == "" {
+		log.Warn("No token digest key is configured; tokens will be digested without a secret key.")
+	}
+	token.SetDigestKey([]byte(cfg.TokenDigestKey))
+
+	// Handle the digest subcommand, which prints the digest of a token to configure it without keeping it in plain text
+	if len(os.Args) > 1 && os.Args[1] == "digest" {
+		if len(os.Args) != 3 || os.Args[2] == "" {
+			log.Fatal("Expected exactly one token to digest")
+		}
+		fmt.Println(token.Digest(os.Args[2]))
+		return
+	}
+
+	// Open the storage backend specified by the DSN scheme
+	st, err := openStore(cfg.DatabaseDSN)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err = namespaces.InitializeTable(); err != nil {
+	defer st.Close()
+
+	// Handle the migrate subcommand
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			log.WithField("command", os.Args[1]).Fatal("Unknown command (expected 'migrate' or 'digest')")
+		}
+		runMigrateCommand(st, os.Args[2:])
+		return
+	}
+
+	// Bring the database schema up to date
+	if err = prepareSchema(st); err != nil {
 		log.Fatal(err)
 	}
-	defer namespaces.Close()
 
-	// Initialize the element service
-	elements, err := postgres.NewElementService(cfg.DatabaseDSN)
+	// Open the blob store if one is configured
+	var blobs shared.BlobStore
+	if cfg.BlobStoreDSN != "" {
+		if blobs, err = openBlobStore(cfg.BlobStoreDSN); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Offload large element payloads into the blob store if one is configured
+	services := st.Services()
+	var transactor shared.Transactor = st
+	if blobs != nil {
+		services.Elements = blob.NewOffloadingElementService(services.Elements, blobs, cfg.BlobInlineLimit)
+		transactor = blob.NewOffloadingTransactor(st, blobs, cfg.BlobInlineLimit)
+	}
+
+	// Only expose the invite service if invites are activated
+	var invites shared.InviteService
+	if cfg.Invites {
+		invites = services.Invites
+	}
+
+	// Start up the hit recorder if analytics are activated
+	var hits *analytics.Recorder
+	var hitService shared.HitService
+	if cfg.Analytics {
+		hits = &analytics.Recorder{
+			BufferSize: cfg.AnalyticsBufferSize,
+			Hits:       services.Hits,
+		}
+		if cfg.AnalyticsGeoIPDatabase != "" {
+			if hits.GeoIP, err = analytics.OpenGeoIP(cfg.AnalyticsGeoIPDatabase); err != nil {
+				log.Fatal(err)
+			}
+			defer hits.GeoIP.Close()
+		}
+		hits.Start()
+		hitService = services.Hits
+	}
+
+	// Parse the reverse proxies whose forwarding headers are trusted
+	proxies, err := logging.ParseTrustedProxies(cfg.TrustedProxies)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err = elements.InitializeTable(); err != nil {
-		log.Fatal(err)
+
+	// Check the reachability and schema version of the database for the readiness probes
+	checker := &health.Checker{
+		Checks: []*health.Check{health.PingCheck("database", st)},
+	}
+	if driver, ok := st.(migration.Driver); ok {
+		checker.Checks = append(checker.Checks, health.SchemaCheck(driver))
 	}
-	defer elements.Close()
 
-	// Initialize the invite service if invites are activated
-	var invites *postgres.InviteService
-	if cfg.Invites {
-		invites, err = postgres.NewInviteService(cfg.DatabaseDSN)
-		if err != nil {
-			log.Fatal(err)
+	// Start up the metrics server if one is configured
+	var metricsServer *metrics.Server
+	if cfg.MetricsAddress != "" {
+		if pg, ok := st.(*postgres.Store); ok {
+			metrics.RegisterPool(pg.Stat)
 		}
-		if err = invites.InitializeTable(); err != nil {
-			log.Fatal(err)
+		metricsServer = &metrics.Server{
+			Address: cfg.MetricsAddress,
 		}
-		defer invites.Close()
+		go func() {
+			if err := metricsServer.Serve(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	// Enqueue the deliveries of lifecycle events to the subscribed webhooks
+	notifier := &webhook.Notifier{
+		Webhooks: services.Webhooks,
 	}
 
 	// Start up the REST API
 	restApi := &api.API{
-		Address:     cfg.APIAddress,
-		Production:  static.ApplicationMode == "PROD",
-		Version:     static.ApplicationVersion,
-		Namespaces:  namespaces,
-		Elements:    elements,
-		Invites:     invites,
-		AdminTokens: cfg.AdminTokens,
-	}
-	if invites == nil {
-		restApi.Invites = nil
+		Address:        cfg.APIAddress,
+		Production:     static.ApplicationMode == "PROD",
+		Version:        static.ApplicationVersion,
+		Namespaces:     services.Namespaces,
+		Elements:       services.Elements,
+		Invites:        invites,
+		Transactor:     transactor,
+		Blobs:          blobs,
+		Hits:           hitService,
+		Tokens:         services.Tokens,
+		BodyLimit:      cfg.APIBodyLimit,
+		AdminTokens:    services.AdminTokens,
+		AdminDigests:   adminDigests(cfg.AdminTokens),
+		Audit:          services.Audit,
+		Webhooks:       services.Webhooks,
+		Notifier:       notifier,
+		Health:         checker,
+		TrustedProxies: proxies,
 	}
 	go func() {
 		if err := restApi.Serve(); err != nil {
@@ -71,13 +172,37 @@ func main() {
 		}
 	}()
 
+	// Load the custom markdown page template if one is configured
+	var markdownTemplate *template.Template
+	if cfg.GatewayMarkdownTemplate != "" {
+		if markdownTemplate, err = render.LoadMarkdownTemplate(cfg.GatewayMarkdownTemplate); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Start up the access tracker recording the gateway accesses in batches
+	accesses := &access.Tracker{
+		Interval:   cfg.AccessFlushInterval,
+		Namespaces: services.Namespaces,
+		Elements:   services.Elements,
+		Notifier:   notifier,
+	}
+	accesses.Start()
+
 	// Start up the gateway
 	gw := &gateway.Gateway{
-		Address:      cfg.GatewayAddress,
-		Production:   static.ApplicationMode == "PROD",
-		Namespaces:   namespaces,
-		Elements:     elements,
-		RootRedirect: cfg.GatewayRootRedirect,
+		Address:          cfg.GatewayAddress,
+		Production:       static.ApplicationMode == "PROD",
+		Namespaces:       services.Namespaces,
+		Elements:         services.Elements,
+		Blobs:            blobs,
+		Accesses:         accesses,
+		Hits:             hits,
+		Notifier:         notifier,
+		MarkdownTemplate: markdownTemplate,
+		RootRedirect:     cfg.GatewayRootRedirect,
+		Health:           checker,
+		TrustedProxies:   proxies,
 	}
 	go func() {
 		if err := gw.Serve(); err != nil {
@@ -85,11 +210,38 @@ func main() {
 		}
 	}()
 
+	// Start up the reaper purging expired elements and sweeping unreferenced blobs
+	rp := &reaper.Reaper{
+		Interval:      cfg.ReaperInterval,
+		SweepInterval: cfg.BlobSweepInterval,
+		Elements:      services.Elements,
+		Blobs:         blobs,
+		Notifier:      notifier,
+	}
+	rp.Start()
+
+	// Start up the dispatcher delivering the enqueued webhook events
+	dispatcher := &webhook.Dispatcher{
+		Interval:    cfg.WebhookInterval,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		RetryDelay:  cfg.WebhookRetryDelay,
+		Client:      webhook.NewClient(cfg.WebhookTimeout, cfg.WebhookPrivateNetworks),
+		Webhooks:    services.Webhooks,
+	}
+	dispatcher.Start()
+
 	// Wait for the program to exit
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
 	<-sc
 
+	// Report not to be ready anymore and give the load balancers some time to notice it
+	checker.Drain()
+	if cfg.ShutdownDrainDelay > 0 {
+		log.WithField("delay", cfg.ShutdownDrainDelay).Info("Draining before shutting down")
+		time.Sleep(cfg.ShutdownDrainDelay)
+	}
+
 	// Gracefully shut down the REST API
 	if err := restApi.Shutdown(); err != nil {
 		log.Error(err)
@@ -99,4 +251,32 @@ func main() {
 	if err := gw.Shutdown(); err != nil {
 		log.Error(err)
 	}
+
+	// Gracefully shut down the metrics server
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(); err != nil {
+			log.Error(err)
+		}
+	}
+
+	// Stop the reaper and record the pending accesses and hits
+	rp.Stop()
+	accesses.Stop()
+	dispatcher.Stop()
+	if hits != nil {
+		hits.Stop()
+	}
+}
+
+// adminDigests digests the configured admin tokens so that none of them is kept in plain text; already digested ones are kept as they are
+func adminDigests(adminTokens []string) []string {
+	digests := make([]string, 0, len(adminTokens))
+	for _, adminToken := range adminTokens {
+		if token.IsDigest(adminToken) {
+			digests = append(digests, adminToken)
+		} else {
+			digests = append(digests, token.Digest(adminToken))
+		}
+	}
+	return digests
 }
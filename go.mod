@@ -3,13 +3,18 @@ module github.com/x0tf/server
 go 1.15
 
 require (
+	github.com/alecthomas/chroma v0.9.4
 	github.com/alexedwards/argon2id v0.0.0-20201228115903-cf543ebc1f7b
 	github.com/gofiber/fiber/v2 v2.5.0
+	github.com/jackc/pgconn v1.8.0
 	github.com/jackc/pgproto3/v2 v2.0.7 // indirect
 	github.com/jackc/pgx/v4 v4.10.1
 	github.com/joho/godotenv v1.3.0
-	github.com/pkg/errors v0.9.1 // indirect
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/oschwald/maxminddb-golang v1.8.0
+	github.com/prometheus/client_golang v1.11.0
 	github.com/sirupsen/logrus v1.8.0
+	github.com/yuin/goldmark v1.4.0
 	golang.org/x/text v0.3.5 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
@@ -0,0 +1,132 @@
+package access
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"sync"
+	"time"
+)
+
+// elementID represents the primary key of an accessed element
+type elementID struct {
+	namespace string
+	key       string
+}
+
+// Tracker represents the background worker collecting element accesses and recording them in batches;
+// only the latest access of every element within an interval gets written to avoid a database write per request
+type Tracker struct {
+	mu         sync.Mutex
+	pending    map[elementID]time.Time
+	stop       chan struct{}
+	done       chan struct{}
+	Interval   time.Duration
+	Namespaces shared.NamespaceService
+	Elements   shared.ElementService
+	Notifier   *webhook.Notifier
+}
+
+// Start starts the tracker in a background goroutine
+func (tracker *Tracker) Start() {
+	tracker.stop = make(chan struct{})
+	tracker.done = make(chan struct{})
+	go tracker.run()
+	log.WithField("interval", tracker.Interval).Info("Started the access tracker")
+}
+
+// Stop stops the tracker and records the accesses which are still pending
+func (tracker *Tracker) Stop() {
+	log.Info("Stopping the access tracker")
+	close(tracker.stop)
+	<-tracker.done
+}
+
+// Record notes an access of an element which gets written with the next batch
+func (tracker *Tracker) Record(namespace, key string) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.pending == nil {
+		tracker.pending = make(map[elementID]time.Time)
+	}
+	tracker.pending[elementID{namespace: namespace, key: key}] = time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// requeue notes accesses which could not be written so they get written with the next batch unless later ones were recorded meanwhile
+func (tracker *Tracker) requeue(accesses []*shared.Access) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.pending == nil {
+		tracker.pending = make(map[elementID]time.Time)
+	}
+	for _, access := range accesses {
+		id := elementID{namespace: access.Namespace, key: access.Key}
+		if accessedAt, ok := tracker.pending[id]; !ok || accessedAt.Before(access.AccessedAt) {
+			tracker.pending[id] = access.AccessedAt
+		}
+	}
+}
+
+// run writes the pending accesses every interval until the tracker gets stopped
+func (tracker *Tracker) run() {
+	defer close(tracker.done)
+
+	ticker := time.NewTicker(tracker.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tracker.stop:
+			tracker.flush()
+			return
+		case <-ticker.C:
+			tracker.flush()
+		}
+	}
+}
+
+// flush writes the pending accesses to the elements and their namespaces
+func (tracker *Tracker) flush() {
+	tracker.mu.Lock()
+	pending := tracker.pending
+	tracker.pending = nil
+	tracker.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	accesses := make([]*shared.Access, 0, len(pending))
+	for id, accessedAt := range pending {
+		accesses = append(accesses, &shared.Access{
+			Namespace:  id.namespace,
+			Key:        id.key,
+			AccessedAt: accessedAt,
+		})
+	}
+	// Retry the accesses with the next batch if they could not be written, as the first accesses among them are only known once they are
+	first, err := tracker.Elements.Touch(accesses)
+	if err != nil {
+		log.WithError(err).WithField("amount", len(accesses)).Error("Could not record the accesses of elements")
+		tracker.requeue(accesses)
+		return
+	}
+	tracker.notifyFirst(first)
+	if err := tracker.Namespaces.Touch(accesses); err != nil {
+		log.WithError(err).WithField("amount", len(accesses)).Error("Could not record the accesses of namespaces")
+	}
+}
+
+// notifyFirst enqueues the deliveries of the first accesses of elements to the webhooks of their namespaces
+func (tracker *Tracker) notifyFirst(first []*shared.Access) {
+	for _, access := range first {
+		err := tracker.Notifier.Notify(access.Namespace, shared.WebhookElementFirstAccessed, &webhook.Access{
+			Key:        access.Key,
+			AccessedAt: access.AccessedAt,
+		})
+		if err != nil {
+			log.WithError(err).WithField("namespace", access.Namespace).Error("Could not enqueue the deliveries of a webhook event")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package analytics
+
+import (
+	"github.com/oschwald/maxminddb-golang"
+	"net"
+)
+
+// GeoIP represents a local MaxMind compatible database resolving IP addresses to countries
+type GeoIP struct {
+	reader *maxminddb.Reader
+}
+
+// geoIPRecord represents the part of a database record containing the country
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// OpenGeoIP opens a local GeoIP database file like GeoLite2-Country.mmdb
+func OpenGeoIP(path string) (*GeoIP, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIP{
+		reader: reader,
+	}, nil
+}
+
+// Country resolves the ISO code of the country an IP address belongs to; it returns an empty string if it is unknown
+func (geoIP *GeoIP) Country(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	var record geoIPRecord
+	if err := geoIP.reader.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+// Close closes the GeoIP database file
+func (geoIP *GeoIP) Close() error {
+	return geoIP.reader.Close()
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/shared"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// batchSize represents the maximum amount of hits which get written at once
+	batchSize = 500
+
+	// flushInterval represents the maximum time a hit is kept in memory before it gets written
+	flushInterval = 5 * time.Second
+)
+
+// event represents a raw hit which still needs to be classified
+type event struct {
+	namespace string
+	key       string
+	hitAt     time.Time
+	ip        string
+	referrer  string
+	userAgent string
+}
+
+// Recorder represents the background worker recording gateway hits asynchronously;
+// hits are handed over through a buffered channel and dropped if the buffer is full to never slow down the gateway
+type Recorder struct {
+	events     chan *event
+	dropped    uint64
+	stop       chan struct{}
+	done       chan struct{}
+	BufferSize int
+	Hits       shared.HitService
+	GeoIP      *GeoIP
+}
+
+// Start starts the recorder in a background goroutine
+func (recorder *Recorder) Start() {
+	recorder.events = make(chan *event, recorder.BufferSize)
+	recorder.stop = make(chan struct{})
+	recorder.done = make(chan struct{})
+	go recorder.run()
+	log.WithField("buffer_size", recorder.BufferSize).Info("Started the hit recorder")
+}
+
+// Stop stops the recorder and writes the hits which are still buffered
+func (recorder *Recorder) Stop() {
+	log.Info("Stopping the hit recorder")
+	close(recorder.stop)
+	<-recorder.done
+}
+
+// Record hands a hit over to the recorder without blocking
+func (recorder *Recorder) Record(namespace, key, ip, referrer, userAgent string) {
+	select {
+	case recorder.events <- &event{
+		namespace: namespace,
+		key:       key,
+		hitAt:     time.Now().UTC().Truncate(time.Microsecond),
+		ip:        ip,
+		referrer:  referrer,
+		userAgent: userAgent,
+	}:
+	default:
+		atomic.AddUint64(&recorder.dropped, 1)
+	}
+}
+
+// run classifies the received hits and writes them in batches until the recorder gets stopped
+func (recorder *Recorder) run() {
+	defer close(recorder.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*shared.Hit
+	for {
+		select {
+		case <-recorder.stop:
+			// Drain the buffer before writing the last batch
+			for {
+				select {
+				case event := <-recorder.events:
+					batch = append(batch, recorder.classify(event))
+				default:
+					recorder.write(batch)
+					return
+				}
+			}
+		case event := <-recorder.events:
+			batch = append(batch, recorder.classify(event))
+			if len(batch) >= batchSize {
+				recorder.write(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			recorder.write(batch)
+			batch = nil
+		}
+	}
+}
+
+// classify converts a raw hit into a hit which only contains the derived, non-identifying values
+func (recorder *Recorder) classify(event *event) *shared.Hit {
+	hit := &shared.Hit{
+		Namespace:      event.namespace,
+		Key:            event.key,
+		HitAt:          event.hitAt,
+		ReferrerHost:   referrerHost(event.referrer),
+		UserAgentClass: ClassifyUserAgent(event.userAgent),
+	}
+	if recorder.GeoIP != nil {
+		hit.Country = recorder.GeoIP.Country(net.ParseIP(event.ip))
+	}
+	return hit
+}
+
+// write writes a batch of hits and reports the hits which were dropped since the last write
+func (recorder *Recorder) write(batch []*shared.Hit) {
+	if dropped := atomic.SwapUint64(&recorder.dropped, 0); dropped > 0 {
+		log.WithField("amount", dropped).Warn("Dropped hits because the hit buffer was full")
+	}
+	if len(batch) == 0 {
+		return
+	}
+	for i := 0; i < len(batch); i += batchSize {
+		end := i + batchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		if err := recorder.Hits.Record(batch[i:end]); err != nil {
+			log.WithError(err).WithField("amount", end-i).Error("Could not record hits")
+		}
+	}
+}
+
+// referrerHost extracts the lowercase host out of a referrer URL; it returns an empty string for direct hits
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if len(host) > 255 {
+		host = host[:255]
+	}
+	return host
+}
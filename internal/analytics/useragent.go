@@ -0,0 +1,43 @@
+package analytics
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+)
+
+// botMarkers contains lowercase user agent fragments identifying crawlers, link previews and monitoring services
+var botMarkers = []string{"bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit", "monitor", "headless", "lighthouse"}
+
+// cliMarkers contains lowercase user agent fragments identifying command line tools and HTTP libraries
+var cliMarkers = []string{"curl/", "wget/", "httpie/", "python-requests/", "python-urllib/", "go-http-client/", "okhttp/", "java/", "libwww-perl/", "powershell/", "axios/", "node-fetch/"}
+
+// mobileMarkers contains lowercase user agent fragments identifying browsers on mobile devices
+var mobileMarkers = []string{"mobile", "android", "iphone", "ipad", "ipod"}
+
+// ClassifyUserAgent determines the kind of client sending the given user agent
+func ClassifyUserAgent(userAgent string) shared.UserAgentClass {
+	lower := strings.ToLower(userAgent)
+	switch {
+	case containsAny(lower, botMarkers):
+		return shared.UserAgentBot
+	case containsAny(lower, cliMarkers):
+		return shared.UserAgentCLI
+	case strings.HasPrefix(lower, "mozilla/") || strings.HasPrefix(lower, "opera/"):
+		if containsAny(lower, mobileMarkers) {
+			return shared.UserAgentMobile
+		}
+		return shared.UserAgentBrowser
+	default:
+		return shared.UserAgentOther
+	}
+}
+
+// containsAny checks whether the given string contains at least one of the given fragments
+func containsAny(value string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(value, fragment) {
+			return true
+		}
+	}
+	return false
+}
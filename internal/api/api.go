@@ -4,33 +4,54 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	recov "github.com/gofiber/fiber/v2/middleware/recover"
 	log "github.com/sirupsen/logrus"
 	v1 "github.com/x0tf/server/internal/api/v1"
+	"github.com/x0tf/server/internal/health"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/metrics"
 	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"time"
 )
 
 // API represents the REST API
 type API struct {
-	app         *fiber.App
-	Address     string
-	Production  bool
-	Version     string
-	AdminTokens []string
-	Namespaces  shared.NamespaceService
-	Elements    shared.ElementService
-	Invites     shared.InviteService
+	app            *fiber.App
+	Address        string
+	Production     bool
+	Version        string
+	AdminTokens    shared.AdminTokenService
+	AdminDigests   []string
+	Audit          shared.AuditService
+	Namespaces     shared.NamespaceService
+	Elements       shared.ElementService
+	Invites        shared.InviteService
+	Transactor     shared.Transactor
+	Blobs          shared.BlobStore
+	Hits           shared.HitService
+	Tokens         shared.NamespaceTokenService
+	Webhooks       shared.WebhookService
+	Notifier       *webhook.Notifier
+	BodyLimit      int
+	Health         *health.Checker
+	TrustedProxies logging.TrustedProxies
 }
 
 // Serve serves the REST API
 func (api *API) Serve() error {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: api.Production,
+		Immutable:             true,
+		BodyLimit:             api.BodyLimit,
 		ErrorHandler:          errorHandler,
 	})
 
+	// Log and observe every handled request
+	app.Use(logging.Middleware("api", api.TrustedProxies))
+	app.Use(metrics.Middleware("api"))
+
 	// Include CORS response headers
 	app.Use(cors.New(cors.Config{
 		Next:             nil,
@@ -38,7 +59,7 @@ func (api *API) Serve() error {
 		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH",
 		AllowHeaders:     "",
 		AllowCredentials: true,
-		ExposeHeaders:    "",
+		ExposeHeaders:    "ETag, X-Next-Cursor, X-Request-ID",
 		MaxAge:           0,
 	}))
 
@@ -47,21 +68,32 @@ func (api *API) Serve() error {
 
 	// Inject debug middlewares if the application runs in development mode
 	if !api.Production {
-		app.Use(logger.New())
 		app.Use(pprof.New())
 	}
 
+	// Register the health endpoints in front of the rate limiter so probes never get rejected
+	if api.Health != nil {
+		app.Get("/healthz", health.Liveness)
+		app.Get("/readyz", api.Health.Readiness)
+	}
+
 	// Inject the rate limiter middleware
 	app.Use(limiter.New(limiter.Config{
 		Next: func(_ *fiber.Ctx) bool {
 			return !api.Production
 		},
-		Max: 60,
+		Max:          60,
+		KeyGenerator: logging.ClientIP,
 		LimitReached: func(ctx *fiber.Ctx) error {
+			metrics.CountLimiterRejection("api")
 			return fiber.ErrTooManyRequests
 		},
 	}))
 
+	// Allow four concurrent verifications of legacy token hashes but only one per namespace so a single namespace
+	// cannot starve the others and remember rejected tokens for ten minutes
+	legacyTokens := v1.NewLegacyTokenGuard(4, 1, 10*time.Minute, 10000)
+
 	// Inject the application data
 	app.Use(func(ctx *fiber.Ctx) error {
 		ctx.Locals("__production", api.Production)
@@ -71,7 +103,20 @@ func (api *API) Serve() error {
 		if api.Invites != nil {
 			ctx.Locals("__invites", api.Invites)
 		}
+		ctx.Locals("__transactor", api.Transactor)
+		if api.Blobs != nil {
+			ctx.Locals("__blobs", api.Blobs)
+		}
+		if api.Hits != nil {
+			ctx.Locals("__hits", api.Hits)
+		}
+		ctx.Locals("__tokens", api.Tokens)
+		ctx.Locals("__legacy_tokens", legacyTokens)
 		ctx.Locals("__admin_tokens", api.AdminTokens)
+		ctx.Locals("__admin_token_digests", api.AdminDigests)
+		ctx.Locals("__audit", api.Audit)
+		ctx.Locals("__webhooks", api.Webhooks)
+		ctx.Locals("__notifier", api.Notifier)
 		return ctx.Next()
 	})
 
@@ -88,22 +133,61 @@ func (api *API) Serve() error {
 			v1router.Delete("/invites/:code", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointDeleteInvite)
 		}
 
+		// Register the audit log endpoints
+		v1router.Get("/audit", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointListAudit)
+		v1router.Get("/audit/export", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointExportAudit)
+
+		// Register the admin token endpoints
+		v1router.Get("/admin/tokens", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointListAdminTokens)
+		v1router.Get("/admin/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointGetAdminToken)
+		v1router.Post("/admin/tokens", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointCreateAdminToken)
+		v1router.Patch("/admin/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointPatchAdminToken)
+		v1router.Post("/admin/tokens/:token/rotate", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointRotateAdminToken)
+		v1router.Delete("/admin/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointDeleteAdminToken)
+
 		// Register the namespace endpoints
 		v1router.Get("/namespaces", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointListNamespaces)
 		v1router.Get("/namespaces/:namespace", v1.MiddlewareInjectNamespace, v1.EndpointGetNamespace)
 		v1router.Post("/namespaces/:namespace", v1.MiddlewareAdminAuth, v1.EndpointCreateNamespace)
-		v1router.Post("/namespaces/:namespace/resetToken", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointResetNamespaceToken)
+		v1router.Post("/namespaces/:namespace/resetToken", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointResetNamespaceToken)
 		v1router.Post("/namespaces/:namespace/deactivate", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.MiddlewareInjectNamespace, v1.EndpointDeactivateNamespace)
 		v1router.Post("/namespaces/:namespace/activate", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.MiddlewareInjectNamespace, v1.EndpointActivateNamespace)
-		v1router.Delete("/namespaces/:namespace", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointDeleteNamespace)
+		v1router.Get("/namespaces/:namespace/tokens", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointListNamespaceTokens)
+		v1router.Get("/namespaces/:namespace/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointGetNamespaceToken)
+		v1router.Post("/namespaces/:namespace/tokens", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointCreateNamespaceToken)
+		v1router.Patch("/namespaces/:namespace/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointPatchNamespaceToken)
+		v1router.Delete("/namespaces/:namespace/tokens/:token", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointDeleteNamespaceToken)
+		v1router.Delete("/namespaces/:namespace", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageTokens), v1.EndpointDeleteNamespace)
+
+		// Register the webhook endpoints
+		v1router.Get("/namespaces/:namespace/webhooks", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointListWebhooks)
+		v1router.Get("/namespaces/:namespace/webhooks/:webhook", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointGetWebhook)
+		v1router.Post("/namespaces/:namespace/webhooks", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointCreateWebhook)
+		v1router.Patch("/namespaces/:namespace/webhooks/:webhook", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointPatchWebhook)
+		v1router.Delete("/namespaces/:namespace/webhooks/:webhook", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointDeleteWebhook)
+		v1router.Post("/namespaces/:namespace/webhooks/:webhook/ping", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointPingWebhook)
+		v1router.Get("/namespaces/:namespace/webhooks/:webhook/deliveries", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointListWebhookDeliveries)
+		v1router.Get("/namespaces/:namespace/webhooks/:webhook/deliveries/:delivery", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointGetWebhookDelivery)
+		v1router.Post("/namespaces/:namespace/webhooks/:webhook/deliveries/:delivery/redeliver", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeManageWebhooks), v1.EndpointRedeliverWebhookDelivery)
 
 		// Register the element endpoints
 		v1router.Get("/elements", v1.MiddlewareAdminAuth, v1.MiddlewareRequireAdminAuth, v1.EndpointListElements)
-		v1router.Get("/elements/:namespace", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointListNamespaceElements)
+		v1router.Get("/elements/:namespace", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeReadElements), v1.EndpointListNamespaceElements)
 		v1router.Get("/elements/:namespace/:key", v1.MiddlewareInjectNamespace, v1.EndpointGetElement)
-		v1router.Post("/elements/:namespace/paste/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointCreatePasteElement)
-		v1router.Post("/elements/:namespace/redirect/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointCreateRedirectElement)
-		v1router.Delete("/elements/:namespace/:key", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth, v1.EndpointDeleteElement)
+		v1router.Post("/elements/:namespace/paste/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeCreatePastes), v1.EndpointCreatePasteElement)
+		v1router.Post("/elements/:namespace/markdown/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeCreatePastes), v1.EndpointCreateMarkdownElement)
+		v1router.Post("/elements/:namespace/redirect/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeCreateRedirects), v1.EndpointCreateRedirectElement)
+		if api.Blobs != nil {
+			v1router.Post("/elements/:namespace/file/:key?", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeCreatePastes), v1.EndpointCreateFileElement)
+		}
+		v1router.Patch("/elements/:namespace/:key", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(), v1.EndpointPatchElement)
+		if api.Hits != nil {
+			v1router.Get("/elements/:namespace/:key/stats", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeReadElements), v1.EndpointGetElementStats)
+		}
+		v1router.Get("/elements/:namespace/:key/revisions", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeReadElements), v1.EndpointListElementRevisions)
+		v1router.Get("/elements/:namespace/:key/revisions/:revision", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeReadElements), v1.EndpointGetElementRevision)
+		v1router.Post("/elements/:namespace/:key/revisions/:revision/restore", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(), v1.EndpointRestoreElementRevision)
+		v1router.Delete("/elements/:namespace/:key", v1.MiddlewareAdminAuth, v1.MiddlewareInjectNamespace, v1.MiddlewareTokenAuth(shared.TokenScopeDeleteElements), v1.EndpointDeleteElement)
 	}
 
 	log.WithField("address", api.Address).Info("Serving the REST API")
@@ -117,11 +201,15 @@ func (api *API) Shutdown() error {
 	return api.app.Shutdown()
 }
 
+// errorHandler responds with the message of the given error and logs it if it is an internal one
 func errorHandler(ctx *fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
 	if fiberError, ok := err.(*fiber.Error); ok {
 		code = fiberError.Code
 	}
+	if code >= fiber.StatusInternalServerError {
+		logging.WithRequest(ctx).WithError(err).Error("Could not handle a request")
+	}
 	return ctx.Status(code).JSON(fiber.Map{
 		"messages": []string{err.Error()},
 	})
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"github.com/x0tf/server/internal/utils"
+	"github.com/x0tf/server/internal/validation"
+	"strings"
+)
+
+// adminTokenPrefix represents the prefix of admin tokens stored in the database, which embed their public ID ('x0a_<id>_<secret>')
+const adminTokenPrefix = "x0a_"
+
+// createdAdminToken represents a freshly created or rotated admin token including its raw value, which is only revealed once
+type createdAdminToken struct {
+	*shared.AdminToken
+	Token string `json:"token"`
+}
+
+// EndpointListAdminTokens handles the GET /v1/admin/tokens endpoint
+func EndpointListAdminTokens(ctx *fiber.Ctx) error {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+	list, err := adminTokens.Tokens()
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []*shared.AdminToken{}
+	}
+	return ctx.JSON(list)
+}
+
+// EndpointGetAdminToken handles the GET /v1/admin/tokens/:token endpoint
+func EndpointGetAdminToken(ctx *fiber.Ctx) error {
+	adminToken, err := requestedAdminToken(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(adminToken)
+}
+
+// EndpointCreateAdminToken handles the POST /v1/admin/tokens endpoint
+func EndpointCreateAdminToken(ctx *fiber.Ctx) error {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Read and validate the token properties
+	name, ok := data["name"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token name")
+	}
+	if errors := validation.ValidateTokenName(name); len(errors) > 0 {
+		return validationErrors(ctx, errors)
+	}
+	expiresAt, err := parseExpiration(data)
+	if err != nil {
+		return err
+	}
+
+	// Generate the token embedding its ID and store its digest
+	adminToken := &shared.AdminToken{
+		ID:        utils.GenerateTokenID(),
+		Name:      strings.TrimSpace(name),
+		ExpiresAt: expiresAt,
+	}
+	raw := generateAdminToken(adminToken)
+	if err = adminTokens.CreateOrReplace(adminToken); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditAdminTokenCreate, "", adminToken.ID)
+	return ctx.JSON(&createdAdminToken{
+		AdminToken: adminToken,
+		Token:      raw,
+	})
+}
+
+// EndpointPatchAdminToken handles the PATCH /v1/admin/tokens/:token endpoint
+func EndpointPatchAdminToken(ctx *fiber.Ctx) error {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+	adminToken, err := requestedAdminToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Apply the requested changes
+	if rawName, ok := data["name"]; ok {
+		name, ok := rawName.(string)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token name")
+		}
+		if errors := validation.ValidateTokenName(name); len(errors) > 0 {
+			return validationErrors(ctx, errors)
+		}
+		adminToken.Name = strings.TrimSpace(name)
+	}
+
+	// Remove the expiration if null is given and replace it otherwise
+	if value, ok := data["expires_at"]; ok && value == nil {
+		adminToken.ExpiresAt = nil
+	} else if expiresAt, err := parseExpiration(data); err != nil {
+		return err
+	} else if expiresAt != nil {
+		adminToken.ExpiresAt = expiresAt
+	}
+
+	if err = adminTokens.CreateOrReplace(adminToken); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditAdminTokenUpdate, "", adminToken.ID)
+	return ctx.JSON(adminToken)
+}
+
+// EndpointRotateAdminToken handles the POST /v1/admin/tokens/:token/rotate endpoint
+func EndpointRotateAdminToken(ctx *fiber.Ctx) error {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+	adminToken, err := requestedAdminToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Replace the secret of the token while keeping its ID, name and expiration
+	raw := generateAdminToken(adminToken)
+	if err = adminTokens.CreateOrReplace(adminToken); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditAdminTokenRotate, "", adminToken.ID)
+	return ctx.JSON(&createdAdminToken{
+		AdminToken: adminToken,
+		Token:      raw,
+	})
+}
+
+// EndpointDeleteAdminToken handles the DELETE /v1/admin/tokens/:token endpoint
+func EndpointDeleteAdminToken(ctx *fiber.Ctx) error {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+	adminToken, err := requestedAdminToken(ctx)
+	if err != nil {
+		return err
+	}
+	if err = adminTokens.Delete(adminToken.ID); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditAdminTokenDelete, "", adminToken.ID)
+	return nil
+}
+
+// requestedAdminToken retrieves the requested admin token
+func requestedAdminToken(ctx *fiber.Ctx) (*shared.AdminToken, error) {
+	adminTokens := ctx.Locals("__admin_tokens").(shared.AdminTokenService)
+	adminToken, err := adminTokens.Token(strings.ToLower(ctx.Params("token")))
+	if err != nil {
+		return nil, err
+	}
+	if adminToken == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that token does not exist")
+	}
+	return adminToken, nil
+}
+
+// generateAdminToken generates a new raw token embedding the ID of the given admin token and stores its digest in it
+func generateAdminToken(adminToken *shared.AdminToken) string {
+	raw := adminTokenPrefix + adminToken.ID + "_" + utils.GenerateToken()
+	adminToken.Hash = token.Digest(raw)
+	return raw
+}
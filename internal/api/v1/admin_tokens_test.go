@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminAuthentication(t *testing.T) {
+	services := memory.NewStore().Services()
+	past := time.Now().Add(-time.Minute)
+	for _, adminToken := range []*shared.AdminToken{
+		{ID: "stored", Name: "stored", Hash: token.Digest(adminTokenPrefix + "stored_secret")},
+		{ID: "expired", Name: "expired", Hash: token.Digest(adminTokenPrefix + "expired_secret"), ExpiresAt: &past},
+	} {
+		if err := services.AdminTokens.CreateOrReplace(adminToken); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configured := []string{token.Digest("first-configured"), token.Digest("second-configured")}
+
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("__admin_tokens", services.AdminTokens)
+		ctx.Locals("__admin_token_digests", configured)
+		return ctx.Next()
+	})
+	app.Get("/", MiddlewareAdminAuth, func(ctx *fiber.Ctx) error {
+		if !ctx.Locals("_admin").(bool) {
+			return ctx.SendString("")
+		}
+		return ctx.SendString(ctx.Locals("_actor").(string))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		actor         string
+	}{
+		{name: "no authorization"},
+		{name: "empty bearer token", authorization: "Bearer "},
+		{name: "other scheme", authorization: "Basic first-configured"},
+		{name: "first configured token", authorization: "Bearer first-configured", actor: "admin"},
+		{name: "second configured token", authorization: "Bearer second-configured", actor: "admin"},
+		{name: "wrong token", authorization: "Bearer third-configured"},
+		{name: "digest of a configured token", authorization: "Bearer " + configured[0]},
+		{name: "stored token", authorization: "Bearer " + adminTokenPrefix + "stored_secret", actor: "admin:stored"},
+		{name: "stored token with a wrong secret", authorization: "Bearer " + adminTokenPrefix + "stored_wrong"},
+		{name: "expired stored token", authorization: "Bearer " + adminTokenPrefix + "expired_secret"},
+		{name: "unknown stored token", authorization: "Bearer " + adminTokenPrefix + "unknown_secret"},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		if test.authorization != "" {
+			request.Header.Set(fiber.HeaderAuthorization, test.authorization)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actor := string(body); actor != test.actor {
+			t.Errorf("%s: authenticated the actor %q, expected %q", test.name, actor, test.actor)
+		}
+	}
+}
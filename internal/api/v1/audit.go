@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+)
+
+// auditExportBatchSize is the amount of audit log entries an export fetches at once
+const auditExportBatchSize = 1000
+
+// EndpointListAudit handles the GET /v1/audit endpoint
+func EndpointListAudit(ctx *fiber.Ctx) error {
+	audit := ctx.Locals("__audit").(shared.AuditService)
+	query, listing, err := parseAuditQuery(ctx)
+	if err != nil {
+		return err
+	}
+	if listing.after != nil {
+		query.AfterID = listing.after.ID
+	}
+
+	// Fetch one more entry than requested to find out whether another page exists
+	query.Limit = listing.limit + 1
+	entries, err := audit.Entries(query)
+	if err != nil {
+		return err
+	}
+	entries = entries[:listing.page(ctx, len(entries), func(last int) *cursor {
+		return &cursor{ID: entries[last].ID}
+	})]
+	if entries == nil {
+		entries = []*shared.AuditEntry{}
+	}
+	return ctx.JSON(entries)
+}
+
+// EndpointExportAudit handles the GET /v1/audit/export endpoint, which streams every matching entry as a JSON line
+func EndpointExportAudit(ctx *fiber.Ctx) error {
+	audit := ctx.Locals("__audit").(shared.AuditService)
+	query, _, err := parseAuditQuery(ctx)
+	if err != nil {
+		return err
+	}
+	query.Limit = auditExportBatchSize
+
+	// Fetch the first batch right away to be able to respond with an error status if the database fails
+	entries, err := audit.Entries(query)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.WithRequest(ctx)
+	ctx.Set(fiber.HeaderContentType, "application/x-ndjson")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="audit.jsonl"`)
+	ctx.Context().SetBodyStreamWriter(func(writer *bufio.Writer) {
+		encoder := json.NewEncoder(writer)
+		for len(entries) > 0 {
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return
+				}
+			}
+			if err := writer.Flush(); err != nil || len(entries) < auditExportBatchSize {
+				return
+			}
+
+			query.AfterID = entries[len(entries)-1].ID
+			if entries, err = audit.Entries(query); err != nil {
+				logger.WithError(err).Error("Could not export the audit log")
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// parseAuditQuery parses the filter, sort and pagination query parameters of an audit log listing
+func parseAuditQuery(ctx *fiber.Ctx) (*shared.AuditQuery, *listing, error) {
+	// Entries are always sorted by their creation, so sorting by a key is not supported
+	if value := ctx.Query("sort"); value != "" && value != "created_at" && value != "-created_at" {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal sort order; use one of 'created_at' or '-created_at'")
+	}
+	listing, err := parseListing(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if listing.after != nil && listing.after.ID <= 0 {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal cursor")
+	}
+
+	return &shared.AuditQuery{
+		Actor:         ctx.Query("actor"),
+		Action:        shared.AuditAction(ctx.Query("action")),
+		Namespace:     strings.ToLower(ctx.Query("namespace")),
+		CreatedAfter:  listing.createdAfter,
+		CreatedBefore: listing.createdBefore,
+		Descending:    listing.sort.Descending(),
+	}, listing, nil
+}
+
+// recordAudit appends an entry describing an action performed by the authenticated actor to the audit log.
+// The action was already carried out at this point, so a failure is only logged instead of failing the request.
+func recordAudit(ctx *fiber.Ctx, action shared.AuditAction, namespace, target string) {
+	audit, ok := ctx.Locals("__audit").(shared.AuditService)
+	if !ok {
+		return
+	}
+	entry := &shared.AuditEntry{
+		Actor:     actor(ctx),
+		Action:    action,
+		Namespace: namespace,
+		Target:    target,
+		IP:        logging.ClientIP(ctx),
+		RequestID: logging.RequestID(ctx),
+	}
+	if entry.Actor == "" {
+		entry.Actor = "anonymous"
+	}
+	entry.ActorName, _ = ctx.Locals("_actor_name").(string)
+	if err := audit.Record(entry); err != nil {
+		logging.WithRequest(ctx).WithError(err).WithField("action", action).Error("Could not record an audit log entry")
+	}
+}
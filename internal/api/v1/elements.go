@@ -1,34 +1,76 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/blob"
+	"github.com/x0tf/server/internal/render"
 	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
 	"github.com/x0tf/server/internal/utils"
+	"github.com/x0tf/server/internal/validation"
+	"github.com/x0tf/server/internal/webhook"
+	"io"
+	"mime/multipart"
+	"net/http"
 	"net/url"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // EndpointListElements handles the GET /v1/elements endpoint
 func EndpointListElements(ctx *fiber.Ctx) error {
-	elements := ctx.Locals("__elements").(shared.ElementService)
-	list, err := elements.Elements()
-	if err != nil {
-		return err
-	}
-	if list == nil {
-		list = []*shared.Element{}
-	}
-	return ctx.JSON(list)
+	return listElements(ctx, "")
 }
 
 // EndpointListNamespaceElements handles the GET /v1/elements/:namespace endpoint
 func EndpointListNamespaceElements(ctx *fiber.Ctx) error {
 	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	return listElements(ctx, namespace.ID)
+}
+
+// listElements lists a page of the elements matching the query parameters, restricted to a namespace if one is given
+func listElements(ctx *fiber.Ctx, namespace string) error {
 	elements := ctx.Locals("__elements").(shared.ElementService)
-	list, err := elements.ElementsInNamespace(namespace.ID)
+	listing, err := parseListing(ctx)
+	if err != nil {
+		return err
+	}
+	typ, err := parseElementType(ctx)
 	if err != nil {
 		return err
 	}
+
+	// Fetch one more element than requested to find out whether another page exists
+	query := &shared.ElementQuery{
+		Namespace:     namespace,
+		Type:          typ,
+		KeyPrefix:     strings.ToLower(ctx.Query("prefix")),
+		CreatedAfter:  listing.createdAfter,
+		CreatedBefore: listing.createdBefore,
+		Sort:          listing.sort,
+		Limit:         listing.limit + 1,
+	}
+	if listing.after != nil {
+		query.After = &shared.Element{
+			Namespace: listing.after.Namespace,
+			Key:       listing.after.Key,
+			CreatedAt: listing.after.CreatedAt,
+		}
+	}
+	list, err := elements.Elements(query)
+	if err != nil {
+		return err
+	}
+	list = list[:listing.page(ctx, len(list), func(last int) *cursor {
+		return &cursor{CreatedAt: list[last].CreatedAt, Namespace: list[last].Namespace, Key: list[last].Key}
+	})]
+
 	if list == nil {
 		list = []*shared.Element{}
 	}
@@ -46,6 +88,18 @@ func EndpointGetElement(ctx *fiber.Ctx) error {
 	if element == nil {
 		return fiber.NewError(fiber.StatusNotFound, "that element does not exist")
 	}
+	if element.Expired() {
+		return fiber.NewError(fiber.StatusGone, "that element has expired")
+	}
+	if element.Exhausted() {
+		return fiber.NewError(fiber.StatusGone, "that element has no views left")
+	}
+
+	// Withhold the data of view-limited and password-protected elements as it may only be resolved through the gateway
+	if element.RemainingViews != nil || element.Protected() {
+		element.Data = ""
+	}
+	ctx.Set(fiber.HeaderETag, elementETag(element))
 	return ctx.JSON(element)
 }
 
@@ -72,38 +126,125 @@ func EndpointCreatePasteElement(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "got an illegal or empty value as paste content")
 	}
 
-	// Generate a new element key
-	key := strings.TrimSpace(strings.ToLower(ctx.Params("key")))
-	if key == "" {
-		for {
-			key = utils.GenerateElementKey()
-			found, err := elements.Element(namespace.ID, key)
-			if err != nil {
-				return err
-			}
-			if found == nil {
-				break
-			}
+	// Read and resolve the optional language hint out of the request body
+	language, err := parseLanguage(data)
+	if err != nil {
+		return err
+	}
+
+	// Read and validate the optional encryption metadata; the content has to be the ciphertext in this case
+	encryption, err := parseEncryption(data)
+	if err != nil {
+		return err
+	}
+	if encryption != nil {
+		if errors := validation.ValidateEncryption(content, encryption); len(errors) > 0 {
+			return validationErrors(ctx, errors)
 		}
 	}
-	found, err := elements.Element(namespace.ID, key)
+
+	// Read the optional expiration time and view limit out of the request body
+	expiresAt, err := parseExpiration(data)
+	if err != nil {
+		return err
+	}
+	maxViews, err := parseMaxViews(data)
+	if err != nil {
+		return err
+	}
+
+	// Hash the optional password the element should be protected with
+	passwordHash, err := parsePassword(data)
+	if err != nil {
+		return err
+	}
+
+	// Determine the key of the new element
+	key, err := elementKey(ctx, elements, namespace.ID)
 	if err != nil {
 		return err
 	}
-	if found != nil {
-		return fiber.NewError(fiber.StatusUnprocessableEntity, "the given element key is already in use")
+
+	// Create the element
+	element := &shared.Element{
+		Namespace:      namespace.ID,
+		Key:            key,
+		Type:           shared.ElementTypePaste,
+		Data:           content,
+		ExpiresAt:      expiresAt,
+		RemainingViews: maxViews,
+		PasswordHash:   passwordHash,
+		Language:       language,
+		Encryption:     encryption,
+		Actor:          actor(ctx),
+	}
+	if err = elements.CreateOrReplace(element); err != nil {
+		return err
+	}
+	notify(ctx, namespace.ID, shared.WebhookElementCreated, webhook.ElementData(element))
+	return ctx.JSON(element)
+}
+
+// EndpointCreateMarkdownElement handles the POST /v1/elements/:namespace/markdown/:key? endpoint
+func EndpointCreateMarkdownElement(ctx *fiber.Ctx) error {
+	isAdmin := ctx.Locals("_admin").(bool)
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	elements := ctx.Locals("__elements").(shared.ElementService)
+
+	// Check if the namespace is deactivated
+	if !namespace.Active && !isAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "this namespace is deactivated")
+	}
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Read and validate the markdown source out of the request body
+	content, ok := data["content"].(string)
+	if !ok || strings.TrimSpace(content) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "got an illegal or empty value as markdown content")
+	}
+
+	// Read the optional expiration time and view limit out of the request body
+	expiresAt, err := parseExpiration(data)
+	if err != nil {
+		return err
+	}
+	maxViews, err := parseMaxViews(data)
+	if err != nil {
+		return err
+	}
+
+	// Hash the optional password the element should be protected with
+	passwordHash, err := parsePassword(data)
+	if err != nil {
+		return err
+	}
+
+	// Determine the key of the new element
+	key, err := elementKey(ctx, elements, namespace.ID)
+	if err != nil {
+		return err
 	}
 
 	// Create the element
 	element := &shared.Element{
-		Namespace: namespace.ID,
-		Key:       key,
-		Type:      shared.ElementTypePaste,
-		Data:      content,
+		Namespace:      namespace.ID,
+		Key:            key,
+		Type:           shared.ElementTypeMarkdown,
+		Data:           content,
+		ExpiresAt:      expiresAt,
+		RemainingViews: maxViews,
+		PasswordHash:   passwordHash,
+		Actor:          actor(ctx),
 	}
 	if err = elements.CreateOrReplace(element); err != nil {
 		return err
 	}
+	notify(ctx, namespace.ID, shared.WebhookElementCreated, webhook.ElementData(element))
 	return ctx.JSON(element)
 }
 
@@ -125,51 +266,384 @@ func EndpointCreateRedirectElement(ctx *fiber.Ctx) error {
 	}
 
 	// Read and validate the target URL out of the request body
-	target, ok := data["target"].(string)
-	if !ok || strings.TrimSpace(target) == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "got an illegal or empty value as target URL")
+	target, err := parseTarget(data)
+	if err != nil {
+		return err
 	}
-	parsedURL, err := url.Parse(target)
+
+	// Read the optional expiration time and view limit out of the request body
+	expiresAt, err := parseExpiration(data)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
-	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "the given target URL is no http(s) url")
+	maxViews, err := parseMaxViews(data)
+	if err != nil {
+		return err
 	}
 
-	// Generate a new element key
-	key := strings.TrimSpace(strings.ToLower(ctx.Params("key")))
-	if key == "" {
-		for {
-			key = utils.GenerateElementKey()
-			found, err := elements.Element(namespace.ID, key)
+	// Hash the optional password the element should be protected with
+	passwordHash, err := parsePassword(data)
+	if err != nil {
+		return err
+	}
+
+	// Determine the key of the new element
+	key, err := elementKey(ctx, elements, namespace.ID)
+	if err != nil {
+		return err
+	}
+
+	// Create the element
+	element := &shared.Element{
+		Namespace:      namespace.ID,
+		Key:            key,
+		Type:           shared.ElementTypeRedirect,
+		Data:           target,
+		ExpiresAt:      expiresAt,
+		RemainingViews: maxViews,
+		PasswordHash:   passwordHash,
+		Actor:          actor(ctx),
+	}
+	if err = elements.CreateOrReplace(element); err != nil {
+		return err
+	}
+	notify(ctx, namespace.ID, shared.WebhookElementCreated, webhook.ElementData(element))
+	return ctx.JSON(element)
+}
+
+// EndpointCreateFileElement handles the POST /v1/elements/:namespace/file/:key? endpoint
+func EndpointCreateFileElement(ctx *fiber.Ctx) error {
+	isAdmin := ctx.Locals("_admin").(bool)
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	elements := ctx.Locals("__elements").(shared.ElementService)
+	blobs := ctx.Locals("__blobs").(shared.BlobStore)
+
+	// Check if the namespace is deactivated
+	if !namespace.Active && !isAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "this namespace is deactivated")
+	}
+
+	// Parse the multipart body and read the uploaded file out of it
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse multipart request body")
+	}
+	files := form.File["file"]
+	if len(files) != 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "expected exactly one file in the 'file' form field")
+	}
+	header := files[0]
+
+	// Read the optional expiration time and view limit out of the form values; the password is kept as typed instead of as a number
+	data := formValues(form)
+	if passwords := form.Value["password"]; len(passwords) > 0 {
+		data["password"] = passwords[0]
+	}
+	expiresAt, err := parseExpiration(data)
+	if err != nil {
+		return err
+	}
+	maxViews, err := parseMaxViews(data)
+	if err != nil {
+		return err
+	}
+
+	// Hash the optional password the element should be protected with
+	passwordHash, err := parsePassword(data)
+	if err != nil {
+		return err
+	}
+
+	// Determine the key of the new element
+	key, err := elementKey(ctx, elements, namespace.ID)
+	if err != nil {
+		return err
+	}
+
+	// Determine the content type of the file, sniffing it if the client did not provide a specific one
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	contentType := header.Header.Get(fiber.HeaderContentType)
+	if contentType == "" || contentType == fiber.MIMEOctetStream {
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(file, sniff)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		contentType = http.DetectContentType(sniff[:n])
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	// Store the file content in the blob store
+	blobID := utils.GenerateBlobID()
+	if err = blobs.Put(blobID, file, header.Size); err != nil {
+		return err
+	}
+
+	// Create the element
+	element := &shared.Element{
+		Namespace:      namespace.ID,
+		Key:            key,
+		Type:           shared.ElementTypeFile,
+		ExpiresAt:      expiresAt,
+		RemainingViews: maxViews,
+		PasswordHash:   passwordHash,
+		Blob:           blobID,
+		ContentType:    contentType,
+		Filename:       sanitizeFilename(header.Filename),
+		Size:           header.Size,
+		Actor:          actor(ctx),
+	}
+	if err = elements.CreateOrReplace(element); err != nil {
+		blob.Release(blobs, element)
+		return err
+	}
+	notify(ctx, namespace.ID, shared.WebhookElementCreated, webhook.ElementData(element))
+	return ctx.JSON(element)
+}
+
+// EndpointPatchElement handles the PATCH /v1/elements/:namespace/:key endpoint
+func EndpointPatchElement(ctx *fiber.Ctx) error {
+	isAdmin := ctx.Locals("_admin").(bool)
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	elements := ctx.Locals("__elements").(shared.ElementService)
+
+	// Check if the namespace is deactivated
+	if !namespace.Active && !isAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "this namespace is deactivated")
+	}
+
+	// Retrieve the element to update
+	element, err := elements.Element(namespace.ID, strings.ToLower(ctx.Params("key")))
+	if err != nil {
+		return err
+	}
+	if element == nil {
+		return fiber.NewError(fiber.StatusNotFound, "that element does not exist")
+	}
+	if element.Expired() {
+		return fiber.NewError(fiber.StatusGone, "that element has expired")
+	}
+	if element.Exhausted() {
+		return fiber.NewError(fiber.StatusGone, "that element has no views left")
+	}
+	if err = requireScope(ctx, creationScope(element.Type)); err != nil {
+		return err
+	}
+
+	// Check if the client modifies the revision it expects
+	ifMatch := ctx.Get(fiber.HeaderIfMatch)
+	if ifMatch != "" && !matchesETag(ifMatch, element.Revision) {
+		return fiber.NewError(fiber.StatusPreconditionFailed, "the element was modified in the meantime")
+	}
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Apply the requested changes to a copy of the element
+	updated := *element
+	updated.Actor = actor(ctx)
+	for field, value := range data {
+		switch {
+		case field == "content" && (element.Type == shared.ElementTypePaste || element.Type == shared.ElementTypeMarkdown):
+			content, ok := value.(string)
+			if !ok || strings.TrimSpace(content) == "" {
+				return fiber.NewError(fiber.StatusBadRequest, "got an illegal or empty value as content")
+			}
+			updated.Data = content
+			updated.Blob = ""
+		case field == "target" && element.Type == shared.ElementTypeRedirect:
+			target, err := parseTarget(data)
 			if err != nil {
 				return err
 			}
-			if found == nil {
-				break
+			updated.Data = target
+			updated.Blob = ""
+		case field == "language" && element.Type == shared.ElementTypePaste:
+			if updated.Language, err = parseLanguage(data); err != nil {
+				return err
+			}
+		case field == "encryption" && element.Type == shared.ElementTypePaste:
+			if _, ok := data["content"]; !ok {
+				return fiber.NewError(fiber.StatusBadRequest, "the encryption metadata may only be changed together with the content")
 			}
+			if updated.Encryption, err = parseEncryption(data); err != nil {
+				return err
+			}
+		case field == "filename" && element.Type == shared.ElementTypeFile:
+			filename, ok := value.(string)
+			if !ok {
+				return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as filename")
+			}
+			updated.Filename = sanitizeFilename(filename)
+		case field == "expires_at" || field == "expires_in":
+			if value == nil {
+				updated.ExpiresAt = nil
+			} else if updated.ExpiresAt, err = parseExpiration(data); err != nil {
+				return err
+			}
+		case field == "max_views":
+			if value == nil {
+				updated.RemainingViews = nil
+			} else if updated.RemainingViews, err = parseMaxViews(data); err != nil {
+				return err
+			}
+		case field == "password":
+			if value == nil || value == "" {
+				updated.PasswordHash = ""
+			} else if updated.PasswordHash, err = parsePassword(data); err != nil {
+				return err
+			}
+		default:
+			return fiber.NewError(fiber.StatusBadRequest, "the field '"+field+"' can not be changed for this element")
+		}
+	}
+
+	// Make sure the content of an encrypted paste is replaced together with its encryption metadata and still forms a valid envelope
+	if element.Encryption != nil && updated.Data != element.Data {
+		if _, ok := data["encryption"]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "the content of an encrypted paste may only be changed together with its encryption metadata")
 		}
 	}
-	found, err := elements.Element(namespace.ID, key)
+	if updated.Encryption != nil {
+		if errors := validation.ValidateEncryption(updated.Data, updated.Encryption); len(errors) > 0 {
+			return validationErrors(ctx, errors)
+		}
+	}
+
+	// Store the updated element if nobody modified it in the meantime
+	ok, err := elements.Update(&updated, element.Revision)
 	if err != nil {
 		return err
 	}
-	if found != nil {
-		return fiber.NewError(fiber.StatusUnprocessableEntity, "the given element key is already in use")
+	if !ok {
+		if ifMatch != "" {
+			return fiber.NewError(fiber.StatusPreconditionFailed, "the element was modified in the meantime")
+		}
+		return fiber.NewError(fiber.StatusConflict, "the element was modified concurrently, please retry")
 	}
 
-	// Create the element
-	element := &shared.Element{
-		Namespace: namespace.ID,
-		Key:       key,
-		Type:      shared.ElementTypeRedirect,
-		Data:      parsedURL.String(),
+	ctx.Set(fiber.HeaderETag, elementETag(&updated))
+	return ctx.JSON(&updated)
+}
+
+// EndpointListElementRevisions handles the GET /v1/elements/:namespace/:key/revisions endpoint.
+// The revisions of an element are lost once it gets deleted.
+func EndpointListElementRevisions(ctx *fiber.Ctx) error {
+	element, err := ownedElement(ctx)
+	if err != nil {
+		return err
 	}
-	if err = elements.CreateOrReplace(element); err != nil {
+	revisions, err := ctx.Locals("__elements").(shared.ElementService).Revisions(element.Namespace, element.Key)
+	if err != nil {
 		return err
 	}
-	return ctx.JSON(element)
+
+	// Only list the metadata of the revisions; their content has to be retrieved one by one
+	for _, revision := range revisions {
+		revision.Data = ""
+	}
+	return ctx.JSON(revisions)
+}
+
+// EndpointGetElementRevision handles the GET /v1/elements/:namespace/:key/revisions/:revision endpoint
+func EndpointGetElementRevision(ctx *fiber.Ctx) error {
+	element, err := ownedElement(ctx)
+	if err != nil {
+		return err
+	}
+	revision, err := elementRevision(ctx, element)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(revision)
+}
+
+// EndpointRestoreElementRevision handles the POST /v1/elements/:namespace/:key/revisions/:revision/restore endpoint
+func EndpointRestoreElementRevision(ctx *fiber.Ctx) error {
+	isAdmin := ctx.Locals("_admin").(bool)
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	elements := ctx.Locals("__elements").(shared.ElementService)
+
+	// Check if the namespace is deactivated
+	if !namespace.Active && !isAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "this namespace is deactivated")
+	}
+
+	element, err := ownedElement(ctx)
+	if err != nil {
+		return err
+	}
+	if element.Expired() {
+		return fiber.NewError(fiber.StatusGone, "that element has expired")
+	}
+	if element.Exhausted() {
+		return fiber.NewError(fiber.StatusGone, "that element has no views left")
+	}
+
+	// Check if the client restores onto the revision it expects
+	ifMatch := ctx.Get(fiber.HeaderIfMatch)
+	if ifMatch != "" && !matchesETag(ifMatch, element.Revision) {
+		return fiber.NewError(fiber.StatusPreconditionFailed, "the element was modified in the meantime")
+	}
+
+	revision, err := elementRevision(ctx, element)
+	if err != nil {
+		return err
+	}
+	if err = requireScope(ctx, creationScope(element.Type), creationScope(revision.Type)); err != nil {
+		return err
+	}
+
+	// Restore the content of the revision as a new revision; expiration, view limit and password are kept
+	updated := *element
+	updated.Actor = actor(ctx)
+	revision.ApplyTo(&updated)
+	ok, err := elements.Update(&updated, element.Revision)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if ifMatch != "" {
+			return fiber.NewError(fiber.StatusPreconditionFailed, "the element was modified in the meantime")
+		}
+		return fiber.NewError(fiber.StatusConflict, "the element was modified concurrently, please retry")
+	}
+
+	ctx.Set(fiber.HeaderETag, elementETag(&updated))
+	return ctx.JSON(&updated)
+}
+
+// EndpointGetElementStats handles the GET /v1/elements/:namespace/:key/stats endpoint
+func EndpointGetElementStats(ctx *fiber.Ctx) error {
+	hits := ctx.Locals("__hits").(shared.HitService)
+	element, err := ownedElement(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Aggregate the hits of the requested amount of days including the current one
+	days := 30
+	if value := ctx.Query("days"); value != "" {
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 || days > 366 {
+			return fiber.NewError(fiber.StatusBadRequest, "the amount of days has to be between 1 and 366")
+		}
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	stats, err := hits.Stats(element.Namespace, element.Key, since)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(stats)
 }
 
 // EndpointDeleteElement handles the DELETE /v1/elements/:namespace/:key endpoint
@@ -183,5 +657,293 @@ func EndpointDeleteElement(ctx *fiber.Ctx) error {
 	if element == nil {
 		return fiber.NewError(fiber.StatusNotFound, "that element does not exist")
 	}
-	return elements.Delete(namespace.ID, element.Key)
+	if err = elements.Delete(namespace.ID, element.Key); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditElementDelete, namespace.ID, element.Key)
+	notify(ctx, namespace.ID, shared.WebhookElementDeleted, webhook.ElementData(element))
+	blob.Release(blobStore(ctx), element)
+	return nil
+}
+
+// ownedElement retrieves the element requested by an authenticated owner of its namespace
+func ownedElement(ctx *fiber.Ctx) (*shared.Element, error) {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	elements := ctx.Locals("__elements").(shared.ElementService)
+	element, err := elements.Element(namespace.ID, strings.ToLower(ctx.Params("key")))
+	if err != nil {
+		return nil, err
+	}
+	if element == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that element does not exist")
+	}
+	return element, nil
+}
+
+// elementRevision retrieves the requested revision of an element
+func elementRevision(ctx *fiber.Ctx, element *shared.Element) (*shared.Revision, error) {
+	number, err := strconv.Atoi(ctx.Params("revision"))
+	if err != nil || number <= 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal revision number")
+	}
+	revision, err := ctx.Locals("__elements").(shared.ElementService).Revision(element.Namespace, element.Key, number)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that revision does not exist")
+	}
+	return revision, nil
+}
+
+// elementKey returns the requested element key if it is not in use or generates a new one if none was requested
+func elementKey(ctx *fiber.Ctx, elements shared.ElementService, namespace string) (string, error) {
+	// Generate a new element key if none was requested
+	key := strings.TrimSpace(strings.ToLower(ctx.Params("key")))
+	if key == "" {
+		for {
+			key = utils.GenerateElementKey()
+			found, err := elements.Element(namespace, key)
+			if err != nil {
+				return "", err
+			}
+			if found == nil {
+				return key, nil
+			}
+		}
+	}
+
+	// Validate the requested element key and check if it is already in use
+	if errors := validation.ValidateElementKey(key); len(errors) > 0 {
+		return "", fiber.NewError(fiber.StatusUnprocessableEntity, errors[0].Error())
+	}
+	found, err := elements.Element(namespace, key)
+	if err != nil {
+		return "", err
+	}
+	if found == nil {
+		return key, nil
+	}
+	if !found.Expired() && !found.Exhausted() {
+		return "", fiber.NewError(fiber.StatusUnprocessableEntity, "the given element key is already in use")
+	}
+
+	// Purge the expired or exhausted element occupying the key
+	if err = elements.Delete(namespace, key); err != nil {
+		return "", err
+	}
+	notify(ctx, namespace, shared.WebhookElementExpired, webhook.ElementData(found))
+	blob.Release(blobStore(ctx), found)
+	return key, nil
+}
+
+// blobStore returns the blob store if one is configured
+func blobStore(ctx *fiber.Ctx) shared.BlobStore {
+	store, _ := ctx.Locals("__blobs").(shared.BlobStore)
+	return store
+}
+
+// formValues converts the values of a multipart form into a request body map; numeric values are parsed as numbers
+func formValues(form *multipart.Form) map[string]interface{} {
+	data := make(map[string]interface{}, len(form.Value))
+	for key, values := range form.Value {
+		if len(values) == 0 {
+			continue
+		}
+		if number, err := strconv.ParseFloat(values[0], 64); err == nil {
+			data[key] = number
+		} else {
+			data[key] = values[0]
+		}
+	}
+	return data
+}
+
+// sanitizeFilename strips directories and characters which may not appear in a header parameter from an uploaded file name
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(char rune) rune {
+		if unicode.IsControl(char) || char == '"' {
+			return -1
+		}
+		return char
+	}, name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+	return name
+}
+
+// creationScope returns the token scope required to create or update elements of the given type
+func creationScope(typ shared.ElementType) shared.TokenScope {
+	if typ == shared.ElementTypeRedirect {
+		return shared.TokenScopeCreateRedirects
+	}
+	return shared.TokenScopeCreatePastes
+}
+
+// actor identifies the authenticated token performing a request for the revision history and the audit log
+func actor(ctx *fiber.Ctx) string {
+	value, _ := ctx.Locals("_actor").(string)
+	return value
+}
+
+// elementETag builds the entity tag identifying the current revision of an element
+func elementETag(element *shared.Element) string {
+	return `"` + strconv.Itoa(element.Revision) + `"`
+}
+
+// matchesETag checks whether an If-Match header value matches the entity tag of the given revision
+func matchesETag(header string, revision int) bool {
+	expected := `"` + strconv.Itoa(revision) + `"`
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// validationErrors responds with the messages of multiple validation errors
+func validationErrors(ctx *fiber.Ctx, errors []error) error {
+	messages := make([]string, 0, len(errors))
+	for _, err := range errors {
+		messages = append(messages, err.Error())
+	}
+	return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"messages": messages,
+	})
+}
+
+// parseTarget reads and validates the target URL of a redirect element out of a parsed JSON body ('target')
+func parseTarget(data map[string]interface{}) (string, error) {
+	target, ok := data["target"].(string)
+	if !ok || strings.TrimSpace(target) == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "got an illegal or empty value as target URL")
+	}
+	parsedURL, err := url.Parse(target)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "the given target URL is no http(s) url")
+	}
+	return parsedURL.String(), nil
+}
+
+// parseLanguage reads and resolves the optional language hint of a paste element out of a parsed JSON body ('language')
+func parseLanguage(data map[string]interface{}) (string, error) {
+	rawLanguage, ok := data["language"]
+	if !ok || rawLanguage == nil {
+		return "", nil
+	}
+	name, ok := rawLanguage.(string)
+	if !ok {
+		return "", fiber.NewError(fiber.StatusBadRequest, "got an illegal value as paste language")
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return "", nil
+	}
+	language, ok := render.Language(name)
+	if !ok {
+		return "", fiber.NewError(fiber.StatusBadRequest, "got an unknown paste language")
+	}
+	return language, nil
+}
+
+// parseExpiration reads the optional expiration time out of a request body; it may either be given as an
+// RFC 3339 timestamp ('expires_at') or as a duration string or amount of seconds relative to now ('expires_in')
+func parseExpiration(data map[string]interface{}) (*time.Time, error) {
+	rawExpiresAt, hasExpiresAt := data["expires_at"]
+	rawExpiresIn, hasExpiresIn := data["expires_in"]
+
+	var expiresAt time.Time
+	switch {
+	case hasExpiresAt && hasExpiresIn:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "only one of 'expires_at' and 'expires_in' may be given")
+	case hasExpiresAt:
+		value, ok := rawExpiresAt.(string)
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as expiration timestamp")
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "the given expiration timestamp is no RFC 3339 timestamp")
+		}
+		expiresAt = parsed
+	case hasExpiresIn:
+		var duration time.Duration
+		switch value := rawExpiresIn.(type) {
+		case string:
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as expiration duration")
+			}
+			duration = parsed
+		case float64:
+			duration = time.Duration(value * float64(time.Second))
+		default:
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as expiration duration")
+		}
+		expiresAt = time.Now().Add(duration)
+	default:
+		return nil, nil
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "the given expiration time lies in the past")
+	}
+	expiresAt = expiresAt.UTC().Truncate(time.Second)
+	return &expiresAt, nil
+}
+
+// parseMaxViews reads the optional amount of times an element may be viewed out of a request body ('max_views')
+func parseMaxViews(data map[string]interface{}) (*int, error) {
+	rawMaxViews, ok := data["max_views"]
+	if !ok {
+		return nil, nil
+	}
+	value, ok := rawMaxViews.(float64)
+	if !ok || value != float64(int(value)) || value < 1 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as maximum view count (expected a positive integer)")
+	}
+	maxViews := int(value)
+	return &maxViews, nil
+}
+
+// parsePassword reads the optional password of an element out of a parsed JSON body and hashes it
+func parsePassword(data map[string]interface{}) (string, error) {
+	rawPassword, ok := data["password"]
+	if !ok || rawPassword == nil {
+		return "", nil
+	}
+	password, ok := rawPassword.(string)
+	if !ok || password == "" || len(password) > 1024 {
+		return "", fiber.NewError(fiber.StatusBadRequest, "got an illegal value as password (expected a non-empty string of at most 1024 bytes)")
+	}
+	return token.Hash(password)
+}
+
+// parseEncryption reads the optional encryption metadata of a client-side encrypted element out of a parsed JSON body
+func parseEncryption(data map[string]interface{}) (*shared.Encryption, error) {
+	rawEncryption, ok := data["encryption"]
+	if !ok || rawEncryption == nil {
+		return nil, nil
+	}
+
+	// Decode the metadata strictly to reject misspelled parameters
+	encoded, err := json.Marshal(rawEncryption)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	encryption := new(shared.Encryption)
+	if err := decoder.Decode(encryption); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as encryption metadata")
+	}
+	return encryption, nil
 }
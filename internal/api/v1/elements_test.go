@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// racingElementService represents an element service which modifies every element right before it gets updated
+type racingElementService struct {
+	shared.ElementService
+}
+
+// Update modifies the element concurrently before updating it
+func (service *racingElementService) Update(element *shared.Element, expectedRevision int) (bool, error) {
+	concurrent := *element
+	concurrent.Data = "concurrent"
+	if _, err := service.ElementService.Update(&concurrent, expectedRevision); err != nil {
+		return false, err
+	}
+	return service.ElementService.Update(element, expectedRevision)
+}
+
+func TestPatchElementConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		racing  bool
+		status  int
+		etag    string
+	}{
+		{name: "unconditional", status: fiber.StatusOK, etag: `"2"`},
+		{name: "matching entity tag", ifMatch: `"1"`, status: fiber.StatusOK, etag: `"2"`},
+		{name: "one of multiple entity tags", ifMatch: `"5", "1"`, status: fiber.StatusOK, etag: `"2"`},
+		{name: "wildcard entity tag", ifMatch: "*", status: fiber.StatusOK, etag: `"2"`},
+		{name: "outdated entity tag", ifMatch: `"0"`, status: fiber.StatusPreconditionFailed},
+		{name: "unconditional concurrent modification", racing: true, status: fiber.StatusConflict},
+		{name: "conditional concurrent modification", ifMatch: `"1"`, racing: true, status: fiber.StatusPreconditionFailed},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			services := memory.NewStore().Services()
+			if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: "ns", Active: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := services.Elements.CreateOrReplace(&shared.Element{Namespace: "ns", Key: "key", Data: "original"}); err != nil {
+				t.Fatal(err)
+			}
+			var elements shared.ElementService = services.Elements
+			if test.racing {
+				elements = &racingElementService{ElementService: services.Elements}
+			}
+
+			app := fiber.New()
+			app.Use(func(ctx *fiber.Ctx) error {
+				ctx.Locals("__namespaces", services.Namespaces)
+				ctx.Locals("__elements", elements)
+				ctx.Locals("_admin", true)
+				return ctx.Next()
+			})
+			app.Patch("/v1/elements/:namespace/:key", MiddlewareInjectNamespace, EndpointPatchElement)
+
+			request := httptest.NewRequest(fiber.MethodPatch, "/v1/elements/ns/key", strings.NewReader(`{"content":"patched"}`))
+			request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			if test.ifMatch != "" {
+				request.Header.Set(fiber.HeaderIfMatch, test.ifMatch)
+			}
+			response, err := app.Test(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != test.status {
+				t.Errorf("responded with status %d, expected %d", response.StatusCode, test.status)
+			}
+			if etag := response.Header.Get(fiber.HeaderETag); etag != test.etag {
+				t.Errorf("responded with entity tag %q, expected %q", etag, test.etag)
+			}
+
+			element, err := services.Elements.Element("ns", "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patched := element.Data == "patched"; patched != (test.status == fiber.StatusOK) {
+				t.Errorf("the element holds the data %q after responding with status %d", element.Data, response.StatusCode)
+			}
+		})
+	}
+}
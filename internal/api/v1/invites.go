@@ -3,6 +3,7 @@ package v1
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
 	"github.com/x0tf/server/internal/utils"
 )
 
@@ -36,6 +37,7 @@ func EndpointCreateInvite(ctx *fiber.Ctx) error {
 	if err := invites.Create(shared.Invite(code)); err != nil {
 		return err
 	}
+	recordAudit(ctx, shared.AuditInviteCreate, "", inviteReference(code))
 	return ctx.JSON(fiber.Map{
 		"code": code,
 	})
@@ -45,5 +47,15 @@ func EndpointCreateInvite(ctx *fiber.Ctx) error {
 func EndpointDeleteInvite(ctx *fiber.Ctx) error {
 	code := ctx.Params("code")
 	invites := ctx.Locals("__invites").(shared.InviteService)
-	return invites.Delete(code)
+	if err := invites.Delete(code); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditInviteDelete, "", inviteReference(code))
+	return nil
+}
+
+// inviteReference creates the reference of an invite code recorded in the audit log; invite codes allow creating namespaces,
+// so only their digest is recorded, which identifies an invite without allowing to redeem it
+func inviteReference(code string) string {
+	return token.Digest(code)
 }
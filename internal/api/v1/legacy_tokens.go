@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"sync"
+	"time"
+)
+
+// LegacyTokenGuard represents the guard bounding the verifications of tokens which are still stored as expensive legacy argon2id hashes.
+// It caps the amount of concurrent verifications, both in total and per namespace so a single namespace cannot occupy all of them,
+// and remembers rejected tokens for a while so they do not get hashed again.
+type LegacyTokenGuard struct {
+	mu            sync.Mutex
+	concurrency   int
+	perNamespace  int
+	ttl           time.Duration
+	maxRejected   int
+	rejected      map[string]time.Time
+	verifications map[string]int
+	inProgress    int
+}
+
+// NewLegacyTokenGuard creates a new legacy token guard allowing the given amount of concurrent verifications with at most
+// perNamespace of them for the same namespace and remembering up to maxRejected rejected tokens for the given duration
+func NewLegacyTokenGuard(concurrency, perNamespace int, ttl time.Duration, maxRejected int) *LegacyTokenGuard {
+	return &LegacyTokenGuard{
+		concurrency:   concurrency,
+		perNamespace:  perNamespace,
+		ttl:           ttl,
+		maxRejected:   maxRejected,
+		rejected:      make(map[string]time.Time),
+		verifications: make(map[string]int),
+	}
+}
+
+// acquire reserves a slot for a verification of a token of the given namespace and reports whether one was free
+func (guard *LegacyTokenGuard) acquire(namespace string) bool {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	if guard.inProgress >= guard.concurrency || guard.verifications[namespace] >= guard.perNamespace {
+		return false
+	}
+	guard.inProgress++
+	guard.verifications[namespace]++
+	return true
+}
+
+// release frees the slot of a finished verification of a token of the given namespace
+func (guard *LegacyTokenGuard) release(namespace string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	guard.inProgress--
+	if guard.verifications[namespace]--; guard.verifications[namespace] <= 0 {
+		delete(guard.verifications, namespace)
+	}
+}
+
+// isRejected checks whether the token with the given key was rejected recently
+func (guard *LegacyTokenGuard) isRejected(key string) bool {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	rejectedAt, ok := guard.rejected[key]
+	return ok && time.Since(rejectedAt) < guard.ttl
+}
+
+// reject remembers the token with the given key as rejected; it is not remembered if too many others are already
+func (guard *LegacyTokenGuard) reject(key string) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	now := time.Now()
+
+	// Forget the rejections which already expired so the map does not grow indefinitely
+	if len(guard.rejected) >= guard.maxRejected {
+		for rejectedKey, rejectedAt := range guard.rejected {
+			if now.Sub(rejectedAt) >= guard.ttl {
+				delete(guard.rejected, rejectedKey)
+			}
+		}
+	}
+	if len(guard.rejected) < guard.maxRejected {
+		guard.rejected[key] = now
+	}
+}
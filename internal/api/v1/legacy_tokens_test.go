@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLegacyTokenGuardPerNamespace(t *testing.T) {
+	services := memory.NewStore().Services()
+	for _, id := range []string{"a", "b"} {
+		hash, err := token.Hash("legacy-" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: id, Token: hash, Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	guard := NewLegacyTokenGuard(4, 1, time.Minute, 100)
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("__namespaces", services.Namespaces)
+		ctx.Locals("__tokens", services.Tokens)
+		ctx.Locals("__legacy_tokens", guard)
+		ctx.Locals("_admin", false)
+		return ctx.Next()
+	})
+	app.Get("/v1/namespaces/:namespace", MiddlewareInjectNamespace, MiddlewareTokenAuth(), EndpointGetNamespace)
+
+	// Flood namespace A so every verification slot it may use is occupied
+	if !guard.acquire("a") {
+		t.Fatal("could not occupy the verification slot of namespace A")
+	}
+	defer guard.release("a")
+
+	tests := []struct {
+		namespace string
+		status    int
+	}{
+		{namespace: "a", status: fiber.StatusTooManyRequests},
+		{namespace: "b", status: fiber.StatusOK},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(fiber.MethodGet, "/v1/namespaces/"+test.namespace, nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer legacy-"+test.namespace)
+		response, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status {
+			t.Errorf("responded to namespace %q with status %d, expected %d", test.namespace, response.StatusCode, test.status)
+		}
+	}
+
+	// The total amount of concurrent verifications stays bounded across namespaces
+	for _, namespace := range []string{"c", "d", "e"} {
+		if !guard.acquire(namespace) {
+			t.Fatalf("could not acquire a verification slot for namespace %q", namespace)
+		}
+		defer guard.release(namespace)
+	}
+	if guard.acquire("f") {
+		guard.release("f")
+		t.Error("acquired more verification slots than allowed in total")
+	}
+}
+
+func TestLegacyTokenUpgrade(t *testing.T) {
+	services := memory.NewStore().Services()
+	namespaceHash, err := token.Hash("namespace-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: "ns", Token: namespaceHash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	namedHash, err := token.Hash("named-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := &shared.NamespaceToken{Namespace: "ns", ID: "named", Name: "named", Hash: namedHash, Scopes: shared.TokenScopes}
+	if err := services.Tokens.CreateOrReplace(named); err != nil {
+		t.Fatal(err)
+	}
+
+	guard := NewLegacyTokenGuard(4, 1, time.Minute, 100)
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("__namespaces", services.Namespaces)
+		ctx.Locals("__tokens", services.Tokens)
+		ctx.Locals("__legacy_tokens", guard)
+		ctx.Locals("_admin", false)
+		return ctx.Next()
+	})
+	app.Get("/v1/namespaces/:namespace", MiddlewareInjectNamespace, MiddlewareTokenAuth(), EndpointGetNamespace)
+	authenticate := func(raw string) int {
+		request := httptest.NewRequest(fiber.MethodGet, "/v1/namespaces/ns", nil)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+raw)
+		response, err := app.Test(request, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response.StatusCode
+	}
+
+	// A wrong token leaves the legacy hashes in place
+	if status := authenticate("wrong-token"); status != fiber.StatusUnauthorized {
+		t.Errorf("responded to a wrong token with status %d, expected %d", status, fiber.StatusUnauthorized)
+	}
+
+	// Using the tokens once replaces their legacy hashes with digests
+	for _, raw := range []string{"namespace-token", "named-token"} {
+		if status := authenticate(raw); status != fiber.StatusOK {
+			t.Errorf("responded to the legacy token %q with status %d, expected %d", raw, status, fiber.StatusOK)
+		}
+	}
+	namespace, err := services.Namespaces.Namespace("ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace.Token != token.Digest("namespace-token") {
+		t.Errorf("stored the namespace token as %q, expected its digest", namespace.Token)
+	}
+	stored, err := services.Tokens.Token("ns", "named")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Hash != token.Digest("named-token") {
+		t.Errorf("stored the named token as %q, expected its digest", stored.Hash)
+	}
+
+	// Upgraded tokens are compared by their digests without verifying any legacy hash
+	if !guard.acquire("ns") {
+		t.Fatal("could not occupy the verification slot of the namespace")
+	}
+	defer guard.release("ns")
+	for _, raw := range []string{"namespace-token", "named-token"} {
+		if status := authenticate(raw); status != fiber.StatusOK {
+			t.Errorf("responded to the upgraded token %q with status %d, expected %d", raw, status, fiber.StatusOK)
+		}
+	}
+	if status := authenticate("wrong-token"); status != fiber.StatusUnauthorized {
+		t.Errorf("responded to a wrong token with status %d once no legacy hash remained, expected %d", status, fiber.StatusUnauthorized)
+	}
+}
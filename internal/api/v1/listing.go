@@ -0,0 +1,142 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/shared"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultListLimit is the amount of entries a listing returns if the client did not request a specific one
+	defaultListLimit = 100
+
+	// maxListLimit is the maximum amount of entries a single listing may return
+	maxListLimit = 1000
+
+	// headerNextCursor is the response header containing the cursor of the next page of a listing
+	headerNextCursor = "X-Next-Cursor"
+)
+
+// sortOrders maps the accepted values of the sort query parameter to their sort order
+var sortOrders = map[string]shared.SortOrder{
+	"key":         shared.SortByKey,
+	"-key":        shared.SortByKeyDescending,
+	"created_at":  shared.SortByCreation,
+	"-created_at": shared.SortByCreationDescending,
+}
+
+// elementTypes maps the names accepted by the type query parameter to their element type
+var elementTypes = map[string]shared.ElementType{
+	"paste":    shared.ElementTypePaste,
+	"redirect": shared.ElementTypeRedirect,
+	"file":     shared.ElementTypeFile,
+	"markdown": shared.ElementTypeMarkdown,
+}
+
+// cursor represents the opaque position behind the last entry of a listed page
+type cursor struct {
+	Sort      shared.SortOrder `json:"s"`
+	CreatedAt time.Time        `json:"c"`
+	Namespace string           `json:"n,omitempty"`
+	Key       string           `json:"k"`
+	ID        int64            `json:"i,omitempty"`
+}
+
+// listing represents the common query parameters of a paginated listing
+type listing struct {
+	sort          shared.SortOrder
+	limit         int
+	after         *cursor
+	createdAfter  *time.Time
+	createdBefore *time.Time
+}
+
+// parseListing parses the sort, limit, cursor, created_after and created_before query parameters
+func parseListing(ctx *fiber.Ctx) (*listing, error) {
+	result := &listing{
+		limit: defaultListLimit,
+	}
+
+	// Parse the requested sort order
+	if value := ctx.Query("sort"); value != "" {
+		order, ok := sortOrders[value]
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal sort order; use one of 'key', '-key', 'created_at' or '-created_at'")
+		}
+		result.sort = order
+	}
+
+	// Parse the requested page size
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxListLimit {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "the limit has to be between 1 and "+strconv.Itoa(maxListLimit))
+		}
+		result.limit = limit
+	}
+
+	// Decode the cursor of the previous page
+	if value := ctx.Query("cursor"); value != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		after := new(cursor)
+		if err != nil || json.Unmarshal(raw, after) != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal cursor")
+		}
+		if after.Sort != result.sort {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "the cursor belongs to a listing with another sort order")
+		}
+		result.after = after
+	}
+
+	// Parse the creation time range
+	var err error
+	if result.createdAfter, err = parseTimeQuery(ctx, "created_after"); err != nil {
+		return nil, err
+	}
+	if result.createdBefore, err = parseTimeQuery(ctx, "created_before"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// page trims the given amount of fetched entries, which is one more than requested if another page exists,
+// to the requested limit and sets the cursor header of the next page using the given function
+func (listing *listing) page(ctx *fiber.Ctx, fetched int, next func(last int) *cursor) int {
+	if fetched <= listing.limit {
+		return fetched
+	}
+	after := next(listing.limit - 1)
+	after.Sort = listing.sort
+	encoded, _ := json.Marshal(after)
+	ctx.Set(headerNextCursor, base64.RawURLEncoding.EncodeToString(encoded))
+	return listing.limit
+}
+
+// parseElementType parses the type query parameter
+func parseElementType(ctx *fiber.Ctx) (*shared.ElementType, error) {
+	value := ctx.Query("type")
+	if value == "" {
+		return nil, nil
+	}
+	typ, ok := elementTypes[value]
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal element type; use one of 'paste', 'redirect', 'file' or 'markdown'")
+	}
+	return &typ, nil
+}
+
+// parseTimeQuery parses an optional RFC 3339 timestamp query parameter
+func parseTimeQuery(ctx *fiber.Ctx, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal RFC 3339 timestamp as "+name)
+	}
+	return &parsed, nil
+}
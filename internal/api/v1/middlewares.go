@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"crypto/subtle"
 	"github.com/gofiber/fiber/v2"
 	"github.com/x0tf/server/internal/shared"
 	"github.com/x0tf/server/internal/token"
@@ -21,45 +22,217 @@ func MiddlewareInjectNamespace(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
-// MiddlewareTokenAuth handles namespace token authentication
-func MiddlewareTokenAuth(ctx *fiber.Ctx) error {
-	// Perform user authentication if the request was not made by an admin
-	isAdmin, _ := ctx.Locals("_admin").(bool)
-	if !isAdmin {
-		// Read and validate the header itself
-		header := strings.SplitN(ctx.Get(fiber.HeaderAuthorization), " ", 2)
-		if len(header) != 2 || header[0] != "Bearer" {
-			return fiber.ErrUnauthorized
+// MiddlewareTokenAuth creates a middleware handling namespace token authentication and requiring the given scopes.
+// The token of the namespace itself and admins are granted every scope.
+func MiddlewareTokenAuth(scopes ...shared.TokenScope) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		// Perform user authentication if the request was not made by an admin
+		isAdmin, _ := ctx.Locals("_admin").(bool)
+		if !isAdmin {
+			// Read and validate the header itself
+			header := strings.SplitN(ctx.Get(fiber.HeaderAuthorization), " ", 2)
+			if len(header) != 2 || header[0] != "Bearer" {
+				return fiber.ErrUnauthorized
+			}
+
+			// Look up named tokens by their embedded ID; other tokens are either the one of the namespace itself
+			// or named ones which were created before IDs got embedded into them
+			namespace := ctx.Locals("_namespace").(*shared.Namespace)
+			var found *shared.NamespaceToken
+			var err error
+			if id, ok := parseTokenID(header[1], namedTokenPrefix); ok {
+				found, err = lookupNamespaceToken(ctx, namespace, id, header[1])
+			} else {
+				var valid bool
+				if valid, found, err = verifyUnprefixedToken(ctx, namespace, header[1]); err != nil {
+					return err
+				}
+				if valid {
+					ctx.Locals("_actor", "namespace:"+namespace.ID)
+					return ctx.Next()
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if found == nil {
+				return fiber.ErrUnauthorized
+			}
+			ctx.Locals("_token", found)
+			ctx.Locals("_actor", "token:"+found.ID)
+			ctx.Locals("_actor_name", found.Name)
+
+			// Check whether the token was granted the scopes the route requires
+			if err := requireScope(ctx, scopes...); err != nil {
+				return err
+			}
 		}
+		return ctx.Next()
+	}
+}
 
-		// Compare the given authentication token with the one of the found namespace
-		namespace := ctx.Locals("_namespace").(*shared.Namespace)
-		if valid, _ := token.Check(namespace.Token, header[1]); !valid {
-			return fiber.ErrUnauthorized
+// verifyNamespaceToken compares the given raw token with the one of the namespace itself and upgrades its legacy hash on a match
+func verifyNamespaceToken(ctx *fiber.Ctx, namespace *shared.Namespace, raw string) (bool, error) {
+	valid, outdated, _ := token.Verify(namespace.Token, raw)
+	if outdated {
+		namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
+		if _, err := namespaces.ReplaceToken(namespace.ID, namespace.Token, token.Digest(raw)); err != nil {
+			return false, err
 		}
 	}
-	return ctx.Next()
+	return valid, nil
+}
+
+// lookupNamespaceToken retrieves the named token of a namespace with the given ID and compares it with the given raw token
+func lookupNamespaceToken(ctx *fiber.Ctx, namespace *shared.Namespace, id, raw string) (*shared.NamespaceToken, error) {
+	namespaceToken, err := ctx.Locals("__tokens").(shared.NamespaceTokenService).Token(namespace.ID, id)
+	if err != nil || namespaceToken == nil {
+		return nil, err
+	}
+	valid, err := verifyNamedToken(ctx, namespaceToken, raw)
+	if err != nil || !valid {
+		return nil, err
+	}
+	return namespaceToken, nil
+}
+
+// verifyUnprefixedToken compares a raw token without an embedded ID with the token of the namespace itself and its named tokens;
+// it reports whether it is the token of the namespace itself or returns the named token it matches.
+// Digests are compared first. Legacy argon2id hashes, which remain for tokens created before digests were introduced
+// until they are used once, are only checked while some remain and through the legacy token guard bounding their cost.
+func verifyUnprefixedToken(ctx *fiber.Ctx, namespace *shared.Namespace, raw string) (bool, *shared.NamespaceToken, error) {
+	digest := []byte(token.Digest(raw))
+	if token.IsDigest(namespace.Token) && subtle.ConstantTimeCompare(digest, []byte(namespace.Token)) == 1 {
+		return true, nil, nil
+	}
+
+	// Compare the digests of the named tokens and collect the ones which still have a legacy hash
+	tokens, err := ctx.Locals("__tokens").(shared.NamespaceTokenService).Tokens(namespace.ID)
+	if err != nil {
+		return false, nil, err
+	}
+	var legacy []*shared.NamespaceToken
+	for _, namespaceToken := range tokens {
+		if namespaceToken.Expired() {
+			continue
+		}
+		if !token.IsDigest(namespaceToken.Hash) {
+			legacy = append(legacy, namespaceToken)
+			continue
+		}
+		if subtle.ConstantTimeCompare(digest, []byte(namespaceToken.Hash)) == 1 {
+			return false, namespaceToken, nil
+		}
+	}
+	legacyNamespace := !token.IsDigest(namespace.Token)
+	if !legacyNamespace && len(legacy) == 0 {
+		return false, nil, nil
+	}
+
+	// Check the legacy hashes unless the token was rejected recently
+	guard := ctx.Locals("__legacy_tokens").(*LegacyTokenGuard)
+	key := token.Digest(namespace.ID + "/" + raw)
+	if guard.isRejected(key) {
+		return false, nil, nil
+	}
+	if !guard.acquire(namespace.ID) {
+		return false, nil, fiber.NewError(fiber.StatusTooManyRequests, "too many token verifications in progress")
+	}
+	defer guard.release(namespace.ID)
+	if legacyNamespace {
+		valid, err := verifyNamespaceToken(ctx, namespace, raw)
+		if err != nil || valid {
+			return valid, nil, err
+		}
+	}
+	for _, namespaceToken := range legacy {
+		valid, err := verifyNamedToken(ctx, namespaceToken, raw)
+		if err != nil {
+			return false, nil, err
+		}
+		if valid {
+			return false, namespaceToken, nil
+		}
+	}
+	guard.reject(key)
+	return false, nil, nil
+}
+
+// verifyNamedToken compares the given raw token with an unexpired named token and upgrades its legacy hash on a match
+func verifyNamedToken(ctx *fiber.Ctx, namespaceToken *shared.NamespaceToken, raw string) (bool, error) {
+	if namespaceToken.Expired() {
+		return false, nil
+	}
+	valid, outdated, _ := token.Verify(namespaceToken.Hash, raw)
+	if outdated {
+		tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+		if _, err := tokens.ReplaceHash(namespaceToken.Namespace, namespaceToken.ID, namespaceToken.Hash, token.Digest(raw)); err != nil {
+			return false, err
+		}
+	}
+	return valid, nil
+}
+
+// requireScope checks whether the authenticated named token was granted every given scope; other authentications are granted every scope
+func requireScope(ctx *fiber.Ctx, scopes ...shared.TokenScope) error {
+	namespaceToken, ok := ctx.Locals("_token").(*shared.NamespaceToken)
+	if !ok {
+		return nil
+	}
+	for _, scope := range scopes {
+		if !namespaceToken.HasScope(scope) {
+			return fiber.NewError(fiber.StatusForbidden, "the token was not granted the '"+string(scope)+"' scope")
+		}
+	}
+	return nil
 }
 
 // MiddlewareAdminAuth handles admin token authentication
 func MiddlewareAdminAuth(ctx *fiber.Ctx) error {
 	header := strings.SplitN(ctx.Get(fiber.HeaderAuthorization), " ", 2)
-	if len(header) != 2 || header[0] != "Bearer" {
+	if len(header) != 2 || header[0] != "Bearer" || header[1] == "" {
 		ctx.Locals("_admin", false)
 		return ctx.Next()
 	}
 
-	isAdmin := false
-	for _, adminToken := range ctx.Locals("__admin_tokens").([]string) {
-		if header[1] == adminToken {
-			isAdmin = true
-			break
-		}
+	actor, err := adminActor(ctx, header[1])
+	if err != nil {
+		return err
+	}
+	ctx.Locals("_admin", actor != "")
+	if actor != "" {
+		ctx.Locals("_actor", actor)
 	}
-	ctx.Locals("_admin", isAdmin)
 	return ctx.Next()
 }
 
+// adminActor identifies the admin the given raw token belongs to or returns an empty string if it is no valid admin token.
+// Tokens embedding an ID are looked up in the database; every other token is compared with the digests of the
+// configured ones, all of which are compared in constant time to not leak which one matched.
+func adminActor(ctx *fiber.Ctx, raw string) (string, error) {
+	if id, ok := parseTokenID(raw, adminTokenPrefix); ok {
+		adminToken, err := ctx.Locals("__admin_tokens").(shared.AdminTokenService).Token(id)
+		if err != nil || adminToken == nil || adminToken.Expired() {
+			return "", err
+		}
+		if valid, _, _ := token.Verify(adminToken.Hash, raw); !valid {
+			return "", nil
+		}
+		ctx.Locals("_actor_name", adminToken.Name)
+		return "admin:" + adminToken.ID, nil
+	}
+
+	digest := []byte(token.Digest(raw))
+	matched := 0
+	for _, configured := range ctx.Locals("__admin_token_digests").([]string) {
+		matched |= subtle.ConstantTimeCompare(digest, []byte(configured))
+	}
+	if matched == 0 {
+		return "", nil
+	}
+	return "admin", nil
+}
+
 // MiddlewareRequireAdminAuth handles admin token authentication requirement
 func MiddlewareRequireAdminAuth(ctx *fiber.Ctx) error {
 	if !ctx.Locals("_admin").(bool) {
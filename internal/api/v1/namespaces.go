@@ -2,20 +2,45 @@ package v1
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/blob"
 	"github.com/x0tf/server/internal/shared"
 	"github.com/x0tf/server/internal/token"
 	"github.com/x0tf/server/internal/utils"
 	"github.com/x0tf/server/internal/validation"
+	"github.com/x0tf/server/internal/webhook"
+	"strings"
 )
 
 // EndpointListNamespaces handles the GET /v1/namespaces endpoint
 func EndpointListNamespaces(ctx *fiber.Ctx) error {
 	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
-	list, err := namespaces.Namespaces()
+	listing, err := parseListing(ctx)
 	if err != nil {
 		return err
 	}
 
+	// Fetch one more namespace than requested to find out whether another page exists
+	query := &shared.NamespaceQuery{
+		IDPrefix:      strings.ToLower(ctx.Query("prefix")),
+		CreatedAfter:  listing.createdAfter,
+		CreatedBefore: listing.createdBefore,
+		Sort:          listing.sort,
+		Limit:         listing.limit + 1,
+	}
+	if listing.after != nil {
+		query.After = &shared.Namespace{
+			ID:        listing.after.Key,
+			CreatedAt: listing.after.CreatedAt,
+		}
+	}
+	list, err := namespaces.Namespaces(query)
+	if err != nil {
+		return err
+	}
+	list = list[:listing.page(ctx, len(list), func(last int) *cursor {
+		return &cursor{CreatedAt: list[last].CreatedAt, Key: list[last].ID}
+	})]
+
 	// Process the list to remove the tokens
 	processedList := make([]shared.Namespace, 0, len(list))
 	for _, namespace := range list {
@@ -74,42 +99,50 @@ func EndpointCreateNamespace(ctx *fiber.Ctx) error {
 		})
 	}
 
-	// Check if a namespace with this ID already exists
-	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
-	found, err := namespaces.Namespace(id)
-	if err != nil {
-		return err
-	}
-	if found != nil {
-		return fiber.NewError(fiber.StatusUnprocessableEntity, "the given namespace ID is already taken")
-	}
-
-	// Create a new namespace and a copy of it
+	// Create a new namespace and keep its raw token
 	namespace := &shared.Namespace{
 		ID:     id,
 		Token:  utils.GenerateToken(),
 		Active: true,
 	}
-	namespaceCopy := *namespace
+	rawToken := namespace.Token
 
-	// Hash the token of the original namespace and insert it into the database
-	hash, err := token.Hash(namespace.Token)
-	if err != nil {
-		return err
-	}
-	namespace.Token = hash
-	if err = namespaces.CreateOrReplace(namespace); err != nil {
-		return err
-	}
+	// Digest the token of the namespace
+	namespace.Token = token.Digest(namespace.Token)
 
-	// Delete the invite code if one was used
-	if usedInvite != "" && invites != nil {
-		if err = invites.Delete(usedInvite); err != nil {
+	// Insert the namespace and consume the used invite code atomically
+	transactor := ctx.Locals("__transactor").(shared.Transactor)
+	err := transactor.Transaction(func(services *shared.Services) error {
+		// Insert the namespace unless its ID is already taken
+		created, err := services.Namespaces.Create(namespace)
+		if err != nil {
 			return err
 		}
+		if !created {
+			return fiber.NewError(fiber.StatusUnprocessableEntity, "the given namespace ID is already taken")
+		}
+
+		// Consume the invite code if one was used; an invalid one rolls back the creation
+		if usedInvite != "" {
+			consumed, err := services.Invites.Consume(usedInvite)
+			if err != nil {
+				return err
+			}
+			if !consumed {
+				return fiber.NewError(fiber.StatusUnprocessableEntity, "invalid invite code")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Return the copied namespace with the raw token still placed in it
+	recordAudit(ctx, shared.AuditNamespaceCreate, namespace.ID, "")
+
+	// Return the created namespace with the raw token placed in it
+	namespaceCopy := *namespace
+	namespaceCopy.Token = rawToken
 	return ctx.JSON(namespaceCopy)
 }
 
@@ -117,16 +150,13 @@ func EndpointCreateNamespace(ctx *fiber.Ctx) error {
 func EndpointResetNamespaceToken(ctx *fiber.Ctx) error {
 	namespace := ctx.Locals("_namespace").(*shared.Namespace)
 	newToken := utils.GenerateToken()
-	hash, err := token.Hash(newToken)
-	if err != nil {
-		return err
-	}
-	namespace.Token = hash
+	namespace.Token = token.Digest(newToken)
 
 	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
-	if err = namespaces.CreateOrReplace(namespace); err != nil {
+	if err := namespaces.CreateOrReplace(namespace); err != nil {
 		return err
 	}
+	recordAudit(ctx, shared.AuditNamespaceResetToken, namespace.ID, "")
 	return ctx.JSON(fiber.Map{"token": newToken})
 }
 
@@ -135,7 +165,12 @@ func EndpointDeactivateNamespace(ctx *fiber.Ctx) error {
 	namespace := ctx.Locals("_namespace").(*shared.Namespace)
 	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
 	namespace.Active = false
-	return namespaces.CreateOrReplace(namespace)
+	if err := namespaces.CreateOrReplace(namespace); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditNamespaceDeactivate, namespace.ID, "")
+	notify(ctx, namespace.ID, shared.WebhookNamespaceDeactivated, &webhook.Namespace{ID: namespace.ID, Active: namespace.Active})
+	return nil
 }
 
 // EndpointActivateNamespace handles the POST /v1/namespaces/:namespace/activate endpoint
@@ -143,18 +178,34 @@ func EndpointActivateNamespace(ctx *fiber.Ctx) error {
 	namespace := ctx.Locals("_namespace").(*shared.Namespace)
 	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
 	namespace.Active = true
-	return namespaces.CreateOrReplace(namespace)
+	if err := namespaces.CreateOrReplace(namespace); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditNamespaceActivate, namespace.ID, "")
+	notify(ctx, namespace.ID, shared.WebhookNamespaceActivated, &webhook.Namespace{ID: namespace.ID, Active: namespace.Active})
+	return nil
 }
 
 // EndpointDeleteNamespace handles the DELETE /v1/namespaces/:namespace endpoint
 func EndpointDeleteNamespace(ctx *fiber.Ctx) error {
 	namespace := ctx.Locals("_namespace").(*shared.Namespace)
-	namespaces := ctx.Locals("__namespaces").(shared.NamespaceService)
-	elements := ctx.Locals("__elements").(shared.ElementService)
+	transactor := ctx.Locals("__transactor").(shared.Transactor)
 
-	if err := elements.DeleteInNamespace(namespace.ID); err != nil {
+	var deleted []*shared.Element
+	err := transactor.Transaction(func(services *shared.Services) error {
+		elements, err := services.Elements.DeleteInNamespace(namespace.ID)
+		if err != nil {
+			return err
+		}
+		deleted = elements
+		return services.Namespaces.Delete(namespace.ID)
+	})
+	if err != nil {
 		return err
 	}
+	recordAudit(ctx, shared.AuditNamespaceDelete, namespace.ID, "")
 
-	return namespaces.Delete(namespace.ID)
+	// Release the blobs of the deleted elements once the deletion was committed
+	blob.Release(blobStore(ctx), deleted...)
+	return nil
 }
@@ -0,0 +1,210 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"github.com/x0tf/server/internal/utils"
+	"github.com/x0tf/server/internal/validation"
+	"strings"
+)
+
+// namedTokenPrefix represents the prefix of named namespace tokens, which embed their public ID ('x0_<id>_<secret>')
+const namedTokenPrefix = "x0_"
+
+// createdToken represents a freshly created namespace token including its raw value, which is only revealed once
+type createdToken struct {
+	*shared.NamespaceToken
+	Token string `json:"token"`
+}
+
+// EndpointListNamespaceTokens handles the GET /v1/namespaces/:namespace/tokens endpoint
+func EndpointListNamespaceTokens(ctx *fiber.Ctx) error {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+	list, err := tokens.Tokens(namespace.ID)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []*shared.NamespaceToken{}
+	}
+	return ctx.JSON(list)
+}
+
+// EndpointGetNamespaceToken handles the GET /v1/namespaces/:namespace/tokens/:token endpoint
+func EndpointGetNamespaceToken(ctx *fiber.Ctx) error {
+	namespaceToken, err := requestedToken(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(namespaceToken)
+}
+
+// EndpointCreateNamespaceToken handles the POST /v1/namespaces/:namespace/tokens endpoint
+func EndpointCreateNamespaceToken(ctx *fiber.Ctx) error {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Read and validate the token properties
+	name, ok := data["name"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token name")
+	}
+	scopes, err := parseScopes(data)
+	if err != nil {
+		return err
+	}
+	if errors := append(validation.ValidateTokenName(name), validation.ValidateTokenScopes(scopes)...); len(errors) > 0 {
+		return validationErrors(ctx, errors)
+	}
+	if err = requireScope(ctx, scopes...); err != nil {
+		return err
+	}
+	expiresAt, err := parseExpiration(data)
+	if err != nil {
+		return err
+	}
+
+	// Generate the token embedding its ID and store its digest
+	id := utils.GenerateTokenID()
+	raw := namedTokenPrefix + id + "_" + utils.GenerateToken()
+	namespaceToken := &shared.NamespaceToken{
+		Namespace: namespace.ID,
+		ID:        id,
+		Name:      strings.TrimSpace(name),
+		Hash:      token.Digest(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err = tokens.CreateOrReplace(namespaceToken); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditTokenCreate, namespaceToken.Namespace, namespaceToken.ID)
+	return ctx.JSON(&createdToken{
+		NamespaceToken: namespaceToken,
+		Token:          raw,
+	})
+}
+
+// EndpointPatchNamespaceToken handles the PATCH /v1/namespaces/:namespace/tokens/:token endpoint
+func EndpointPatchNamespaceToken(ctx *fiber.Ctx) error {
+	tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+	namespaceToken, err := requestedToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Apply the requested changes
+	var errors []error
+	if rawName, ok := data["name"]; ok {
+		name, ok := rawName.(string)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token name")
+		}
+		errors = append(errors, validation.ValidateTokenName(name)...)
+		namespaceToken.Name = strings.TrimSpace(name)
+	}
+	if _, ok := data["scopes"]; ok {
+		scopes, err := parseScopes(data)
+		if err != nil {
+			return err
+		}
+		errors = append(errors, validation.ValidateTokenScopes(scopes)...)
+		if err = requireScope(ctx, scopes...); err != nil {
+			return err
+		}
+		namespaceToken.Scopes = scopes
+	}
+	if len(errors) > 0 {
+		return validationErrors(ctx, errors)
+	}
+
+	// Remove the expiration if null is given and replace it otherwise
+	if value, ok := data["expires_at"]; ok && value == nil {
+		namespaceToken.ExpiresAt = nil
+	} else if expiresAt, err := parseExpiration(data); err != nil {
+		return err
+	} else if expiresAt != nil {
+		namespaceToken.ExpiresAt = expiresAt
+	}
+
+	if err = tokens.CreateOrReplace(namespaceToken); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditTokenUpdate, namespaceToken.Namespace, namespaceToken.ID)
+	return ctx.JSON(namespaceToken)
+}
+
+// EndpointDeleteNamespaceToken handles the DELETE /v1/namespaces/:namespace/tokens/:token endpoint
+func EndpointDeleteNamespaceToken(ctx *fiber.Ctx) error {
+	tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+	namespaceToken, err := requestedToken(ctx)
+	if err != nil {
+		return err
+	}
+	if err = tokens.Delete(namespaceToken.Namespace, namespaceToken.ID); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditTokenDelete, namespaceToken.Namespace, namespaceToken.ID)
+	return nil
+}
+
+// requestedToken retrieves the requested token of the current namespace
+func requestedToken(ctx *fiber.Ctx) (*shared.NamespaceToken, error) {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	tokens := ctx.Locals("__tokens").(shared.NamespaceTokenService)
+	namespaceToken, err := tokens.Token(namespace.ID, strings.ToLower(ctx.Params("token")))
+	if err != nil {
+		return nil, err
+	}
+	if namespaceToken == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that token does not exist")
+	}
+	return namespaceToken, nil
+}
+
+// parseTokenID extracts the ID embedded into a token carrying the given prefix ('<prefix><id>_<secret>')
+func parseTokenID(raw, prefix string) (string, bool) {
+	if !strings.HasPrefix(raw, prefix) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(raw, prefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// parseScopes reads the scopes of a namespace token out of a parsed JSON body ('scopes')
+func parseScopes(data map[string]interface{}) ([]shared.TokenScope, error) {
+	rawScopes, ok := data["scopes"].([]interface{})
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token scopes (expected a list of strings)")
+	}
+	scopes := make([]shared.TokenScope, 0, len(rawScopes))
+	seen := make(map[shared.TokenScope]bool, len(rawScopes))
+	for _, rawScope := range rawScopes {
+		name, ok := rawScope.(string)
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as token scopes (expected a list of strings)")
+		}
+		if scope := shared.TokenScope(name); !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -0,0 +1,301 @@
+package v1
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/utils"
+	"github.com/x0tf/server/internal/validation"
+	"github.com/x0tf/server/internal/webhook"
+	"strconv"
+	"strings"
+)
+
+// defaultDeliveryLimit is the amount of deliveries the delivery log returns if the client did not request a specific one
+const defaultDeliveryLimit = 50
+
+// createdWebhook represents a freshly created webhook including its secret, which is only revealed once
+type createdWebhook struct {
+	*shared.Webhook
+	Secret string `json:"secret"`
+}
+
+// EndpointListWebhooks handles the GET /v1/namespaces/:namespace/webhooks endpoint
+func EndpointListWebhooks(ctx *fiber.Ctx) error {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	list, err := webhooks.Webhooks(namespace.ID)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = []*shared.Webhook{}
+	}
+	return ctx.JSON(list)
+}
+
+// EndpointGetWebhook handles the GET /v1/namespaces/:namespace/webhooks/:webhook endpoint
+func EndpointGetWebhook(ctx *fiber.Ctx) error {
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(hook)
+}
+
+// EndpointCreateWebhook handles the POST /v1/namespaces/:namespace/webhooks endpoint
+func EndpointCreateWebhook(ctx *fiber.Ctx) error {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Read and validate the webhook properties
+	url, ok := data["url"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook URL")
+	}
+	events, err := parseEvents(data)
+	if err != nil {
+		return err
+	}
+	errors := append(validation.ValidateWebhookURL(url), validation.ValidateWebhookEvents(events)...)
+
+	// Use the secret chosen by the user or generate one
+	secret := utils.GenerateToken()
+	if rawSecret, ok := data["secret"]; ok {
+		if secret, ok = rawSecret.(string); !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook secret")
+		}
+		errors = append(errors, validation.ValidateWebhookSecret(secret)...)
+	}
+	if len(errors) > 0 {
+		return validationErrors(ctx, errors)
+	}
+
+	hook := &shared.Webhook{
+		Namespace: namespace.ID,
+		ID:        utils.GenerateTokenID(),
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		Active:    true,
+	}
+	if err = webhooks.CreateOrReplace(hook); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditWebhookCreate, namespace.ID, hook.ID)
+	return ctx.JSON(&createdWebhook{
+		Webhook: hook,
+		Secret:  secret,
+	})
+}
+
+// EndpointPatchWebhook handles the PATCH /v1/namespaces/:namespace/webhooks/:webhook endpoint
+func EndpointPatchWebhook(ctx *fiber.Ctx) error {
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Parse the JSON body into a map
+	var data map[string]interface{}
+	if err := ctx.BodyParser(&data); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "could not parse request body")
+	}
+
+	// Apply the requested changes
+	var errors []error
+	if rawURL, ok := data["url"]; ok {
+		url, ok := rawURL.(string)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook URL")
+		}
+		errors = append(errors, validation.ValidateWebhookURL(url)...)
+		hook.URL = url
+	}
+	if _, ok := data["events"]; ok {
+		events, err := parseEvents(data)
+		if err != nil {
+			return err
+		}
+		errors = append(errors, validation.ValidateWebhookEvents(events)...)
+		hook.Events = events
+	}
+	if rawSecret, ok := data["secret"]; ok {
+		secret, ok := rawSecret.(string)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook secret")
+		}
+		errors = append(errors, validation.ValidateWebhookSecret(secret)...)
+		hook.Secret = secret
+	}
+	if rawActive, ok := data["active"]; ok {
+		active, ok := rawActive.(bool)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook activity state")
+		}
+		hook.Active = active
+	}
+	if len(errors) > 0 {
+		return validationErrors(ctx, errors)
+	}
+
+	if err = webhooks.CreateOrReplace(hook); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditWebhookUpdate, hook.Namespace, hook.ID)
+	return ctx.JSON(hook)
+}
+
+// EndpointDeleteWebhook handles the DELETE /v1/namespaces/:namespace/webhooks/:webhook endpoint
+func EndpointDeleteWebhook(ctx *fiber.Ctx) error {
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	if err = webhooks.Delete(hook.Namespace, hook.ID); err != nil {
+		return err
+	}
+	recordAudit(ctx, shared.AuditWebhookDelete, hook.Namespace, hook.ID)
+	return nil
+}
+
+// EndpointPingWebhook handles the POST /v1/namespaces/:namespace/webhooks/:webhook/ping endpoint
+func EndpointPingWebhook(ctx *fiber.Ctx) error {
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := webhook.NewPayload(hook.Namespace, shared.WebhookPing, fiber.Map{"webhook": hook.ID})
+	if err != nil {
+		return err
+	}
+	return enqueueDelivery(ctx, webhook.NewDelivery(hook, shared.WebhookPing, payload))
+}
+
+// EndpointListWebhookDeliveries handles the GET /v1/namespaces/:namespace/webhooks/:webhook/deliveries endpoint
+func EndpointListWebhookDeliveries(ctx *fiber.Ctx) error {
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Parse the optional status filter and the requested amount of deliveries
+	status := shared.DeliveryStatus(ctx.Query("status"))
+	if status != "" && status != shared.DeliveryPending && status != shared.DeliverySucceeded && status != shared.DeliveryFailed {
+		return fiber.NewError(fiber.StatusBadRequest, "got an illegal delivery status; use one of 'pending', 'succeeded' or 'failed'")
+	}
+	limit := defaultDeliveryLimit
+	if value := ctx.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxListLimit {
+			return fiber.NewError(fiber.StatusBadRequest, "the limit has to be between 1 and "+strconv.Itoa(maxListLimit))
+		}
+	}
+
+	deliveries, err := webhooks.Deliveries(hook.Namespace, hook.ID, status, limit)
+	if err != nil {
+		return err
+	}
+	if deliveries == nil {
+		deliveries = []*shared.WebhookDelivery{}
+	}
+	return ctx.JSON(deliveries)
+}
+
+// EndpointGetWebhookDelivery handles the GET /v1/namespaces/:namespace/webhooks/:webhook/deliveries/:delivery endpoint
+func EndpointGetWebhookDelivery(ctx *fiber.Ctx) error {
+	delivery, err := requestedDelivery(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(delivery)
+}
+
+// EndpointRedeliverWebhookDelivery handles the POST /v1/namespaces/:namespace/webhooks/:webhook/deliveries/:delivery/redeliver endpoint.
+// The payload of the delivery is enqueued again as a new delivery, so the original one stays in the delivery log.
+func EndpointRedeliverWebhookDelivery(ctx *fiber.Ctx) error {
+	hook, err := requestedWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	delivery, err := requestedDelivery(ctx)
+	if err != nil {
+		return err
+	}
+	return enqueueDelivery(ctx, webhook.NewDelivery(hook, delivery.Event, delivery.Payload))
+}
+
+// enqueueDelivery enqueues a single delivery and responds with it
+func enqueueDelivery(ctx *fiber.Ctx, delivery *shared.WebhookDelivery) error {
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	if err := webhooks.Enqueue([]*shared.WebhookDelivery{delivery}); err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+// requestedWebhook retrieves the requested webhook of the current namespace
+func requestedWebhook(ctx *fiber.Ctx) (*shared.Webhook, error) {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	hook, err := webhooks.Webhook(namespace.ID, strings.ToLower(ctx.Params("webhook")))
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that webhook does not exist")
+	}
+	return hook, nil
+}
+
+// requestedDelivery retrieves the requested delivery of the requested webhook
+func requestedDelivery(ctx *fiber.Ctx) (*shared.WebhookDelivery, error) {
+	namespace := ctx.Locals("_namespace").(*shared.Namespace)
+	webhooks := ctx.Locals("__webhooks").(shared.WebhookService)
+	delivery, err := webhooks.Delivery(namespace.ID, strings.ToLower(ctx.Params("webhook")), strings.ToLower(ctx.Params("delivery")))
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "that delivery does not exist")
+	}
+	return delivery, nil
+}
+
+// parseEvents reads the events of a webhook out of a parsed JSON body ('events')
+func parseEvents(data map[string]interface{}) ([]shared.WebhookEvent, error) {
+	rawEvents, ok := data["events"].([]interface{})
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook events (expected a list of strings)")
+	}
+	events := make([]shared.WebhookEvent, 0, len(rawEvents))
+	seen := make(map[shared.WebhookEvent]bool, len(rawEvents))
+	for _, rawEvent := range rawEvents {
+		name, ok := rawEvent.(string)
+		if !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "got an illegal value as webhook events (expected a list of strings)")
+		}
+		if event := shared.WebhookEvent(name); !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// notify enqueues deliveries of an event to the webhooks of a namespace subscribed to it.
+// The event already happened at this point, so a failure is only logged instead of failing the request.
+func notify(ctx *fiber.Ctx, namespace string, event shared.WebhookEvent, data interface{}) {
+	notifier, _ := ctx.Locals("__notifier").(*webhook.Notifier)
+	if err := notifier.Notify(namespace, event, data); err != nil {
+		logging.WithRequest(ctx).WithError(err).WithField("event", event).Error("Could not enqueue the deliveries of a webhook event")
+	}
+}
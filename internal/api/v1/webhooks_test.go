@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedeliverWebhookDelivery(t *testing.T) {
+	// Start a receiver verifying the signatures of the deliveries it receives
+	var mu sync.Mutex
+	var received []string
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		timestamp, _ := strconv.ParseInt(request.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if request.Header.Get(webhook.HeaderSignature) != webhook.Sign("secret", timestamp, body) {
+			t.Error("received a delivery with an invalid signature")
+		}
+		mu.Lock()
+		received = append(received, request.Header.Get(webhook.HeaderDelivery))
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	services := memory.NewStore().Services()
+	if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: "ns", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	hook := &shared.Webhook{
+		Namespace: "ns",
+		ID:        "hook",
+		URL:       server.URL,
+		Events:    []shared.WebhookEvent{shared.WebhookElementCreated},
+		Secret:    "secret",
+		Active:    true,
+	}
+	if err := services.Webhooks.CreateOrReplace(hook); err != nil {
+		t.Fatal(err)
+	}
+
+	// Store a delivery which was already given up on
+	payload, err := webhook.NewPayload("ns", shared.WebhookElementCreated, &webhook.Element{Key: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := webhook.NewDelivery(hook, shared.WebhookElementCreated, payload)
+	failed.Status = shared.DeliveryFailed
+	failed.Attempts = 5
+	failed.NextAttemptAt = nil
+	if err := services.Webhooks.Enqueue([]*shared.WebhookDelivery{failed}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("__namespaces", services.Namespaces)
+		ctx.Locals("__webhooks", services.Webhooks)
+		ctx.Locals("_admin", true)
+		return ctx.Next()
+	})
+	app.Post("/v1/namespaces/:namespace/webhooks/:webhook/deliveries/:delivery/redeliver", MiddlewareInjectNamespace, EndpointRedeliverWebhookDelivery)
+
+	request := httptest.NewRequest(fiber.MethodPost, "/v1/namespaces/ns/webhooks/hook/deliveries/"+failed.ID+"/redeliver", nil)
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("responded with status %d, expected %d", response.StatusCode, fiber.StatusAccepted)
+	}
+	var redelivery shared.WebhookDelivery
+	if err := json.NewDecoder(response.Body).Decode(&redelivery); err != nil {
+		t.Fatal(err)
+	}
+	if redelivery.ID == failed.ID || redelivery.Status != shared.DeliveryPending {
+		t.Fatalf("responded with the %s delivery %q, expected a new pending one", redelivery.Status, redelivery.ID)
+	}
+
+	// Let a dispatcher run once to attempt the redelivery
+	dispatcher := &webhook.Dispatcher{
+		Interval:    time.Hour,
+		MaxAttempts: 1,
+		RetryDelay:  time.Minute,
+		Client:      webhook.NewClient(5*time.Second, true),
+		Webhooks:    services.Webhooks,
+	}
+	dispatcher.Start()
+	dispatcher.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0] != redelivery.ID {
+		t.Fatalf("received the deliveries %q, expected only %q", received, redelivery.ID)
+	}
+	if string(bodies[0]) != string(payload) {
+		t.Errorf("received the payload %s, expected the original one %s", bodies[0], payload)
+	}
+
+	// The original delivery stays in the delivery log untouched
+	original, err := services.Webhooks.Delivery("ns", "hook", failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original == nil || original.Status != shared.DeliveryFailed || original.Attempts != 5 {
+		t.Errorf("the original delivery changed to %+v", original)
+	}
+	stored, err := services.Webhooks.Delivery("ns", "hook", redelivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || stored.Status != shared.DeliverySucceeded {
+		t.Errorf("stored the redelivery as %+v, expected it to have succeeded", stored)
+	}
+}
@@ -0,0 +1,21 @@
+package blob
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/shared"
+)
+
+// Release deletes the blobs referenced by the given elements; failures only waste storage space, so they are logged instead of returned
+func Release(store shared.BlobStore, elements ...*shared.Element) {
+	if store == nil {
+		return
+	}
+	for _, element := range elements {
+		if element == nil || element.Blob == "" {
+			continue
+		}
+		if err := store.Delete(element.Blob); err != nil {
+			log.WithError(err).WithField("blob", element.Blob).Error("Could not delete a blob")
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidID is used when a blob ID contains characters which are not allowed in a file name
+var ErrInvalidID = errors.New("the given blob ID contains an illegal character")
+
+// idAllowedCharacters contains all characters a blob ID may contain
+var idAllowedCharacters = "abcdefghijklmnopqrstuvwxyz0123456789_-"
+
+// Store represents the local filesystem blob store
+type Store struct {
+	root string
+}
+
+// NewStore creates a new local filesystem blob store rooted at the given directory
+func NewStore(root string) (*Store, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		root: root,
+	}, nil
+}
+
+// Put writes a blob of the given size; the blob becomes visible atomically once it was written completely
+func (store *Store) Put(id string, reader io.Reader, size int64) error {
+	path, err := store.path(id)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// Write the blob into a temporary file first
+	file, err := ioutil.TempFile(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("expected a blob of %d bytes but got %d bytes", size, written)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	// Move the temporary file to its final location
+	if err = os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// Get opens a blob for reading; it returns nil if the blob does not exist
+func (store *Store) Get(id string) (io.ReadCloser, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Stat reads the metadata of a blob; it returns nil if the blob does not exist
+func (store *Store) Stat(id string) (*shared.BlobInfo, error) {
+	path, err := store.path(id)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &shared.BlobInfo{
+		ID:         id,
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime(),
+	}, nil
+}
+
+// Delete deletes a blob
+func (store *Store) Delete(id string) error {
+	path, err := store.path(id)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Walk calls the given function for every stored blob; unfinished uploads are skipped
+func (store *Store) Walk(fn func(*shared.BlobInfo) error) error {
+	return filepath.Walk(store.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		return fn(&shared.BlobInfo{
+			ID:         info.Name(),
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+	})
+}
+
+// path validates a blob ID and returns the path of the corresponding file; blobs are sharded by the first two characters of their ID
+func (store *Store) path(id string) (string, error) {
+	if len(id) < 3 {
+		return "", ErrInvalidID
+	}
+	for _, char := range id {
+		if !strings.ContainsRune(idAllowedCharacters, char) {
+			return "", ErrInvalidID
+		}
+	}
+	return filepath.Join(store.root, id[:2], id), nil
+}
@@ -0,0 +1,99 @@
+package filesystem
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("could not open the store: %v", err)
+	}
+
+	// Put a few blobs, including an empty one
+	blobs := map[string]string{"first": "first blob", "second": "second", "third": "", "fourth": "4"}
+	for id, data := range blobs {
+		if err := store.Put(id, strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("could not put the blob %q: %v", id, err)
+		}
+	}
+
+	// Uploads of an unexpected size are discarded without leaving anything behind
+	if err := store.Put("short", strings.NewReader("abc"), 5); err == nil {
+		t.Error("put a blob which is shorter than its announced size")
+	}
+
+	// Unfinished uploads are not walked
+	if err := ioutil.WriteFile(filepath.Join(root, "fi", ".upload-unfinished"), []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read a blob back along with its metadata
+	reader, err := store.Get("first")
+	if err != nil || reader == nil {
+		t.Fatalf("could not get the blob: %v", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != blobs["first"] {
+		t.Errorf("read the blob %q (%v), expected %q", data, err, blobs["first"])
+	}
+	info, err := store.Stat("second")
+	if err != nil || info == nil || info.ID != "second" || info.Size != int64(len(blobs["second"])) {
+		t.Errorf("read the metadata %+v (%v), expected the one of the second blob", info, err)
+	}
+	if reader, err = store.Get("short"); err != nil || reader != nil {
+		t.Errorf("got the blob of the failed upload (%v)", err)
+	}
+
+	// Walk every blob
+	walked := make(map[string]int64)
+	err = store.Walk(func(info *shared.BlobInfo) error {
+		walked[info.ID] = info.Size
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not walk the blobs: %v", err)
+	}
+	if len(walked) != len(blobs) {
+		t.Errorf("walked the blobs %v, expected %d of them", walked, len(blobs))
+	}
+	for id, data := range blobs {
+		if size, ok := walked[id]; !ok || size != int64(len(data)) {
+			t.Errorf("walked the blob %q with size %d, expected %d", id, size, len(data))
+		}
+	}
+
+	// Deleted blobs are gone, and deleting them again is no error
+	for i := 0; i < 2; i++ {
+		if err := store.Delete("first"); err != nil {
+			t.Fatalf("could not delete the blob: %v", err)
+		}
+	}
+	if reader, err = store.Get("first"); err != nil || reader != nil {
+		t.Errorf("got the deleted blob (%v)", err)
+	}
+	if info, err = store.Stat("first"); err != nil || info != nil {
+		t.Errorf("read the metadata of the deleted blob (%v)", err)
+	}
+}
+
+func TestStoreInvalidIDs(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "ab", "../escape", "with/slash", "UPPER", "dot.ted"} {
+		if err := store.Put(id, strings.NewReader("data"), 4); err != ErrInvalidID {
+			t.Errorf("put the blob %q with error %v, expected %v", id, err, ErrInvalidID)
+		}
+		if _, err := store.Get(id); err != ErrInvalidID {
+			t.Errorf("got the blob %q with error %v, expected %v", id, err, ErrInvalidID)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package blob
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/utils"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// OffloadingElementService represents an element service storing payloads exceeding an inline limit in a blob store.
+// Single elements are retrieved including their offloaded payloads while listings only contain their size to keep them cheap.
+type OffloadingElementService struct {
+	shared.ElementService
+	blobs       shared.BlobStore
+	inlineLimit int
+	created     *createdBlobs
+}
+
+// NewOffloadingElementService creates a new element service offloading payloads larger than inlineLimit bytes into the given blob store
+func NewOffloadingElementService(elements shared.ElementService, blobs shared.BlobStore, inlineLimit int) *OffloadingElementService {
+	return &OffloadingElementService{
+		ElementService: elements,
+		blobs:          blobs,
+		inlineLimit:    inlineLimit,
+	}
+}
+
+// Element retrieves an element and loads its offloaded payload
+func (service *OffloadingElementService) Element(namespace, key string) (*shared.Element, error) {
+	element, err := service.ElementService.Element(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return service.load(element)
+}
+
+// CreateOrReplace offloads the payload of an element into the blob store if it exceeds the inline limit and stores the element afterwards
+func (service *OffloadingElementService) CreateOrReplace(element *shared.Element) error {
+	_, err := service.write(element, func(stored *shared.Element) (bool, error) {
+		return true, service.ElementService.CreateOrReplace(stored)
+	})
+	return err
+}
+
+// Update offloads the payload of an element into the blob store if it exceeds the inline limit and updates the element afterwards
+func (service *OffloadingElementService) Update(element *shared.Element, expectedRevision int) (bool, error) {
+	return service.write(element, func(stored *shared.Element) (bool, error) {
+		return service.ElementService.Update(stored, expectedRevision)
+	})
+}
+
+// write stores an element using the given function after moving its payload into the blob store if required.
+// Elements with an offloaded payload are stored without their loaded data; callers changing the payload have to clear the blob reference.
+func (service *OffloadingElementService) write(element *shared.Element, fn func(*shared.Element) (bool, error)) (bool, error) {
+	stored := *element
+	var uploaded string
+	switch {
+	case service.offloadable(element):
+		uploaded = utils.GenerateBlobID()
+		if err := service.blobs.Put(uploaded, strings.NewReader(element.Data), int64(len(element.Data))); err != nil {
+			return false, err
+		}
+		stored.Data = ""
+		stored.Blob = uploaded
+		stored.Size = int64(len(element.Data))
+	case element.Type != shared.ElementTypeFile && element.Blob != "":
+		stored.Data = ""
+	case element.Type != shared.ElementTypeFile:
+		stored.Size = 0
+	}
+
+	// Release the uploaded payload again if the element could not be stored
+	ok, err := fn(&stored)
+	if err != nil || !ok {
+		if uploaded != "" {
+			Release(service.blobs, &stored)
+		}
+		return ok, err
+	}
+	if uploaded != "" && service.created != nil {
+		service.created.add(uploaded)
+	}
+	element.Blob = stored.Blob
+	element.Size = stored.Size
+	element.Revision = stored.Revision
+	return true, nil
+}
+
+// ConsumeView consumes a view of an element and loads its offloaded payload
+func (service *OffloadingElementService) ConsumeView(namespace, key string) (*shared.Element, error) {
+	element, err := service.ElementService.ConsumeView(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	return service.load(element)
+}
+
+// Revision retrieves a single revision of an element and loads its offloaded payload
+func (service *OffloadingElementService) Revision(namespace, key string, number int) (*shared.Revision, error) {
+	revision, err := service.ElementService.Revision(namespace, key, number)
+	if err != nil || revision == nil || revision.Type == shared.ElementTypeFile || revision.Blob == "" {
+		return revision, err
+	}
+	if revision.Data, err = service.read(revision.Blob); err != nil {
+		return nil, fmt.Errorf("could not load the payload of revision %d of element '%s/%s': %w", number, namespace, key, err)
+	}
+	return revision, nil
+}
+
+// offloadable checks whether the payload of the given element has to be stored in the blob store
+func (service *OffloadingElementService) offloadable(element *shared.Element) bool {
+	return element.Type != shared.ElementTypeFile && element.Blob == "" && len(element.Data) > service.inlineLimit
+}
+
+// load reads the offloaded payload of an element into its data field
+func (service *OffloadingElementService) load(element *shared.Element) (*shared.Element, error) {
+	if element == nil || element.Type == shared.ElementTypeFile || element.Blob == "" {
+		return element, nil
+	}
+	data, err := service.read(element.Blob)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the payload of element '%s/%s': %w", element.Namespace, element.Key, err)
+	}
+
+	loaded := *element
+	loaded.Data = data
+	return &loaded, nil
+}
+
+// read reads an offloaded payload out of the blob store
+func (service *OffloadingElementService) read(id string) (string, error) {
+	reader, err := service.blobs.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if reader == nil {
+		return "", fmt.Errorf("the blob '%s' does not exist", id)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// OffloadingTransactor represents a transactor handing out offloading element services inside of transactions
+type OffloadingTransactor struct {
+	transactor  shared.Transactor
+	blobs       shared.BlobStore
+	inlineLimit int
+}
+
+// NewOffloadingTransactor creates a new transactor wrapping the element services of the given one into offloading ones
+func NewOffloadingTransactor(transactor shared.Transactor, blobs shared.BlobStore, inlineLimit int) *OffloadingTransactor {
+	return &OffloadingTransactor{
+		transactor:  transactor,
+		blobs:       blobs,
+		inlineLimit: inlineLimit,
+	}
+}
+
+// Transaction executes the given function using offloading services bound to a single transaction.
+// Blobs uploaded inside of a transaction which gets rolled back are released again.
+func (transactor *OffloadingTransactor) Transaction(fn func(*shared.Services) error) error {
+	created := new(createdBlobs)
+	err := transactor.transactor.Transaction(func(services *shared.Services) error {
+		elements := NewOffloadingElementService(services.Elements, transactor.blobs, transactor.inlineLimit)
+		elements.created = created
+		wrapped := *services
+		wrapped.Elements = elements
+		return fn(&wrapped)
+	})
+	if err != nil {
+		for _, id := range created.ids {
+			Release(transactor.blobs, &shared.Element{Blob: id})
+		}
+	}
+	return err
+}
+
+// createdBlobs keeps track of the blobs uploaded inside of a transaction
+type createdBlobs struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+// add remembers an uploaded blob
+func (blobs *createdBlobs) add(id string) {
+	blobs.mu.Lock()
+	defer blobs.mu.Unlock()
+	blobs.ids = append(blobs.ids, id)
+}
@@ -0,0 +1,133 @@
+package blob
+
+import (
+	"bytes"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// countingBlobStore represents an in-memory blob store counting the reads of blobs
+type countingBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+	gets  int
+}
+
+func (store *countingBlobStore) Put(id string, reader io.Reader, _ int64) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.blobs[id] = data
+	return nil
+}
+
+func (store *countingBlobStore) Get(id string) (io.ReadCloser, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.gets++
+	data, ok := store.blobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (store *countingBlobStore) Stat(id string) (*shared.BlobInfo, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	data, ok := store.blobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &shared.BlobInfo{ID: id, Size: int64(len(data))}, nil
+}
+
+func (store *countingBlobStore) Delete(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.blobs, id)
+	return nil
+}
+
+func (store *countingBlobStore) Walk(fn func(*shared.BlobInfo) error) error {
+	return nil
+}
+
+func TestOffloadingElementService(t *testing.T) {
+	blobs := &countingBlobStore{blobs: make(map[string][]byte)}
+	services := memory.NewStore().Services()
+	elements := NewOffloadingElementService(services.Elements, blobs, 16)
+
+	tests := []struct {
+		key       string
+		data      string
+		offloaded bool
+	}{
+		{key: "inline", data: "short", offloaded: false},
+		{key: "large", data: strings.Repeat("large ", 10), offloaded: true},
+		{key: "missing", data: strings.Repeat("missing ", 10), offloaded: true},
+	}
+	for _, test := range tests {
+		if err := elements.CreateOrReplace(&shared.Element{Namespace: "ns", Key: test.key, Data: test.data}); err != nil {
+			t.Fatalf("could not create element %q: %v", test.key, err)
+		}
+	}
+
+	// Lose the payload of one element; listings must not depend on it
+	missing, err := services.Elements.Element("ns", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = blobs.Delete(missing.Blob); err != nil {
+		t.Fatal(err)
+	}
+
+	// List the elements without reading any payload out of the blob store
+	list, err := elements.Elements(&shared.ElementQuery{Namespace: "ns"})
+	if err != nil {
+		t.Fatalf("could not list the elements: %v", err)
+	}
+	if blobs.gets != 0 {
+		t.Errorf("listing read %d blobs, expected none", blobs.gets)
+	}
+	listed := make(map[string]*shared.Element, len(list))
+	for _, element := range list {
+		listed[element.Key] = element
+	}
+	for _, test := range tests {
+		element := listed[test.key]
+		if element == nil {
+			t.Errorf("did not list element %q", test.key)
+			continue
+		}
+		if element.Offloaded() != test.offloaded {
+			t.Errorf("listed element %q as offloaded: %t, expected %t", test.key, element.Offloaded(), test.offloaded)
+		}
+		if test.offloaded && element.Size != int64(len(test.data)) {
+			t.Errorf("listed element %q with size %d, expected %d", test.key, element.Size, len(test.data))
+		}
+		if !test.offloaded && element.Data != test.data {
+			t.Errorf("listed element %q with data %q, expected %q", test.key, element.Data, test.data)
+		}
+	}
+
+	// Load the payload of a single element
+	element, err := elements.Element("ns", "large")
+	if err != nil {
+		t.Fatalf("could not retrieve the element: %v", err)
+	}
+	if element.Data != tests[1].data || element.Offloaded() {
+		t.Errorf("retrieved element with data %q, expected its loaded payload", element.Data)
+	}
+	if blobs.gets != 1 {
+		t.Errorf("retrieving an element read %d blobs, expected 1", blobs.gets)
+	}
+}
@@ -0,0 +1,350 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// unsignedPayload represents the payload hash used to stream request bodies without hashing them upfront
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// defaultTimeout is the time connecting to the endpoint and waiting for its responses may take if no other one is configured
+	defaultTimeout = 30 * time.Second
+)
+
+// Config represents the configuration of an S3-compatible blob store
+type Config struct {
+	Endpoint  string
+	Secure    bool
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	Timeout   time.Duration
+}
+
+// ParseDSN parses a blob store DSN of the form
+// 's3://<access key>:<secret key>@<host>/<bucket>[/<prefix>][?region=<region>&insecure=true&timeout=<duration>]'
+func ParseDSN(dsn string) (Config, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return Config{}, err
+	}
+	if parsed.Scheme != "s3" || parsed.Host == "" {
+		return Config{}, errors.New("the S3 blob store DSN has to look like 's3://<access key>:<secret key>@<host>/<bucket>[/<prefix>]'")
+	}
+
+	bucket, prefix := strings.TrimPrefix(parsed.Path, "/"), ""
+	if index := strings.Index(bucket, "/"); index >= 0 {
+		bucket, prefix = bucket[:index], strings.Trim(bucket[index+1:], "/")
+	}
+	if bucket == "" {
+		return Config{}, errors.New("the S3 blob store DSN does not contain a bucket")
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	secretKey, _ := parsed.User.Password()
+	region := parsed.Query().Get("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	timeout := defaultTimeout
+	if value := parsed.Query().Get("timeout"); value != "" {
+		if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 {
+			return Config{}, fmt.Errorf("the S3 blob store DSN contains an illegal timeout '%s'", value)
+		}
+	}
+	return Config{
+		Endpoint:  parsed.Host,
+		Secure:    parsed.Query().Get("insecure") != "true",
+		Region:    region,
+		Bucket:    bucket,
+		Prefix:    prefix,
+		AccessKey: parsed.User.Username(),
+		SecretKey: secretKey,
+		Timeout:   timeout,
+	}, nil
+}
+
+// Store represents an S3-compatible blob store using path-style requests signed with AWS signature version 4
+type Store struct {
+	client *http.Client
+	config Config
+}
+
+// NewStore creates a new S3-compatible blob store and makes sure its bucket is accessible.
+// Connecting to the endpoint and waiting for the headers of its responses is bounded by the configured timeout;
+// the request as a whole is not, as blob bodies get streamed and may take arbitrarily long.
+func NewStore(config Config) (*Store, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+	store := &Store{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				IdleConnTimeout:       time.Minute,
+			},
+		},
+		config: config,
+	}
+
+	response, err := store.request(http.MethodHead, "/"+config.Bucket, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not access the S3 bucket '%s' (status %d)", config.Bucket, response.StatusCode)
+	}
+	return store, nil
+}
+
+// Put uploads a blob of the given size
+func (store *Store) Put(id string, reader io.Reader, size int64) error {
+	response, err := store.request(http.MethodPut, store.objectPath(id), nil, reader, size)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return responseError(response)
+	}
+	return nil
+}
+
+// Get opens a blob for reading; it returns nil if the blob does not exist
+func (store *Store) Get(id string) (io.ReadCloser, error) {
+	response, err := store.request(http.MethodGet, store.objectPath(id), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response.Body, nil
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, nil
+	default:
+		defer response.Body.Close()
+		return nil, responseError(response)
+	}
+}
+
+// Stat reads the metadata of a blob; it returns nil if the blob does not exist
+func (store *Store) Stat(id string) (*shared.BlobInfo, error) {
+	response, err := store.request(http.MethodHead, store.objectPath(id), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+		modifiedAt, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+		return &shared.BlobInfo{
+			ID:         id,
+			Size:       response.ContentLength,
+			ModifiedAt: modifiedAt,
+		}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, responseError(response)
+	}
+}
+
+// Delete deletes a blob
+func (store *Store) Delete(id string) error {
+	response, err := store.request(http.MethodDelete, store.objectPath(id), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return responseError(response)
+	}
+	return nil
+}
+
+// listBucketResult represents a page of a ListObjectsV2 response
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// Walk calls the given function for every stored blob
+func (store *Store) Walk(fn func(*shared.BlobInfo) error) error {
+	continuationToken := ""
+	for {
+		// Request the next page of blobs
+		query := url.Values{"list-type": {"2"}}
+		if store.config.Prefix != "" {
+			query.Set("prefix", store.config.Prefix)
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		response, err := store.request(http.MethodGet, "/"+store.config.Bucket, query, nil, 0)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode != http.StatusOK {
+			err = responseError(response)
+			response.Body.Close()
+			return err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		// Pass the blobs of the page, skipping objects in nested prefixes
+		for _, object := range result.Contents {
+			id := strings.TrimPrefix(object.Key, store.config.Prefix)
+			if id == "" || strings.Contains(id, "/") {
+				continue
+			}
+			if err = fn(&shared.BlobInfo{ID: id, Size: object.Size, ModifiedAt: object.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// objectPath builds the path-style request path of a blob
+func (store *Store) objectPath(id string) string {
+	return "/" + store.config.Bucket + "/" + store.config.Prefix + id
+}
+
+// request performs a signed request against the S3 endpoint
+func (store *Store) request(method, path string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	scheme := "https"
+	if !store.config.Secure {
+		scheme = "http"
+	}
+	target := &url.URL{
+		Scheme: scheme,
+		Host:   store.config.Endpoint,
+		Path:   path,
+		// Signature version 4 expects sorted query parameters encoding spaces as '%20'
+		RawQuery: strings.ReplaceAll(query.Encode(), "+", "%20"),
+	}
+
+	request, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.ContentLength = size
+		if size == 0 {
+			request.Body = http.NoBody
+		}
+	}
+	store.sign(request, time.Now())
+	return store.client.Do(request)
+}
+
+// sign signs a request using AWS signature version 4
+func (store *Store) sign(request *http.Request, now time.Time) {
+	timestamp := now.UTC().Format("20060102T150405Z")
+	date := timestamp[:8]
+	request.Header.Set("X-Amz-Date", timestamp)
+	request.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	scope := date + "/" + store.config.Region + "/s3/aws4_request"
+	key := signingKey(store.config.SecretKey, date, store.config.Region, "s3")
+	signature := signature(key, timestamp, scope, canonicalRequest(request, signedHeaders, unsignedPayload))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.config.AccessKey, scope, strings.Join(signedHeaders, ";"), signature,
+	))
+}
+
+// canonicalRequest builds the canonical form of a request covering the given headers, which have to be lowercase and sorted
+func canonicalRequest(request *http.Request, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := request.Header.Get(name)
+		if name == "host" {
+			value = request.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// signingKey derives the key signing the requests of the given day to a service in a region
+func signingKey(secretKey, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// signature signs the hash of a canonical request created at the given timestamp within the given credential scope
+func signature(key []byte, timestamp, scope, canonicalRequest string) string {
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 calculates the HMAC-SHA256 of the given data
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError creates an error out of an unexpected S3 response
+func responseError(response *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(response.StatusCode)
+	}
+	return fmt.Errorf("unexpected S3 response (status %s): %s", strconv.Itoa(response.StatusCode), message)
+}
@@ -0,0 +1,355 @@
+package s3
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"github.com/x0tf/server/internal/shared"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 hash of an empty payload
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func TestSigningKey(t *testing.T) {
+	// Published example of deriving a signing key
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"; hex.EncodeToString(key) != expected {
+		t.Errorf("derived the signing key %s, expected %s", hex.EncodeToString(key), expected)
+	}
+}
+
+func TestSignatureVectors(t *testing.T) {
+	// Published signature version 4 test vectors: the vanilla GET request of the test suite
+	// as well as the GET Object and ListObjects examples of the S3 documentation
+	tests := []struct {
+		name          string
+		url           string
+		headers       map[string]string
+		signedHeaders []string
+		secretKey     string
+		timestamp     string
+		region        string
+		service       string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			url:           "https://example.amazonaws.com/",
+			headers:       map[string]string{"X-Amz-Date": "20150830T123600Z"},
+			signedHeaders: []string{"host", "x-amz-date"},
+			secretKey:     "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			timestamp:     "20150830T123600Z",
+			region:        "us-east-1",
+			service:       "service",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name: "s3 get object",
+			url:  "https://examplebucket.s3.amazonaws.com/test.txt",
+			headers: map[string]string{
+				"Range":                "bytes=0-9",
+				"X-Amz-Content-Sha256": emptyPayloadHash,
+				"X-Amz-Date":           "20130524T000000Z",
+			},
+			signedHeaders: []string{"host", "range", "x-amz-content-sha256", "x-amz-date"},
+			secretKey:     "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+			timestamp:     "20130524T000000Z",
+			region:        "us-east-1",
+			service:       "s3",
+			signature:     "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41",
+		},
+		{
+			name: "s3 list objects",
+			url:  "https://examplebucket.s3.amazonaws.com/?max-keys=2&prefix=J",
+			headers: map[string]string{
+				"X-Amz-Content-Sha256": emptyPayloadHash,
+				"X-Amz-Date":           "20130524T000000Z",
+			},
+			signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"},
+			secretKey:     "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+			timestamp:     "20130524T000000Z",
+			region:        "us-east-1",
+			service:       "s3",
+			signature:     "34b48302e7b5fa45bde8084f4b7868a86f0a534bc59db6670ed5711ef69dc6f7",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+			date := test.timestamp[:8]
+			scope := date + "/" + test.region + "/" + test.service + "/aws4_request"
+			key := signingKey(test.secretKey, date, test.region, test.service)
+			if signature := signature(key, test.timestamp, scope, canonicalRequest(request, test.signedHeaders, emptyPayloadHash)); signature != test.signature {
+				t.Errorf("calculated the signature %s, expected %s", signature, test.signature)
+			}
+		})
+	}
+}
+
+// bucket represents an S3 stand-in serving a single bucket out of memory and verifying the signatures of the requests it receives
+type bucket struct {
+	t        *testing.T
+	config   Config
+	pageSize int
+	mu       sync.Mutex
+	objects  map[string][]byte
+}
+
+func (bucket *bucket) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if !bucket.verify(request) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	path := strings.TrimPrefix(request.URL.Path, "/"+bucket.config.Bucket)
+	if path == "" {
+		switch request.Method {
+		case http.MethodHead:
+			writer.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			bucket.list(writer, request)
+		default:
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	key := strings.TrimPrefix(path, "/")
+	switch request.Method {
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(request.Body)
+		if err != nil || int64(len(data)) != request.ContentLength {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		bucket.objects[key] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := bucket.objects[key]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		writer.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if request.Method == http.MethodGet {
+			_, _ = writer.Write(data)
+		}
+	case http.MethodDelete:
+		delete(bucket.objects, key)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recalculates the signature of a request and compares it with the one it carries
+func (bucket *bucket) verify(request *http.Request) bool {
+	timestamp := request.Header.Get("X-Amz-Date")
+	if len(timestamp) != 16 {
+		bucket.t.Errorf("received the illegal timestamp %q", timestamp)
+		return false
+	}
+	date := timestamp[:8]
+	scope := date + "/" + bucket.config.Region + "/s3/aws4_request"
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	request.URL.Host = request.Host
+	signature := signature(
+		signingKey(bucket.config.SecretKey, date, bucket.config.Region, "s3"),
+		timestamp,
+		scope,
+		canonicalRequest(request, signedHeaders, request.Header.Get("X-Amz-Content-Sha256")),
+	)
+	expected := "AWS4-HMAC-SHA256 Credential=" + bucket.config.AccessKey + "/" + scope +
+		", SignedHeaders=" + strings.Join(signedHeaders, ";") + ", Signature=" + signature
+	if authorization := request.Header.Get("Authorization"); authorization != expected {
+		bucket.t.Errorf("received the authorization %q, expected %q", authorization, expected)
+		return false
+	}
+	return true
+}
+
+// list responds with a page of a ListObjectsV2 request
+func (bucket *bucket) list(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Query().Get("list-type") != "2" {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	prefix := request.URL.Query().Get("prefix")
+	keys := make([]string, 0, len(bucket.objects))
+	for key := range bucket.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// Continuation tokens are the index of the first key of the next page
+	start, _ := strconv.Atoi(request.URL.Query().Get("continuation-token"))
+	end := start + bucket.pageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+	type object struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool     `xml:"IsTruncated"`
+		NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+		Contents              []object `xml:"Contents"`
+	}{}
+	for _, key := range keys[start:end] {
+		result.Contents = append(result.Contents, object{Key: key, Size: int64(len(bucket.objects[key])), LastModified: time.Now().UTC()})
+	}
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	writer.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(writer).Encode(&result)
+}
+
+func TestStore(t *testing.T) {
+	config := Config{
+		Region:    "eu-central-1",
+		Bucket:    "bucket",
+		Prefix:    "blobs/",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Timeout:   5 * time.Second,
+	}
+	bucket := &bucket{t: t, config: config, pageSize: 2, objects: make(map[string][]byte)}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+	config.Endpoint = strings.TrimPrefix(server.URL, "http://")
+
+	store, err := NewStore(config)
+	if err != nil {
+		t.Fatalf("could not open the store: %v", err)
+	}
+
+	// Put a few blobs, including an empty one
+	blobs := map[string]string{"first": "first blob", "second": "second", "third": "", "fourth": "4", "fifth": "fifth blob"}
+	for id, data := range blobs {
+		if err := store.Put(id, strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("could not put the blob %q: %v", id, err)
+		}
+	}
+	bucket.mu.Lock()
+	bucket.objects["unrelated"] = []byte("outside of the prefix")
+	bucket.objects["blobs/nested/object"] = []byte("in a nested prefix")
+	bucket.mu.Unlock()
+
+	// Read a blob back along with its metadata
+	reader, err := store.Get("first")
+	if err != nil || reader == nil {
+		t.Fatalf("could not get the blob: %v", err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != blobs["first"] {
+		t.Errorf("read the blob %q (%v), expected %q", data, err, blobs["first"])
+	}
+	info, err := store.Stat("second")
+	if err != nil || info == nil || info.ID != "second" || info.Size != int64(len(blobs["second"])) {
+		t.Errorf("read the metadata %+v (%v), expected the one of the second blob", info, err)
+	}
+
+	// Walk every blob across multiple pages
+	walked := make(map[string]int64)
+	err = store.Walk(func(info *shared.BlobInfo) error {
+		walked[info.ID] = info.Size
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not walk the blobs: %v", err)
+	}
+	if len(walked) != len(blobs) {
+		t.Errorf("walked the blobs %v, expected %d of them", walked, len(blobs))
+	}
+	for id, data := range blobs {
+		if size, ok := walked[id]; !ok || size != int64(len(data)) {
+			t.Errorf("walked the blob %q with size %d, expected %d", id, size, len(data))
+		}
+	}
+
+	// Deleted blobs are gone, and deleting them again is no error
+	for i := 0; i < 2; i++ {
+		if err := store.Delete("first"); err != nil {
+			t.Fatalf("could not delete the blob: %v", err)
+		}
+	}
+	if reader, err = store.Get("first"); err != nil || reader != nil {
+		t.Errorf("got the deleted blob (%v)", err)
+	}
+	if info, err = store.Stat("first"); err != nil || info != nil {
+		t.Errorf("read the metadata of the deleted blob (%v)", err)
+	}
+}
+
+func TestStoreTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	started := time.Now()
+	_, err := NewStore(Config{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Region:   "us-east-1",
+		Bucket:   "bucket",
+		Timeout:  100 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("opened a store whose endpoint never responds")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("gave up on the endpoint after %v, expected the timeout to apply", elapsed)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	config, err := ParseDSN("s3://access:secret@localhost:9000/bucket/some/prefix?insecure=true&timeout=5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{
+		Endpoint:  "localhost:9000",
+		Secure:    false,
+		Region:    "us-east-1",
+		Bucket:    "bucket",
+		Prefix:    "some/prefix/",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Timeout:   5 * time.Second,
+	}
+	if config != expected {
+		t.Errorf("parsed the DSN into %+v, expected %+v", config, expected)
+	}
+
+	if config, err = ParseDSN("s3://access:secret@localhost/bucket"); err != nil || config.Timeout != defaultTimeout || !config.Secure {
+		t.Errorf("parsed the DSN into %+v (%v), expected a secure one with the default timeout", config, err)
+	}
+	for _, dsn := range []string{"s3://access:secret@localhost/", "file:///tmp", "s3://localhost/bucket?timeout=never"} {
+		if _, err := ParseDSN(dsn); err == nil {
+			t.Errorf("parsed the illegal DSN %q", dsn)
+		}
+	}
+}
@@ -3,28 +3,97 @@ package config
 import (
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
-	DatabaseDSN         string
-	APIAddress          string
-	GatewayAddress      string
-	GatewayRootRedirect string
-	Invites             bool
-	AdminTokens         []string
+	DatabaseDSN             string
+	APIAddress              string
+	GatewayAddress          string
+	GatewayRootRedirect     string
+	GatewayMarkdownTemplate string
+	AccessFlushInterval     time.Duration
+	Analytics               bool
+	AnalyticsBufferSize     int
+	AnalyticsGeoIPDatabase  string
+	Invites                 bool
+	AdminTokens             []string
+	TokenDigestKey          string
+	ReaperInterval          time.Duration
+	BlobStoreDSN            string
+	BlobInlineLimit         int
+	BlobSweepInterval       time.Duration
+	APIBodyLimit            int
+	MetricsAddress          string
+	ShutdownDrainDelay      time.Duration
+	TrustedProxies          []string
+	WebhookInterval         time.Duration
+	WebhookMaxAttempts      int
+	WebhookRetryDelay       time.Duration
+	WebhookTimeout          time.Duration
+	WebhookPrivateNetworks  bool
 }
 
 // Load loads and creates a new application configuration
 func Load() (*Config, bool) {
 	err := godotenv.Load()
 	return &Config{
-		DatabaseDSN:         os.Getenv("X0_DATABASE_DSN"),
-		APIAddress:          os.Getenv("X0_API_ADDRESS"),
-		GatewayAddress:      os.Getenv("X0_GATEWAY_ADDRESS"),
-		GatewayRootRedirect: os.Getenv("X0_GATEWAY_ROOT_REDIRECT"),
-		Invites:             os.Getenv("X0_INVITES") != "",
-		AdminTokens:         strings.Split(os.Getenv("X0_ADMIN_TOKENS"), ";;"),
+		DatabaseDSN:             os.Getenv("X0_DATABASE_DSN"),
+		APIAddress:              os.Getenv("X0_API_ADDRESS"),
+		GatewayAddress:          os.Getenv("X0_GATEWAY_ADDRESS"),
+		GatewayRootRedirect:     os.Getenv("X0_GATEWAY_ROOT_REDIRECT"),
+		GatewayMarkdownTemplate: os.Getenv("X0_GATEWAY_MARKDOWN_TEMPLATE"),
+		AccessFlushInterval:     getDuration("X0_ACCESS_FLUSH_INTERVAL", 30*time.Second),
+		Analytics:               os.Getenv("X0_ANALYTICS") != "",
+		AnalyticsBufferSize:     getInt("X0_ANALYTICS_BUFFER_SIZE", 4096),
+		AnalyticsGeoIPDatabase:  os.Getenv("X0_ANALYTICS_GEOIP_DATABASE"),
+		Invites:                 os.Getenv("X0_INVITES") != "",
+		AdminTokens:             getList("X0_ADMIN_TOKENS", ";;"),
+		TokenDigestKey:          os.Getenv("X0_TOKEN_DIGEST_KEY"),
+		ReaperInterval:          getDuration("X0_REAPER_INTERVAL", time.Minute),
+		BlobStoreDSN:            os.Getenv("X0_BLOB_STORE_DSN"),
+		BlobInlineLimit:         getInt("X0_BLOB_INLINE_LIMIT", 64*1024),
+		BlobSweepInterval:       getDuration("X0_BLOB_SWEEP_INTERVAL", time.Hour),
+		APIBodyLimit:            getInt("X0_API_BODY_LIMIT", 4*1024*1024),
+		MetricsAddress:          os.Getenv("X0_METRICS_ADDRESS"),
+		ShutdownDrainDelay:      getDuration("X0_SHUTDOWN_DRAIN_DELAY", 0),
+		TrustedProxies:          getList("X0_TRUSTED_PROXIES", ","),
+		WebhookInterval:         getDuration("X0_WEBHOOK_INTERVAL", 5*time.Second),
+		WebhookMaxAttempts:      getInt("X0_WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryDelay:       getDuration("X0_WEBHOOK_RETRY_DELAY", 30*time.Second),
+		WebhookTimeout:          getDuration("X0_WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookPrivateNetworks:  os.Getenv("X0_WEBHOOK_PRIVATE_NETWORKS") != "",
 	}, err == nil
 }
+
+// getDuration reads a positive duration out of an environment variable and falls back to the given default value if it is missing or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// getInt reads a positive integer out of an environment variable and falls back to the given default value if it is missing or invalid
+func getInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// getList reads a list separated by the given separator out of an environment variable, omitting empty entries
+func getList(key, separator string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), separator) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"time"
+)
+
+// AdminTokenService represents the in-memory admin token service
+type AdminTokenService struct {
+	store *Store
+}
+
+// Token searches for an admin token by its ID
+func (service *AdminTokenService) Token(id string) (*shared.AdminToken, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	token, ok := service.store.data.adminTokens[id]
+	if !ok {
+		return nil, nil
+	}
+	tokenCopy := *token
+	return &tokenCopy, nil
+}
+
+// Tokens searches for all admin tokens ordered by their creation time
+func (service *AdminTokenService) Tokens() ([]*shared.AdminToken, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var tokens []*shared.AdminToken
+	for _, token := range service.store.data.adminTokens {
+		tokenCopy := *token
+		tokens = append(tokens, &tokenCopy)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// CreateOrReplace creates or replaces an admin token; the creation time of an existing token is kept
+func (service *AdminTokenService) CreateOrReplace(token *shared.AdminToken) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	if existing, ok := service.store.data.adminTokens[token.ID]; ok {
+		token.CreatedAt = existing.CreatedAt
+	} else if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	tokenCopy := *token
+	service.store.data.adminTokens[token.ID] = &tokenCopy
+	return nil
+}
+
+// Delete deletes an admin token
+func (service *AdminTokenService) Delete(id string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	delete(service.store.data.adminTokens, id)
+	return nil
+}
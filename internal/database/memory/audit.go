@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// AuditService represents the in-memory audit log service
+type AuditService struct {
+	store *Store
+}
+
+// Record appends an entry to the audit log and assigns its ID and creation time
+func (service *AuditService) Record(entry *shared.AuditEntry) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	entry.ID = 1
+	if count := len(service.store.data.audit); count > 0 {
+		entry.ID = service.store.data.audit[count-1].ID + 1
+	}
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entryCopy := *entry
+	service.store.data.audit = append(service.store.data.audit, &entryCopy)
+	return nil
+}
+
+// Entries searches for the audit log entries matching the given query
+func (service *AuditService) Entries(query *shared.AuditQuery) ([]*shared.AuditEntry, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	entries := service.store.data.audit
+	count := len(entries)
+	var listed []*shared.AuditEntry
+	for i := 0; i < count; i++ {
+		entry := entries[i]
+		if query.Descending {
+			entry = entries[count-1-i]
+		}
+		if !matchesAuditQuery(entry, query) {
+			continue
+		}
+		entryCopy := *entry
+		listed = append(listed, &entryCopy)
+		if query.Limit > 0 && len(listed) == query.Limit {
+			break
+		}
+	}
+	return listed, nil
+}
+
+// matchesAuditQuery checks whether an audit log entry matches the filters and the cursor of the given query
+func matchesAuditQuery(entry *shared.AuditEntry, query *shared.AuditQuery) bool {
+	if query.AfterID > 0 {
+		if query.Descending && entry.ID >= query.AfterID || !query.Descending && entry.ID <= query.AfterID {
+			return false
+		}
+	}
+	if query.Actor != "" && entry.Actor != query.Actor {
+		return false
+	}
+	if query.Action != "" && entry.Action != query.Action {
+		return false
+	}
+	if query.Namespace != "" && entry.Namespace != query.Namespace {
+		return false
+	}
+	return (query.CreatedAfter == nil || entry.CreatedAt.After(*query.CreatedAfter)) &&
+		(query.CreatedBefore == nil || entry.CreatedAt.Before(*query.CreatedBefore))
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ElementService represents the in-memory element service
+type ElementService struct {
+	store *Store
+}
+
+// elementID represents the primary key of an element
+type elementID struct {
+	namespace string
+	key       string
+}
+
+// Element searches for a single element with a specific key in a specific namespace
+func (service *ElementService) Element(namespace, key string) (*shared.Element, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	element, ok := service.store.data.elements[elementID{namespace: namespace, key: key}]
+	if !ok {
+		return nil, nil
+	}
+	elementCopy := *element
+	return &elementCopy, nil
+}
+
+// Elements searches for the elements matching the given query
+func (service *ElementService) Elements(query *shared.ElementQuery) ([]*shared.Element, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var elements []*shared.Element
+	for _, element := range service.store.data.elements {
+		if matchesElementQuery(element, query) {
+			elementCopy := *element
+			elements = append(elements, &elementCopy)
+		}
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		return elementBefore(elements[i], elements[j], query.Sort)
+	})
+
+	// Skip every element up to and including the cursor element and apply the limit
+	if query.After != nil {
+		elements = elements[sort.Search(len(elements), func(i int) bool {
+			return elementBefore(query.After, elements[i], query.Sort)
+		}):]
+	}
+	if query.Limit > 0 && len(elements) > query.Limit {
+		elements = elements[:query.Limit]
+	}
+	return elements, nil
+}
+
+// CreateOrReplace creates or replaces an element
+func (service *ElementService) CreateOrReplace(element *shared.Element) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	// Continue the revision counter of a replaced element
+	id := elementID{namespace: element.Namespace, key: element.Key}
+	element.Revision = 1
+	element.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	element.UpdatedAt = element.CreatedAt
+	if existing, ok := service.store.data.elements[id]; ok {
+		element.Revision = existing.Revision + 1
+	}
+
+	elementCopy := *element
+	service.store.data.elements[id] = &elementCopy
+	service.record(id, &elementCopy)
+	return nil
+}
+
+// Update replaces an existing element if its revision still matches the expected one and increments the revision;
+// it returns false if the element does not exist anymore or was modified concurrently
+func (service *ElementService) Update(element *shared.Element, expectedRevision int) (bool, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	id := elementID{namespace: element.Namespace, key: element.Key}
+	existing, ok := service.store.data.elements[id]
+	if !ok || existing.Revision != expectedRevision {
+		return false, nil
+	}
+
+	element.Revision = expectedRevision + 1
+	element.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	element.LastAccessedAt = existing.LastAccessedAt
+	elementCopy := *element
+	service.store.data.elements[id] = &elementCopy
+	service.record(id, &elementCopy)
+	return true, nil
+}
+
+// Delete deletes an element
+func (service *ElementService) Delete(namespace, key string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	id := elementID{namespace: namespace, key: key}
+	delete(service.store.data.elements, id)
+	delete(service.store.data.revisions, id)
+	delete(service.store.data.hits, id)
+	return nil
+}
+
+// DeleteInNamespace deletes every element in a namespace and returns the deleted elements
+func (service *ElementService) DeleteInNamespace(namespace string) ([]*shared.Element, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	var elements []*shared.Element
+	for id, element := range service.store.data.elements {
+		if id.namespace == namespace {
+			delete(service.store.data.elements, id)
+			delete(service.store.data.revisions, id)
+			delete(service.store.data.hits, id)
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
+
+// DeleteExpired deletes every element which reached its expiration time or has no views left and returns the deleted elements
+func (service *ElementService) DeleteExpired() ([]*shared.Element, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	var elements []*shared.Element
+	for id, element := range service.store.data.elements {
+		if element.Expired() || element.Exhausted() {
+			delete(service.store.data.elements, id)
+			delete(service.store.data.revisions, id)
+			delete(service.store.data.hits, id)
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
+
+// ConsumeView atomically decrements the remaining views of a view-limited element and returns the updated element;
+// it returns nil if the element does not exist, is not view-limited or has no views left
+func (service *ElementService) ConsumeView(namespace, key string) (*shared.Element, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	id := elementID{namespace: namespace, key: key}
+	element, ok := service.store.data.elements[id]
+	if !ok || element.RemainingViews == nil || *element.RemainingViews <= 0 {
+		return nil, nil
+	}
+
+	remainingViews := *element.RemainingViews - 1
+	updated := *element
+	updated.RemainingViews = &remainingViews
+	service.store.data.elements[id] = &updated
+
+	elementCopy := updated
+	return &elementCopy, nil
+}
+
+// Touch records the given accesses of elements unless a later access was already recorded and returns the ones which were the first accesses of their elements
+func (service *ElementService) Touch(accesses []*shared.Access) ([]*shared.Access, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	var first []*shared.Access
+	for _, access := range accesses {
+		id := elementID{namespace: access.Namespace, key: access.Key}
+		element, ok := service.store.data.elements[id]
+		if !ok || (element.LastAccessedAt != nil && !element.LastAccessedAt.Before(access.AccessedAt)) {
+			continue
+		}
+		if element.LastAccessedAt == nil {
+			first = append(first, access)
+		}
+		accessedAt := access.AccessedAt
+		updated := *element
+		updated.LastAccessedAt = &accessedAt
+		service.store.data.elements[id] = &updated
+	}
+	return first, nil
+}
+
+// Revisions searches for all revisions of an element ordered by their number
+func (service *ElementService) Revisions(namespace, key string) ([]*shared.Revision, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	revisions := service.store.data.revisions[elementID{namespace: namespace, key: key}]
+	copies := make([]*shared.Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionCopy := *revision
+		copies = append(copies, &revisionCopy)
+	}
+	return copies, nil
+}
+
+// Revision searches for a single revision of an element
+func (service *ElementService) Revision(namespace, key string, number int) (*shared.Revision, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	for _, revision := range service.store.data.revisions[elementID{namespace: namespace, key: key}] {
+		if revision.Revision == number {
+			revisionCopy := *revision
+			return &revisionCopy, nil
+		}
+	}
+	return nil, nil
+}
+
+// ReferencedBlobs searches for the IDs of all blobs referenced by elements or their revisions
+func (service *ElementService) ReferencedBlobs() ([]string, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var ids []string
+	for _, element := range service.store.data.elements {
+		if element.Blob != "" {
+			ids = append(ids, element.Blob)
+		}
+	}
+	for _, revisions := range service.store.data.revisions {
+		for _, revision := range revisions {
+			if revision.Blob != "" {
+				ids = append(ids, revision.Blob)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// record appends a revision of the given element to its history; the store has to be locked exclusively
+func (service *ElementService) record(id elementID, element *shared.Element) {
+	existing := service.store.data.revisions[id]
+	revisions := make([]*shared.Revision, 0, len(existing)+1)
+	revisions = append(revisions, existing...)
+	service.store.data.revisions[id] = append(revisions, shared.NewRevision(element, time.Now().UTC()))
+}
+
+// matchesElementQuery checks whether an element passes the filters of the given query
+func matchesElementQuery(element *shared.Element, query *shared.ElementQuery) bool {
+	switch {
+	case query.Namespace != "" && element.Namespace != query.Namespace:
+		return false
+	case query.Type != nil && element.Type != *query.Type:
+		return false
+	case !strings.HasPrefix(element.Key, query.KeyPrefix):
+		return false
+	case query.CreatedAfter != nil && !element.CreatedAt.After(*query.CreatedAfter):
+		return false
+	case query.CreatedBefore != nil && !element.CreatedAt.Before(*query.CreatedBefore):
+		return false
+	default:
+		return true
+	}
+}
+
+// elementBefore checks whether an element is listed before another one in the given sort order
+func elementBefore(a, b *shared.Element, order shared.SortOrder) bool {
+	if order.Descending() {
+		a, b = b, a
+	}
+	if (order == shared.SortByCreation || order == shared.SortByCreationDescending) && !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Key < b.Key
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"time"
+)
+
+// HitService represents the in-memory hit service
+type HitService struct {
+	store *Store
+}
+
+// Record stores the given hits; hits of elements which do not exist anymore are dropped
+func (service *HitService) Record(hits []*shared.Hit) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	// Group the hits by their element to copy every hit list only once
+	recorded := make(map[elementID][]*shared.Hit)
+	for _, hit := range hits {
+		id := elementID{namespace: hit.Namespace, key: hit.Key}
+		if _, ok := service.store.data.elements[id]; !ok {
+			continue
+		}
+		hitCopy := *hit
+		recorded[id] = append(recorded[id], &hitCopy)
+	}
+	for id, added := range recorded {
+		existing := service.store.data.hits[id]
+		updated := make([]*shared.Hit, 0, len(existing)+len(added))
+		updated = append(updated, existing...)
+		service.store.data.hits[id] = append(updated, added...)
+	}
+	return nil
+}
+
+// Stats aggregates the hits of an element since the given time
+func (service *HitService) Stats(namespace, key string, since time.Time) (*shared.HitStats, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	days := make(map[string]int)
+	referrers := make(map[string]int)
+	userAgents := make(map[string]int)
+	countries := make(map[string]int)
+	stats := new(shared.HitStats)
+	for _, hit := range service.store.data.hits[elementID{namespace: namespace, key: key}] {
+		if hit.HitAt.Before(since) {
+			continue
+		}
+		stats.Total++
+		days[hit.HitAt.UTC().Format("2006-01-02")]++
+		referrers[hit.ReferrerHost]++
+		userAgents[string(hit.UserAgentClass)]++
+		countries[hit.Country]++
+	}
+
+	stats.Days = hitCounts(days)
+	sort.Slice(stats.Days, func(i, j int) bool {
+		return stats.Days[i].Value < stats.Days[j].Value
+	})
+	stats.Referrers = hitCounts(referrers)
+	stats.UserAgents = hitCounts(userAgents)
+	stats.Countries = hitCounts(countries)
+	return stats, nil
+}
+
+// hitCounts converts counted values into hit counts ordered by their amount of hits
+func hitCounts(counts map[string]int) []*shared.HitCount {
+	result := make([]*shared.HitCount, 0, len(counts))
+	for value, hits := range counts {
+		result = append(result, &shared.HitCount{
+			Value: value,
+			Hits:  hits,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Hits != result[j].Hits {
+			return result[i].Hits > result[j].Hits
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+)
+
+// InviteService represents the in-memory invite service
+type InviteService struct {
+	store *Store
+}
+
+// IsValid searches for a single invite with a specific token
+func (service *InviteService) IsValid(token string) (bool, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	_, ok := service.store.data.invites[shared.Invite(token)]
+	return ok, nil
+}
+
+// Invites searches for all invites
+func (service *InviteService) Invites() ([]shared.Invite, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var invites []shared.Invite
+	for invite := range service.store.data.invites {
+		invites = append(invites, invite)
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i] < invites[j]
+	})
+	return invites, nil
+}
+
+// Create creates an invite
+func (service *InviteService) Create(invite shared.Invite) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	service.store.data.invites[invite] = struct{}{}
+	return nil
+}
+
+// Delete deletes an invite
+func (service *InviteService) Delete(token string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	delete(service.store.data.invites, shared.Invite(token))
+	return nil
+}
+
+// Consume deletes an invite and reports whether it existed
+func (service *InviteService) Consume(token string) (bool, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	_, ok := service.store.data.invites[shared.Invite(token)]
+	delete(service.store.data.invites, shared.Invite(token))
+	return ok, nil
+}
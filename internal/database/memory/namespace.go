@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NamespaceService represents the in-memory namespace service
+type NamespaceService struct {
+	store *Store
+}
+
+// Namespace searches for a namespace by its ID
+func (service *NamespaceService) Namespace(id string) (*shared.Namespace, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	namespace, ok := service.store.data.namespaces[id]
+	if !ok {
+		return nil, nil
+	}
+	namespaceCopy := *namespace
+	return &namespaceCopy, nil
+}
+
+// Namespaces searches for the namespaces matching the given query
+func (service *NamespaceService) Namespaces(query *shared.NamespaceQuery) ([]*shared.Namespace, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var namespaces []*shared.Namespace
+	for _, namespace := range service.store.data.namespaces {
+		if matchesNamespaceQuery(namespace, query) {
+			namespaceCopy := *namespace
+			namespaces = append(namespaces, &namespaceCopy)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaceBefore(namespaces[i], namespaces[j], query.Sort)
+	})
+
+	// Skip every namespace up to and including the cursor namespace and apply the limit
+	if query.After != nil {
+		namespaces = namespaces[sort.Search(len(namespaces), func(i int) bool {
+			return namespaceBefore(query.After, namespaces[i], query.Sort)
+		}):]
+	}
+	if query.Limit > 0 && len(namespaces) > query.Limit {
+		namespaces = namespaces[:query.Limit]
+	}
+	return namespaces, nil
+}
+
+// CreateOrReplace creates or replaces a namespace; the creation and access times of an existing namespace are kept
+func (service *NamespaceService) CreateOrReplace(namespace *shared.Namespace) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if existing, ok := service.store.data.namespaces[namespace.ID]; ok {
+		namespace.CreatedAt = existing.CreatedAt
+		namespace.LastAccessedAt = existing.LastAccessedAt
+	} else if namespace.CreatedAt.IsZero() {
+		namespace.CreatedAt = now
+	}
+	namespace.UpdatedAt = now
+
+	namespaceCopy := *namespace
+	service.store.data.namespaces[namespace.ID] = &namespaceCopy
+	return nil
+}
+
+// Create creates a namespace unless one with the same ID already exists; it returns false if one does
+func (service *NamespaceService) Create(namespace *shared.Namespace) (bool, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	if _, ok := service.store.data.namespaces[namespace.ID]; ok {
+		return false, nil
+	}
+	namespace.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	namespace.UpdatedAt = namespace.CreatedAt
+
+	namespaceCopy := *namespace
+	service.store.data.namespaces[namespace.ID] = &namespaceCopy
+	return true, nil
+}
+
+// Delete deletes a namespace and its tokens
+func (service *NamespaceService) Delete(id string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	delete(service.store.data.namespaces, id)
+	for token := range service.store.data.tokens {
+		if token.namespace == id {
+			delete(service.store.data.tokens, token)
+		}
+	}
+	service.store.data.deleteWebhooks(func(webhook webhookID) bool {
+		return webhook.namespace == id
+	})
+	return nil
+}
+
+// Touch records the given accesses of elements as accesses of their namespaces unless a later access was already recorded
+func (service *NamespaceService) Touch(accesses []*shared.Access) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	for _, access := range accesses {
+		namespace, ok := service.store.data.namespaces[access.Namespace]
+		if !ok || (namespace.LastAccessedAt != nil && !namespace.LastAccessedAt.Before(access.AccessedAt)) {
+			continue
+		}
+		accessedAt := access.AccessedAt
+		updated := *namespace
+		updated.LastAccessedAt = &accessedAt
+		service.store.data.namespaces[access.Namespace] = &updated
+	}
+	return nil
+}
+
+// matchesNamespaceQuery checks whether a namespace passes the filters of the given query
+func matchesNamespaceQuery(namespace *shared.Namespace, query *shared.NamespaceQuery) bool {
+	switch {
+	case !strings.HasPrefix(namespace.ID, query.IDPrefix):
+		return false
+	case query.CreatedAfter != nil && !namespace.CreatedAt.After(*query.CreatedAfter):
+		return false
+	case query.CreatedBefore != nil && !namespace.CreatedAt.Before(*query.CreatedBefore):
+		return false
+	default:
+		return true
+	}
+}
+
+// namespaceBefore checks whether a namespace is listed before another one in the given sort order
+func namespaceBefore(a, b *shared.Namespace, order shared.SortOrder) bool {
+	if order.Descending() {
+		a, b = b, a
+	}
+	if (order == shared.SortByCreation || order == shared.SortByCreationDescending) && !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// ReplaceToken replaces the token hash of a namespace if it still equals the previous one
+func (service *NamespaceService) ReplaceToken(id, previous, replacement string) (bool, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	namespace, ok := service.store.data.namespaces[id]
+	if !ok || namespace.Token != previous {
+		return false, nil
+	}
+	updated := *namespace
+	updated.Token = replacement
+	service.store.data.namespaces[id] = &updated
+	return true, nil
+}
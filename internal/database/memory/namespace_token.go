@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"time"
+)
+
+// NamespaceTokenService represents the in-memory namespace token service
+type NamespaceTokenService struct {
+	store *Store
+}
+
+// tokenID represents the identifier of a namespace token
+type tokenID struct {
+	namespace string
+	id        string
+}
+
+// Token searches for a namespace token by its ID
+func (service *NamespaceTokenService) Token(namespace, id string) (*shared.NamespaceToken, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	token, ok := service.store.data.tokens[tokenID{namespace: namespace, id: id}]
+	if !ok {
+		return nil, nil
+	}
+	return copyToken(token), nil
+}
+
+// Tokens searches for all tokens of a namespace ordered by their creation time
+func (service *NamespaceTokenService) Tokens(namespace string) ([]*shared.NamespaceToken, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var tokens []*shared.NamespaceToken
+	for id, token := range service.store.data.tokens {
+		if id.namespace == namespace {
+			tokens = append(tokens, copyToken(token))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// CreateOrReplace creates or replaces a namespace token; the creation time of an existing token is kept
+func (service *NamespaceTokenService) CreateOrReplace(token *shared.NamespaceToken) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	id := tokenID{namespace: token.Namespace, id: token.ID}
+	if existing, ok := service.store.data.tokens[id]; ok {
+		token.CreatedAt = existing.CreatedAt
+	} else if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	service.store.data.tokens[id] = copyToken(token)
+	return nil
+}
+
+// Delete deletes a namespace token
+func (service *NamespaceTokenService) Delete(namespace, id string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	delete(service.store.data.tokens, tokenID{namespace: namespace, id: id})
+	return nil
+}
+
+// ReplaceHash replaces the hash of a namespace token if it still equals the previous one
+func (service *NamespaceTokenService) ReplaceHash(namespace, id, previous, replacement string) (bool, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	key := tokenID{namespace: namespace, id: id}
+	token, ok := service.store.data.tokens[key]
+	if !ok || token.Hash != previous {
+		return false, nil
+	}
+	updated := copyToken(token)
+	updated.Hash = replacement
+	service.store.data.tokens[key] = updated
+	return true, nil
+}
+
+// copyToken copies a namespace token including its scopes
+func copyToken(token *shared.NamespaceToken) *shared.NamespaceToken {
+	tokenCopy := *token
+	tokenCopy.Scopes = append([]shared.TokenScope(nil), token.Scopes...)
+	return &tokenCopy
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"github.com/x0tf/server/internal/shared"
+	"sync"
+)
+
+// Store represents the in-memory storage backend
+type Store struct {
+	mu   sync.RWMutex
+	data *data
+}
+
+// data holds every record of an in-memory store
+type data struct {
+	namespaces  map[string]*shared.Namespace
+	elements    map[elementID]*shared.Element
+	revisions   map[elementID][]*shared.Revision
+	hits        map[elementID][]*shared.Hit
+	invites     map[shared.Invite]struct{}
+	tokens      map[tokenID]*shared.NamespaceToken
+	adminTokens map[string]*shared.AdminToken
+	audit       []*shared.AuditEntry
+	webhooks    map[webhookID]*shared.Webhook
+	deliveries  map[string]*shared.WebhookDelivery
+}
+
+// NewStore creates a new in-memory storage backend
+func NewStore() *Store {
+	return &Store{
+		data: &data{
+			namespaces:  make(map[string]*shared.Namespace),
+			elements:    make(map[elementID]*shared.Element),
+			revisions:   make(map[elementID][]*shared.Revision),
+			hits:        make(map[elementID][]*shared.Hit),
+			invites:     make(map[shared.Invite]struct{}),
+			tokens:      make(map[tokenID]*shared.NamespaceToken),
+			adminTokens: make(map[string]*shared.AdminToken),
+			webhooks:    make(map[webhookID]*shared.Webhook),
+			deliveries:  make(map[string]*shared.WebhookDelivery),
+		},
+	}
+}
+
+// Services returns the database services operating on the store
+func (store *Store) Services() *shared.Services {
+	return &shared.Services{
+		Namespaces:  &NamespaceService{store: store},
+		Elements:    &ElementService{store: store},
+		Invites:     &InviteService{store: store},
+		Hits:        &HitService{store: store},
+		Tokens:      &NamespaceTokenService{store: store},
+		AdminTokens: &AdminTokenService{store: store},
+		Audit:       &AuditService{store: store},
+		Webhooks:    &WebhookService{store: store},
+	}
+}
+
+// Transaction executes the given function using services bound to a copy of the store.
+// The store is locked exclusively while the function runs and the copy replaces its data if no error occurred.
+func (store *Store) Transaction(fn func(*shared.Services) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tx := &Store{data: store.data.clone()}
+	if err := fn(tx.Services()); err != nil {
+		return err
+	}
+	store.data = tx.data
+	return nil
+}
+
+// Ping checks whether the store is reachable, which an in-memory one always is
+func (store *Store) Ping(_ context.Context) error {
+	return nil
+}
+
+// Close closes the in-memory storage backend
+func (store *Store) Close() {
+}
+
+// clone creates a copy of the data; records, revision and hit lists are never modified in place, so they may be shared.
+// The audit log is only ever appended to while the store is locked, so the copy may share it as well.
+func (original *data) clone() *data {
+	cloned := &data{
+		audit:       original.audit,
+		namespaces:  make(map[string]*shared.Namespace, len(original.namespaces)),
+		elements:    make(map[elementID]*shared.Element, len(original.elements)),
+		revisions:   make(map[elementID][]*shared.Revision, len(original.revisions)),
+		hits:        make(map[elementID][]*shared.Hit, len(original.hits)),
+		invites:     make(map[shared.Invite]struct{}, len(original.invites)),
+		tokens:      make(map[tokenID]*shared.NamespaceToken, len(original.tokens)),
+		adminTokens: make(map[string]*shared.AdminToken, len(original.adminTokens)),
+		webhooks:    make(map[webhookID]*shared.Webhook, len(original.webhooks)),
+		deliveries:  make(map[string]*shared.WebhookDelivery, len(original.deliveries)),
+	}
+	for id, namespace := range original.namespaces {
+		cloned.namespaces[id] = namespace
+	}
+	for id, element := range original.elements {
+		cloned.elements[id] = element
+	}
+	for id, revisions := range original.revisions {
+		cloned.revisions[id] = revisions
+	}
+	for id, hits := range original.hits {
+		cloned.hits[id] = hits
+	}
+	for invite := range original.invites {
+		cloned.invites[invite] = struct{}{}
+	}
+	for id, token := range original.tokens {
+		cloned.tokens[id] = token
+	}
+	for id, token := range original.adminTokens {
+		cloned.adminTokens[id] = token
+	}
+	for id, webhook := range original.webhooks {
+		cloned.webhooks[id] = webhook
+	}
+	for id, delivery := range original.deliveries {
+		cloned.deliveries[id] = delivery
+	}
+	return cloned
+}
@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/database/storetest"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return NewStore()
+	})
+}
@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"sort"
+	"time"
+)
+
+// webhookID represents the primary key of a webhook
+type webhookID struct {
+	namespace string
+	id        string
+}
+
+// WebhookService represents the in-memory webhook service
+type WebhookService struct {
+	store *Store
+}
+
+// Webhook searches for a webhook by its ID
+func (service *WebhookService) Webhook(namespace, id string) (*shared.Webhook, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	webhook, ok := service.store.data.webhooks[webhookID{namespace: namespace, id: id}]
+	if !ok {
+		return nil, nil
+	}
+	return copyWebhook(webhook), nil
+}
+
+// Webhooks searches for all webhooks of a namespace ordered by their creation time
+func (service *WebhookService) Webhooks(namespace string) ([]*shared.Webhook, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var webhooks []*shared.Webhook
+	for id, webhook := range service.store.data.webhooks {
+		if id.namespace == namespace {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+// CreateOrReplace creates or replaces a webhook; the creation time of an existing webhook is kept
+func (service *WebhookService) CreateOrReplace(webhook *shared.Webhook) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	id := webhookID{namespace: webhook.Namespace, id: webhook.ID}
+	if existing, ok := service.store.data.webhooks[id]; ok {
+		webhook.CreatedAt = existing.CreatedAt
+	} else if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	service.store.data.webhooks[id] = copyWebhook(webhook)
+	return nil
+}
+
+// Delete deletes a webhook including its deliveries
+func (service *WebhookService) Delete(namespace, id string) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	service.store.data.deleteWebhooks(func(webhook webhookID) bool {
+		return webhook.namespace == namespace && webhook.id == id
+	})
+	return nil
+}
+
+// Enqueue stores new pending deliveries; deliveries of webhooks which do not exist anymore are dropped
+func (service *WebhookService) Enqueue(deliveries []*shared.WebhookDelivery) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		if _, ok := service.store.data.webhooks[webhookID{namespace: delivery.Namespace, id: delivery.Webhook}]; !ok {
+			continue
+		}
+		if delivery.CreatedAt.IsZero() {
+			delivery.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		}
+		service.store.data.deliveries[delivery.ID] = copyDelivery(delivery)
+	}
+	return nil
+}
+
+// Delivery searches for a single delivery of a webhook
+func (service *WebhookService) Delivery(namespace, webhook, id string) (*shared.WebhookDelivery, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	delivery, ok := service.store.data.deliveries[id]
+	if !ok || delivery.Namespace != namespace || delivery.Webhook != webhook {
+		return nil, nil
+	}
+	return copyDelivery(delivery), nil
+}
+
+// Deliveries searches for the latest deliveries of a webhook, optionally restricted to the given status
+func (service *WebhookService) Deliveries(namespace, webhook string, status shared.DeliveryStatus, limit int) ([]*shared.WebhookDelivery, error) {
+	service.store.mu.RLock()
+	defer service.store.mu.RUnlock()
+
+	var deliveries []*shared.WebhookDelivery
+	for _, delivery := range service.store.data.deliveries {
+		if delivery.Namespace == namespace && delivery.Webhook == webhook && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, copyDelivery(delivery))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// Claim returns up to the given amount of due pending deliveries and postpones their next attempt by the given lease
+func (service *WebhookService) Claim(limit int, lease time.Duration) ([]*shared.WebhookDelivery, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	var due []*shared.WebhookDelivery
+	for _, delivery := range service.store.data.deliveries {
+		if delivery.Status == shared.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	leasedUntil := now.Add(lease)
+	claimed := make([]*shared.WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		leased := copyDelivery(delivery)
+		leased.NextAttemptAt = &leasedUntil
+		service.store.data.deliveries[leased.ID] = leased
+		claimed = append(claimed, copyDelivery(leased))
+	}
+	return claimed, nil
+}
+
+// UpdateDelivery stores the outcome of an attempt of a delivery
+func (service *WebhookService) UpdateDelivery(delivery *shared.WebhookDelivery) error {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	existing, ok := service.store.data.deliveries[delivery.ID]
+	if !ok {
+		return nil
+	}
+	updated := copyDelivery(existing)
+	updated.Status = delivery.Status
+	updated.Attempts = delivery.Attempts
+	updated.ResponseStatus = delivery.ResponseStatus
+	updated.Error = delivery.Error
+	updated.NextAttemptAt = delivery.NextAttemptAt
+	updated.LastAttemptAt = delivery.LastAttemptAt
+	service.store.data.deliveries[delivery.ID] = updated
+	return nil
+}
+
+// PruneDeliveries deletes every finished delivery created before the given time and returns their amount
+func (service *WebhookService) PruneDeliveries(before time.Time) (int, error) {
+	service.store.mu.Lock()
+	defer service.store.mu.Unlock()
+
+	pruned := 0
+	for id, delivery := range service.store.data.deliveries {
+		if delivery.Status != shared.DeliveryPending && delivery.CreatedAt.Before(before) {
+			delete(service.store.data.deliveries, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// deleteWebhooks deletes every webhook matching the given function including its deliveries
+func (data *data) deleteWebhooks(matches func(webhookID) bool) {
+	for id := range data.webhooks {
+		if matches(id) {
+			delete(data.webhooks, id)
+		}
+	}
+	for id, delivery := range data.deliveries {
+		if matches(webhookID{namespace: delivery.Namespace, id: delivery.Webhook}) {
+			delete(data.deliveries, id)
+		}
+	}
+}
+
+// copyWebhook copies a webhook including its events
+func copyWebhook(webhook *shared.Webhook) *shared.Webhook {
+	webhookCopy := *webhook
+	webhookCopy.Events = append([]shared.WebhookEvent(nil), webhook.Events...)
+	return &webhookCopy
+}
+
+// copyDelivery copies a webhook delivery including its payload
+func copyDelivery(delivery *shared.WebhookDelivery) *shared.WebhookDelivery {
+	deliveryCopy := *delivery
+	deliveryCopy.Payload = append([]byte(nil), delivery.Payload...)
+	return &deliveryCopy
+}
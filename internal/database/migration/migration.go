@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSchemaTooNew is used when the database schema is newer than the latest schema known to this application
+	ErrSchemaTooNew = errors.New("the database schema is newer than the one supported by this application version")
+)
+
+// Migration represents a single versioned schema migration
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// Driver represents a database driver supporting versioned schema migrations
+type Driver interface {
+	// Migrations returns all migrations of the driver ordered by their version
+	Migrations() []Migration
+
+	// SchemaVersion returns the currently applied schema version (0 if no migration was applied yet)
+	SchemaVersion() (int, error)
+
+	// Apply atomically applies a single migration and records its version; it does nothing if the migration was already applied
+	Apply(Migration) error
+}
+
+// Latest returns the latest schema version known to the given driver
+func Latest(driver Driver) int {
+	migrations := driver.Migrations()
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Check returns the current schema version and fails if it is newer than the latest one known to the given driver
+func Check(driver Driver) (int, error) {
+	current, err := driver.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if latest := Latest(driver); current > latest {
+		return current, fmt.Errorf("%w (database: %d, supported: %d)", ErrSchemaTooNew, current, latest)
+	}
+	return current, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones
+func Up(driver Driver) ([]Migration, error) {
+	current, err := Check(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range driver.Migrations() {
+		if migration.Version <= current {
+			continue
+		}
+		if err := driver.Apply(migration); err != nil {
+			return applied, fmt.Errorf("could not apply migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
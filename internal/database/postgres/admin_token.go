@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// adminTokenColumns represents the ordered admin token columns every admin token query selects
+const adminTokenColumns = "id, name, hash, expires_at, created_at"
+
+// AdminTokenService represents the postgres admin token service
+type AdminTokenService struct {
+	db querier
+}
+
+// Token searches for an admin token by its ID
+func (service *AdminTokenService) Token(id string) (*shared.AdminToken, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", adminTokenColumns, tableAdminTokens)
+	token, err := rowToAdminToken(service.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// Tokens searches for all admin tokens ordered by their creation time
+func (service *AdminTokenService) Tokens() ([]*shared.AdminToken, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY created_at, id", adminTokenColumns, tableAdminTokens)
+	rows, err := service.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*shared.AdminToken
+	for rows.Next() {
+		token, err := rowToAdminToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// CreateOrReplace creates or replaces an admin token; the creation time of an existing token is kept
+func (service *AdminTokenService) CreateOrReplace(token *shared.AdminToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, name, hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE
+			SET name = excluded.name,
+				hash = excluded.hash,
+				expires_at = excluded.expires_at
+    `, tableAdminTokens)
+	_, err := service.db.Exec(context.Background(), query, token.ID, token.Name, token.Hash, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+// Delete deletes an admin token
+func (service *AdminTokenService) Delete(id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tableAdminTokens)
+	_, err := service.db.Exec(context.Background(), query, id)
+	return err
+}
+
+// rowToAdminToken creates an admin token from a postgres row
+func rowToAdminToken(row pgx.Row) (*shared.AdminToken, error) {
+	var id string
+	var name string
+	var hash string
+	var expiresAt *time.Time
+	var createdAt time.Time
+
+	err := row.Scan(&id, &name, &hash, &expiresAt, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.AdminToken{
+		ID:        id,
+		Name:      name,
+		Hash:      hash,
+		ExpiresAt: expiresAt,
+		CreatedAt: createdAt,
+	}, nil
+}
@@ -2,51 +2,171 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/x0tf/server/internal/shared"
+	"time"
 )
 
+// elementColumns represents the ordered element columns every element query selects
+const elementColumns = "namespace, key, type, data, expires_at, remaining_views, blob, content_type, filename, size, language, encryption, password_hash, revision, created_at, updated_at, last_accessed_at"
+
+// elementKeyColumns represents the columns uniquely identifying an element in the order listings get sorted by
+var elementKeyColumns = []string{"namespace", "key"}
+
+// revisionContentColumns represents the element columns which get recorded in every revision
+const revisionContentColumns = "namespace, key, revision, type, data, blob, content_type, filename, size, language, encryption"
+
 // ElementService represents the postgres element service
 type ElementService struct {
-	pool *pgxpool.Pool
+	db querier
 }
 
-// NewElementService creates a new postgres element service
-func NewElementService(dsn string) (*ElementService, error) {
-	// Open a postgres connection pool
-	pool, err := pgxpool.Connect(context.Background(), dsn)
+// Element searches for a single element with a specific key in a specific namespace
+func (service *ElementService) Element(sourceNamespace, sourceKey string) (*shared.Element, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = $1 AND key = $2", elementColumns, tableElements)
+	element, err := rowToElement(service.db.QueryRow(context.Background(), query, sourceNamespace, sourceKey))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
+	return element, nil
+}
 
-	// Create and return the element service
-	return &ElementService{
-		pool: pool,
-	}, nil
+// Elements searches for the elements matching the given query
+func (service *ElementService) Elements(query *shared.ElementQuery) ([]*shared.Element, error) {
+	listing := new(listing)
+	if query.Namespace != "" {
+		listing.where("namespace = " + listing.arg(query.Namespace))
+	}
+	if query.Type != nil {
+		listing.where("type = " + listing.arg(*query.Type))
+	}
+	listing.prefix("key", query.KeyPrefix)
+	listing.createdBetween(query.CreatedAfter, query.CreatedBefore)
+
+	if query.After != nil {
+		listing.after(query.Sort, elementKeyColumns, query.After.CreatedAt, query.After.Namespace, query.After.Key)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s", elementColumns, tableElements) + listing.clauses(query.Sort, elementKeyColumns, query.Limit)
+	return service.queryElements(statement, listing.args...)
+}
+
+// CreateOrReplace creates or replaces an element and records its content as a new revision
+func (service *ElementService) CreateOrReplace(element *shared.Element) error {
+	element.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	element.UpdatedAt = element.CreatedAt
+	encryption, err := encryptionValue(element.Encryption)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		WITH written AS (
+			INSERT INTO %[1]s (namespace, key, type, data, expires_at, remaining_views, blob, content_type, filename, size, language, encryption, password_hash, created_at, updated_at, last_accessed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $15, $16, $17)
+			ON CONFLICT (namespace, key) DO UPDATE
+				SET type = excluded.type,
+					data = excluded.data,
+					expires_at = excluded.expires_at,
+					remaining_views = excluded.remaining_views,
+					blob = excluded.blob,
+					content_type = excluded.content_type,
+					filename = excluded.filename,
+					size = excluded.size,
+					language = excluded.language,
+					encryption = excluded.encryption,
+					password_hash = excluded.password_hash,
+					created_at = excluded.created_at,
+					updated_at = excluded.updated_at,
+					last_accessed_at = excluded.last_accessed_at,
+					revision = %[1]s.revision + 1
+			RETURNING %[3]s
+		)
+		INSERT INTO %[2]s (%[3]s, created_at, actor)
+		SELECT %[3]s, NOW(), $14 FROM written
+		RETURNING revision
+    `, tableElements, tableElementRevisions, revisionContentColumns)
+	row := service.db.QueryRow(context.Background(), query, element.Namespace, element.Key, element.Type, element.Data, element.ExpiresAt, element.RemainingViews, element.Blob, element.ContentType, element.Filename, element.Size, element.Language, encryption, element.PasswordHash, element.Actor, element.CreatedAt, element.UpdatedAt, element.LastAccessedAt)
+	return row.Scan(&element.Revision)
 }
 
-// InitializeTable initializes the element table
-func (service *ElementService) InitializeTable() error {
+// Update replaces an existing element if its revision still matches the expected one, increments the revision and records the new content;
+// it returns false if the element does not exist anymore or was modified concurrently
+func (service *ElementService) Update(element *shared.Element, expectedRevision int) (bool, error) {
+	element.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	encryption, err := encryptionValue(element.Encryption)
+	if err != nil {
+		return false, err
+	}
+
 	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			namespace VARCHAR(32) NOT NULL,
-			key VARCHAR(32) NOT NULL,
-			type SMALLINT NOT NULL,
-			data TEXT NOT NULL,
-			PRIMARY KEY (namespace, key)
+		WITH written AS (
+			UPDATE %[1]s
+			SET type = $3,
+				data = $4,
+				expires_at = $5,
+				remaining_views = $6,
+				blob = $7,
+				content_type = $8,
+				filename = $9,
+				size = $10,
+				language = $11,
+				encryption = $12,
+				password_hash = $13,
+				updated_at = $16,
+				revision = revision + 1
+			WHERE namespace = $1 AND key = $2 AND revision = $14
+			RETURNING %[3]s
 		)
-    `, tableElements)
-	_, err := service.pool.Exec(context.Background(), query)
+		INSERT INTO %[2]s (%[3]s, created_at, actor)
+		SELECT %[3]s, NOW(), $15 FROM written
+		RETURNING revision
+    `, tableElements, tableElementRevisions, revisionContentColumns)
+	row := service.db.QueryRow(context.Background(), query, element.Namespace, element.Key, element.Type, element.Data, element.ExpiresAt, element.RemainingViews, element.Blob, element.ContentType, element.Filename, element.Size, element.Language, encryption, element.PasswordHash, expectedRevision, element.Actor, element.UpdatedAt)
+	if err = row.Scan(&element.Revision); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete deletes an element
+func (service *ElementService) Delete(namespace, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 AND key = $2", tableElements)
+	_, err := service.db.Exec(context.Background(), query, namespace, key)
 	return err
 }
 
-// Element searches for a single element with a specific key in a specific namespace
-func (service *ElementService) Element(sourceNamespace, sourceKey string) (*shared.Element, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE namespace = $1 AND key = $2", tableElements)
-	element, err := rowToElement(service.pool.QueryRow(context.Background(), query, sourceNamespace, sourceKey))
+// DeleteInNamespace deletes every element in a namespace and returns the deleted elements
+func (service *ElementService) DeleteInNamespace(namespace string) ([]*shared.Element, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 RETURNING %s", tableElements, elementColumns)
+	return service.queryElements(query, namespace)
+}
+
+// DeleteExpired deletes every element which reached its expiration time or has no views left and returns the deleted elements
+func (service *ElementService) DeleteExpired() ([]*shared.Element, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= NOW() OR remaining_views <= 0 RETURNING %s", tableElements, elementColumns)
+	return service.queryElements(query)
+}
+
+// ConsumeView atomically decrements the remaining views of a view-limited element and returns the updated element;
+// it returns nil if the element does not exist, is not view-limited or has no views left
+func (service *ElementService) ConsumeView(namespace, key string) (*shared.Element, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET remaining_views = remaining_views - 1
+		WHERE namespace = $1 AND key = $2 AND remaining_views > 0
+		RETURNING %s
+    `, tableElements, elementColumns)
+	element, err := rowToElement(service.db.QueryRow(context.Background(), query, namespace, key))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -56,39 +176,113 @@ func (service *ElementService) Element(sourceNamespace, sourceKey string) (*shar
 	return element, nil
 }
 
-// Elements searches for all elements
-func (service *ElementService) Elements() ([]*shared.Element, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", tableElements)
-	rows, err := service.pool.Query(context.Background(), query)
+// Touch records the given accesses of elements unless a later access was already recorded and returns the ones which were the first accesses of their elements.
+// The previous access times are read while locking the rows so concurrent touches cannot both report the first access.
+func (service *ElementService) Touch(accesses []*shared.Access) ([]*shared.Access, error) {
+	namespaces := make([]string, len(accesses))
+	keys := make([]string, len(accesses))
+	accessedAt := make([]time.Time, len(accesses))
+	for i, access := range accesses {
+		namespaces[i], keys[i], accessedAt[i] = access.Namespace, access.Key, access.AccessedAt
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s AS elements
+		SET last_accessed_at = accesses.accessed_at
+		FROM UNNEST($1::VARCHAR[], $2::VARCHAR[], $3::TIMESTAMPTZ[]) AS accesses (namespace, key, accessed_at),
+			(
+				SELECT namespace, key, last_accessed_at
+				FROM %s
+				WHERE (namespace, key) IN (SELECT * FROM UNNEST($1::VARCHAR[], $2::VARCHAR[]))
+				FOR UPDATE
+			) AS previous
+		WHERE elements.namespace = accesses.namespace
+			AND elements.key = accesses.key
+			AND previous.namespace = elements.namespace
+			AND previous.key = elements.key
+			AND (elements.last_accessed_at IS NULL OR elements.last_accessed_at < accesses.accessed_at)
+		RETURNING elements.namespace, elements.key, elements.last_accessed_at, previous.last_accessed_at IS NULL
+    `, tableElements, tableElements)
+	rows, err := service.db.Query(context.Background(), query, namespaces, keys, accessedAt)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+		return nil, err
+	}
+	defer rows.Close()
+
+	var first []*shared.Access
+	for rows.Next() {
+		access := new(shared.Access)
+		var isFirst bool
+		if err := rows.Scan(&access.Namespace, &access.Key, &access.AccessedAt, &isFirst); err != nil {
+			return nil, err
 		}
+		if isFirst {
+			access.AccessedAt = access.AccessedAt.UTC()
+			first = append(first, access)
+		}
+	}
+	return first, rows.Err()
+}
+
+// Revisions searches for all revisions of an element ordered by their number
+func (service *ElementService) Revisions(namespace, key string) ([]*shared.Revision, error) {
+	query := fmt.Sprintf("SELECT %s, created_at, actor FROM %s WHERE namespace = $1 AND key = $2 ORDER BY revision", revisionContentColumns, tableElementRevisions)
+	rows, err := service.db.Query(context.Background(), query, namespace, key)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var elements []*shared.Element
+	var revisions []*shared.Revision
 	for rows.Next() {
-		element, err := rowToElement(rows)
+		revision, err := rowToRevision(rows)
 		if err != nil {
 			return nil, err
 		}
-		elements = append(elements, element)
+		revisions = append(revisions, revision)
 	}
-	return elements, nil
+	return revisions, rows.Err()
 }
 
-// ElementsInNamespace searches for all elements in a specific namespace
-func (service *ElementService) ElementsInNamespace(namespace string) ([]*shared.Element, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE namespace = $1", tableElements)
-	rows, err := service.pool.Query(context.Background(), query, namespace)
+// Revision searches for a single revision of an element
+func (service *ElementService) Revision(namespace, key string, number int) (*shared.Revision, error) {
+	query := fmt.Sprintf("SELECT %s, created_at, actor FROM %s WHERE namespace = $1 AND key = $2 AND revision = $3", revisionContentColumns, tableElementRevisions)
+	revision, err := rowToRevision(service.db.QueryRow(context.Background(), query, namespace, key, number))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	return revision, nil
+}
+
+// ReferencedBlobs searches for the IDs of all blobs referenced by elements or their revisions
+func (service *ElementService) ReferencedBlobs() ([]string, error) {
+	query := fmt.Sprintf("SELECT blob FROM %s WHERE blob <> '' UNION SELECT blob FROM %s WHERE blob <> ''", tableElements, tableElementRevisions)
+	rows, err := service.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// queryElements executes the given query and creates an element out of every resulting row
+func (service *ElementService) queryElements(query string, args ...interface{}) ([]*shared.Element, error) {
+	rows, err := service.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var elements []*shared.Element
@@ -99,39 +293,7 @@ func (service *ElementService) ElementsInNamespace(namespace string) ([]*shared.
 		}
 		elements = append(elements, element)
 	}
-	return elements, nil
-}
-
-// CreateOrReplace creates or replaces an element
-func (service *ElementService) CreateOrReplace(element *shared.Element) error {
-	query := fmt.Sprintf(`
-		INSERT INTO %s (namespace, key, type, data)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (namespace, key) DO UPDATE
-			SET type = excluded.type,
-				data = excluded.data
-    `, tableElements)
-	_, err := service.pool.Exec(context.Background(), query, element.Namespace, element.Key, element.Type, element.Data)
-	return err
-}
-
-// Delete deletes an element
-func (service *ElementService) Delete(namespace, key string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 AND key = $2", tableElements)
-	_, err := service.pool.Exec(context.Background(), query, namespace, key)
-	return err
-}
-
-// DeleteInNamespace deletes every element in a namespace
-func (service *ElementService) DeleteInNamespace(namespace string) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1", tableElements)
-	_, err := service.pool.Exec(context.Background(), query, namespace)
-	return err
-}
-
-// Close closes the postgres element service
-func (service *ElementService) Close() {
-	service.pool.Close()
+	return elements, rows.Err()
 }
 
 // rowToElement creates an element from a postgres row
@@ -140,16 +302,81 @@ func rowToElement(row pgx.Row) (*shared.Element, error) {
 	var key string
 	var typ shared.ElementType
 	var data string
+	var expiresAt *time.Time
+	var remainingViews *int
+	var blob string
+	var contentType string
+	var filename string
+	var size int64
+	var language string
+	var rawEncryption string
+	var passwordHash string
+	var revision int
+	var createdAt time.Time
+	var updatedAt time.Time
+	var lastAccessedAt *time.Time
 
-	err := row.Scan(&namespace, &key, &typ, &data)
+	err := row.Scan(&namespace, &key, &typ, &data, &expiresAt, &remainingViews, &blob, &contentType, &filename, &size, &language, &rawEncryption, &passwordHash, &revision, &createdAt, &updatedAt, &lastAccessedAt)
+	if err != nil {
+		return nil, err
+	}
+	encryption, err := encryptionPointer(rawEncryption)
 	if err != nil {
 		return nil, err
 	}
 
 	return &shared.Element{
-		Namespace: namespace,
-		Key:       key,
-		Type:      typ,
-		Data:      data,
+		Namespace:      namespace,
+		Key:            key,
+		Type:           typ,
+		Data:           data,
+		ExpiresAt:      expiresAt,
+		RemainingViews: remainingViews,
+		Blob:           blob,
+		ContentType:    contentType,
+		Filename:       filename,
+		Size:           size,
+		Language:       language,
+		Encryption:     encryption,
+		PasswordHash:   passwordHash,
+		Revision:       revision,
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+		LastAccessedAt: lastAccessedAt,
 	}, nil
 }
+
+// encryptionValue encodes optional encryption metadata into its JSON column representation
+func encryptionValue(encryption *shared.Encryption) (string, error) {
+	if encryption == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(encryption)
+	return string(encoded), err
+}
+
+// encryptionPointer decodes the JSON column representation of optional encryption metadata
+func encryptionPointer(value string) (*shared.Encryption, error) {
+	if value == "" {
+		return nil, nil
+	}
+	encryption := new(shared.Encryption)
+	if err := json.Unmarshal([]byte(value), encryption); err != nil {
+		return nil, err
+	}
+	return encryption, nil
+}
+
+// rowToRevision creates a revision from a postgres row
+func rowToRevision(row pgx.Row) (*shared.Revision, error) {
+	revision := new(shared.Revision)
+	var rawEncryption string
+	err := row.Scan(&revision.Namespace, &revision.Key, &revision.Revision, &revision.Type, &revision.Data, &revision.Blob, &revision.ContentType, &revision.Filename, &revision.Size, &revision.Language, &rawEncryption, &revision.CreatedAt, &revision.Actor)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Encryption, err = encryptionPointer(rawEncryption); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
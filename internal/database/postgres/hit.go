@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// HitService represents the postgres hit service
+type HitService struct {
+	db querier
+}
+
+// Record stores the given hits; hits of elements which do not exist anymore are dropped
+func (service *HitService) Record(hits []*shared.Hit) error {
+	namespaces := make([]string, len(hits))
+	keys := make([]string, len(hits))
+	hitAt := make([]time.Time, len(hits))
+	referrerHosts := make([]string, len(hits))
+	userAgentClasses := make([]string, len(hits))
+	countries := make([]string, len(hits))
+	for i, hit := range hits {
+		namespaces[i], keys[i], hitAt[i] = hit.Namespace, hit.Key, hit.HitAt
+		referrerHosts[i], userAgentClasses[i], countries[i] = hit.ReferrerHost, string(hit.UserAgentClass), hit.Country
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (namespace, key, hit_at, referrer_host, user_agent_class, country)
+		SELECT hits.namespace, hits.key, hits.hit_at, hits.referrer_host, hits.user_agent_class, hits.country
+		FROM UNNEST($1::VARCHAR[], $2::VARCHAR[], $3::TIMESTAMPTZ[], $4::VARCHAR[], $5::VARCHAR[], $6::VARCHAR[])
+			AS hits (namespace, key, hit_at, referrer_host, user_agent_class, country)
+		WHERE EXISTS (SELECT 1 FROM %[2]s WHERE %[2]s.namespace = hits.namespace AND %[2]s.key = hits.key)
+    `, tableElementHits, tableElements)
+	_, err := service.db.Exec(context.Background(), query, namespaces, keys, hitAt, referrerHosts, userAgentClasses, countries)
+	return err
+}
+
+// Stats aggregates the hits of an element since the given time
+func (service *HitService) Stats(namespace, key string, since time.Time) (*shared.HitStats, error) {
+	stats := new(shared.HitStats)
+	var err error
+	if stats.Days, err = service.count("TO_CHAR(hit_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')", "value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	for _, day := range stats.Days {
+		stats.Total += day.Hits
+	}
+	if stats.Referrers, err = service.count("referrer_host", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	if stats.UserAgents, err = service.count("user_agent_class", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	if stats.Countries, err = service.count("country", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// count counts the hits of an element since the given time grouped by the value of the given expression
+func (service *HitService) count(expression, order, namespace, key string, since time.Time) ([]*shared.HitCount, error) {
+	query := fmt.Sprintf(`
+		SELECT %s AS value, COUNT(*) AS hits
+		FROM %s
+		WHERE namespace = $1 AND key = $2 AND hit_at >= $3
+		GROUP BY value
+		ORDER BY %s
+    `, expression, tableElementHits, order)
+	rows, err := service.db.Query(context.Background(), query, namespace, key, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*shared.HitCount{}
+	for rows.Next() {
+		count := new(shared.HitCount)
+		if err := rows.Scan(&count.Value, &count.Hits); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/x0tf/server/internal/metrics"
+	"time"
+)
+
+// instrumentedQuerier represents a querier observing the latencies of the queries executed on it
+type instrumentedQuerier struct {
+	db querier
+}
+
+// Exec executes the given statement and observes its latency
+func (instrumented *instrumentedQuerier) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	defer metrics.ObserveQuery(sql, time.Now())
+	return instrumented.db.Exec(ctx, sql, arguments...)
+}
+
+// Query executes the given query and observes its latency until its rows are closed
+func (instrumented *instrumentedQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := instrumented.db.Query(ctx, sql, args...)
+	if err != nil {
+		metrics.ObserveQuery(sql, start)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, sql: sql, start: start}, nil
+}
+
+// QueryRow executes the given query and observes its latency until its row is scanned
+func (instrumented *instrumentedQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &instrumentedRow{
+		row:   instrumented.db.QueryRow(ctx, sql, args...),
+		sql:   sql,
+		start: time.Now(),
+	}
+}
+
+// instrumentedRows represents query result rows observing the latency of their query once they get closed
+type instrumentedRows struct {
+	pgx.Rows
+	sql      string
+	start    time.Time
+	observed bool
+}
+
+// Next prepares the next row and closes the rows once all of them were read
+func (rows *instrumentedRows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+	rows.observe()
+	return false
+}
+
+// Close closes the rows
+func (rows *instrumentedRows) Close() {
+	rows.Rows.Close()
+	rows.observe()
+}
+
+// observe observes the latency of the query once
+func (rows *instrumentedRows) observe() {
+	if !rows.observed {
+		rows.observed = true
+		metrics.ObserveQuery(rows.sql, rows.start)
+	}
+}
+
+// instrumentedRow represents a single query result row observing the latency of its query once it gets scanned
+type instrumentedRow struct {
+	row   pgx.Row
+	sql   string
+	start time.Time
+}
+
+// Scan scans the row into the given destinations
+func (row *instrumentedRow) Scan(dest ...interface{}) error {
+	defer metrics.ObserveQuery(row.sql, row.start)
+	return row.row.Scan(dest...)
+}
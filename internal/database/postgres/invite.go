@@ -5,46 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/x0tf/server/internal/shared"
 	"strings"
 )
 
 // InviteService represents the postgres invite service
 type InviteService struct {
-	pool *pgxpool.Pool
-}
-
-// NewInviteService creates a new postgres invite service
-func NewInviteService(dsn string) (*InviteService, error) {
-	// Open a postgres connection pool
-	pool, err := pgxpool.Connect(context.Background(), dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create and return the invite service
-	return &InviteService{
-		pool: pool,
-	}, nil
-}
-
-// InitializeTable initializes the invite table
-func (service *InviteService) InitializeTable() error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			token VARCHAR(32) NOT NULL,
-			PRIMARY KEY (token)
-		)
-    `, tableInvites)
-	_, err := service.pool.Exec(context.Background(), query)
-	return err
+	db querier
 }
 
 // IsValid searches for a single invite with a specific token
 func (service *InviteService) IsValid(token string) (bool, error) {
 	query := fmt.Sprintf("SELECT * FROM %s WHERE token = $1", tableInvites)
-	if err := service.pool.QueryRow(context.Background(), query, token).Scan(nil); err != nil {
+	if err := service.db.QueryRow(context.Background(), query, token).Scan(nil); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return false, nil
 		}
@@ -56,7 +29,7 @@ func (service *InviteService) IsValid(token string) (bool, error) {
 // Invites searches for all invites
 func (service *InviteService) Invites() ([]shared.Invite, error) {
 	query := fmt.Sprintf("SELECT * FROM %s", tableInvites)
-	rows, err := service.pool.Query(context.Background(), query)
+	rows, err := service.db.Query(context.Background(), query)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -79,7 +52,7 @@ func (service *InviteService) Invites() ([]shared.Invite, error) {
 // Create creates an invite
 func (service *InviteService) Create(invite shared.Invite) error {
 	query := fmt.Sprintf("INSERT INTO %s (token) VALUES ($1)", tableInvites)
-	_, err := service.pool.Exec(context.Background(), query, invite)
+	_, err := service.db.Exec(context.Background(), query, invite)
 	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
 		err = nil
 	}
@@ -89,11 +62,16 @@ func (service *InviteService) Create(invite shared.Invite) error {
 // Delete deletes an invite
 func (service *InviteService) Delete(token string) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE token = $1", tableInvites)
-	_, err := service.pool.Exec(context.Background(), query, token)
+	_, err := service.db.Exec(context.Background(), query, token)
 	return err
 }
 
-// Close closes the postgres invite service
-func (service *InviteService) Close() {
-	service.pool.Close()
+// Consume deletes an invite and reports whether it existed
+func (service *InviteService) Consume(token string) (bool, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE token = $1", tableInvites)
+	tag, err := service.db.Exec(context.Background(), query, token)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
 }
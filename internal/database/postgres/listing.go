@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listing builds the filter, cursor, order and limit clauses of a paginated listing query
+type listing struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg registers a query argument and returns its placeholder
+func (listing *listing) arg(value interface{}) string {
+	listing.args = append(listing.args, value)
+	return "$" + strconv.Itoa(len(listing.args))
+}
+
+// where adds a condition every listed row has to fulfill
+func (listing *listing) where(condition string) {
+	listing.conditions = append(listing.conditions, condition)
+}
+
+// prefix adds a condition requiring the given column to start with the given prefix
+func (listing *listing) prefix(column, prefix string) {
+	if prefix == "" {
+		return
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	listing.where(fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, column, listing.arg(escaped+"%")))
+}
+
+// createdBetween adds conditions restricting the creation time to the given (exclusive) range
+func (listing *listing) createdBetween(after, before *time.Time) {
+	if after != nil {
+		listing.where("created_at > " + listing.arg(*after))
+	}
+	if before != nil {
+		listing.where("created_at < " + listing.arg(*before))
+	}
+}
+
+// after adds a condition continuing a listing behind the row with the given creation time and key column values
+func (listing *listing) after(order shared.SortOrder, keyColumns []string, createdAt time.Time, keys ...interface{}) {
+	columns := sortColumns(order, keyColumns)
+	values := keys
+	if len(columns) > len(keyColumns) {
+		values = append([]interface{}{createdAt}, keys...)
+	}
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = listing.arg(value)
+	}
+
+	// Compare the sort columns as a whole
+	comparison := ">"
+	if order.Descending() {
+		comparison = "<"
+	}
+	listing.where(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), comparison, strings.Join(placeholders, ", ")))
+}
+
+// clauses returns the WHERE, ORDER BY and LIMIT clauses of a listing sorted by the given key columns
+func (listing *listing) clauses(order shared.SortOrder, keyColumns []string, limit int) string {
+	var clauses strings.Builder
+	if len(listing.conditions) > 0 {
+		clauses.WriteString(" WHERE " + strings.Join(listing.conditions, " AND "))
+	}
+
+	direction := "ASC"
+	if order.Descending() {
+		direction = "DESC"
+	}
+	columns := sortColumns(order, keyColumns)
+	ordering := make([]string, len(columns))
+	for i, column := range columns {
+		ordering[i] = column + " " + direction
+	}
+	clauses.WriteString(" ORDER BY " + strings.Join(ordering, ", "))
+
+	if limit > 0 {
+		clauses.WriteString(" LIMIT " + strconv.Itoa(limit))
+	}
+	return clauses.String()
+}
+
+// sortColumns returns the columns a listing with the given key columns is sorted by
+func sortColumns(order shared.SortOrder, keyColumns []string) []string {
+	if order == shared.SortByCreation || order == shared.SortByCreationDescending {
+		return append([]string{"created_at"}, keyColumns...)
+	}
+	return keyColumns
+}
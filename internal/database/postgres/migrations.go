@@ -0,0 +1,272 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/database/migration"
+)
+
+// migrations contains every postgres schema migration ordered by its version
+var migrations = []migration.Migration{
+	{
+		Version:     1,
+		Description: "create the namespace, element and invite tables",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					id VARCHAR(32) NOT NULL,
+					token VARCHAR(100) NOT NULL,
+					active BOOLEAN NOT NULL,
+					PRIMARY KEY (id)
+				)
+			`, tableNamespaces),
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					namespace VARCHAR(32) NOT NULL,
+					key VARCHAR(32) NOT NULL,
+					type SMALLINT NOT NULL,
+					data TEXT NOT NULL,
+					PRIMARY KEY (namespace, key)
+				)
+			`, tableElements),
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					token VARCHAR(32) NOT NULL,
+					PRIMARY KEY (token)
+				)
+			`, tableInvites),
+		},
+	},
+	{
+		Version:     2,
+		Description: "add the expiration time to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN expires_at TIMESTAMPTZ", tableElements),
+			fmt.Sprintf("CREATE INDEX %s_expires_at ON %s (expires_at)", tableElements, tableElements),
+		},
+	},
+	{
+		Version:     3,
+		Description: "add the remaining view count to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN remaining_views INTEGER", tableElements),
+		},
+	},
+	{
+		Version:     4,
+		Description: "add the blob reference and file metadata to elements",
+		Statements: []string{
+			fmt.Sprintf(`
+				ALTER TABLE %s
+					ADD COLUMN blob VARCHAR(64) NOT NULL DEFAULT '',
+					ADD COLUMN content_type VARCHAR(255) NOT NULL DEFAULT '',
+					ADD COLUMN filename VARCHAR(255) NOT NULL DEFAULT '',
+					ADD COLUMN size BIGINT NOT NULL DEFAULT 0
+			`, tableElements),
+		},
+	},
+	{
+		Version:     5,
+		Description: "add the language hint to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN language VARCHAR(64) NOT NULL DEFAULT ''", tableElements),
+		},
+	},
+	{
+		Version:     6,
+		Description: "add the encryption metadata to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN encryption TEXT NOT NULL DEFAULT ''", tableElements),
+		},
+	},
+	{
+		Version:     7,
+		Description: "add the password hash to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN password_hash VARCHAR(255) NOT NULL DEFAULT ''", tableElements),
+		},
+	},
+	{
+		Version:     8,
+		Description: "add the revision counter to elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN revision INTEGER NOT NULL DEFAULT 1", tableElements),
+		},
+	},
+	{
+		Version:     9,
+		Description: "create the element revision table",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					namespace VARCHAR(32) NOT NULL,
+					key VARCHAR(32) NOT NULL,
+					revision INTEGER NOT NULL,
+					type SMALLINT NOT NULL,
+					data TEXT NOT NULL,
+					blob VARCHAR(64) NOT NULL DEFAULT '',
+					content_type VARCHAR(255) NOT NULL DEFAULT '',
+					filename VARCHAR(255) NOT NULL DEFAULT '',
+					size BIGINT NOT NULL DEFAULT 0,
+					language VARCHAR(64) NOT NULL DEFAULT '',
+					encryption TEXT NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL,
+					actor VARCHAR(64) NOT NULL DEFAULT '',
+					PRIMARY KEY (namespace, key, revision),
+					FOREIGN KEY (namespace, key) REFERENCES %s (namespace, key) ON DELETE CASCADE
+				)
+			`, tableElementRevisions, tableElements),
+			fmt.Sprintf(`
+				INSERT INTO %s (namespace, key, revision, type, data, blob, content_type, filename, size, language, encryption, created_at)
+				SELECT namespace, key, revision, type, data, blob, content_type, filename, size, language, encryption, NOW()
+				FROM %s
+			`, tableElementRevisions, tableElements),
+		},
+	},
+	{
+		Version:     10,
+		Description: "add the creation time to namespaces and elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()", tableNamespaces),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()", tableElements),
+			fmt.Sprintf("CREATE INDEX %s_created_at ON %s (created_at, namespace, key)", tableElements, tableElements),
+			fmt.Sprintf("CREATE INDEX %s_namespace_created_at ON %s (namespace, created_at, key)", tableElements, tableElements),
+		},
+	},
+	{
+		Version:     11,
+		Description: "add the update and access times to namespaces and elements",
+		Statements: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()", tableNamespaces),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN last_accessed_at TIMESTAMPTZ", tableNamespaces),
+			fmt.Sprintf("UPDATE %s SET updated_at = created_at", tableNamespaces),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()", tableElements),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN last_accessed_at TIMESTAMPTZ", tableElements),
+			fmt.Sprintf("UPDATE %s SET updated_at = created_at", tableElements),
+		},
+	},
+	{
+		Version:     12,
+		Description: "create the element hit table",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					namespace VARCHAR(32) NOT NULL,
+					key VARCHAR(32) NOT NULL,
+					hit_at TIMESTAMPTZ NOT NULL,
+					referrer_host VARCHAR(255) NOT NULL DEFAULT '',
+					user_agent_class VARCHAR(16) NOT NULL DEFAULT '',
+					country VARCHAR(2) NOT NULL DEFAULT '',
+					FOREIGN KEY (namespace, key) REFERENCES %s (namespace, key) ON DELETE CASCADE
+				)
+			`, tableElementHits, tableElements),
+			fmt.Sprintf("CREATE INDEX %s_element ON %s (namespace, key, hit_at)", tableElementHits, tableElementHits),
+		},
+	},
+	{
+		Version:     13,
+		Description: "create the namespace token table",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					namespace VARCHAR(32) NOT NULL,
+					id VARCHAR(16) NOT NULL,
+					name VARCHAR(64) NOT NULL,
+					hash VARCHAR(100) NOT NULL,
+					scopes TEXT NOT NULL DEFAULT '',
+					expires_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (namespace, id),
+					FOREIGN KEY (namespace) REFERENCES %s (id) ON DELETE CASCADE
+				)
+			`, tableNamespaceTokens, tableNamespaces),
+		},
+	},
+	{
+		Version:     14,
+		Description: "create the admin token table",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					id VARCHAR(16) NOT NULL,
+					name VARCHAR(64) NOT NULL,
+					hash VARCHAR(100) NOT NULL,
+					expires_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (id)
+				)
+			`, tableAdminTokens),
+		},
+	},
+	{
+		Version:     15,
+		Description: "create the append-only audit log table",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					id BIGSERIAL NOT NULL,
+					actor VARCHAR(64) NOT NULL,
+					actor_name VARCHAR(64) NOT NULL DEFAULT '',
+					action VARCHAR(64) NOT NULL,
+					namespace VARCHAR(32) NOT NULL DEFAULT '',
+					target VARCHAR(255) NOT NULL DEFAULT '',
+					ip VARCHAR(45) NOT NULL DEFAULT '',
+					request_id VARCHAR(64) NOT NULL DEFAULT '',
+					created_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (id)
+				)
+			`, tableAuditLog),
+			fmt.Sprintf("CREATE INDEX %s_namespace ON %s (namespace, id)", tableAuditLog, tableAuditLog),
+			fmt.Sprintf("CREATE INDEX %s_actor ON %s (actor, id)", tableAuditLog, tableAuditLog),
+			fmt.Sprintf(`
+				CREATE OR REPLACE FUNCTION %s_append_only() RETURNS TRIGGER AS $$
+				BEGIN
+					RAISE EXCEPTION 'the audit log is append-only';
+				END
+				$$ LANGUAGE plpgsql
+			`, tableAuditLog),
+			fmt.Sprintf(`
+				CREATE TRIGGER %s_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON %s
+				FOR EACH STATEMENT EXECUTE PROCEDURE %s_append_only()
+			`, tableAuditLog, tableAuditLog, tableAuditLog),
+		},
+	},
+	{
+		Version:     16,
+		Description: "create the webhook and webhook delivery tables",
+		Statements: []string{
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					namespace VARCHAR(32) NOT NULL,
+					id VARCHAR(16) NOT NULL,
+					url TEXT NOT NULL,
+					events TEXT NOT NULL DEFAULT '',
+					secret VARCHAR(100) NOT NULL,
+					active BOOLEAN NOT NULL DEFAULT TRUE,
+					created_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (namespace, id),
+					FOREIGN KEY (namespace) REFERENCES %s (id) ON DELETE CASCADE
+				)
+			`, tableWebhooks, tableNamespaces),
+			fmt.Sprintf(`
+				CREATE TABLE IF NOT EXISTS %s (
+					id VARCHAR(16) NOT NULL,
+					namespace VARCHAR(32) NOT NULL,
+					webhook VARCHAR(16) NOT NULL,
+					event VARCHAR(64) NOT NULL,
+					payload TEXT NOT NULL,
+					status VARCHAR(16) NOT NULL,
+					attempts INTEGER NOT NULL DEFAULT 0,
+					response_status INTEGER NOT NULL DEFAULT 0,
+					error TEXT NOT NULL DEFAULT '',
+					next_attempt_at TIMESTAMPTZ,
+					last_attempt_at TIMESTAMPTZ,
+					created_at TIMESTAMPTZ NOT NULL,
+					PRIMARY KEY (id),
+					FOREIGN KEY (namespace, webhook) REFERENCES %s (namespace, id) ON DELETE CASCADE
+				)
+			`, tableWebhookDeliveries, tableWebhooks),
+			fmt.Sprintf("CREATE INDEX %s_webhook ON %s (namespace, webhook, created_at)", tableWebhookDeliveries, tableWebhookDeliveries),
+			fmt.Sprintf("CREATE INDEX %s_due ON %s (status, next_attempt_at)", tableWebhookDeliveries, tableWebhookDeliveries),
+		},
+	},
+}
@@ -5,47 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/x0tf/server/internal/shared"
+	"time"
 )
 
-// NamespaceService represents the postgres namespace service
-type NamespaceService struct {
-	pool *pgxpool.Pool
-}
+// namespaceColumns represents the ordered namespace columns every namespace query selects
+const namespaceColumns = "id, token, active, created_at, updated_at, last_accessed_at"
 
-// NewNamespaceService creates a new postgres namespace service
-func NewNamespaceService(dsn string) (*NamespaceService, error) {
-	// Open a postgres connection pool
-	pool, err := pgxpool.Connect(context.Background(), dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create and return the namespace service
-	return &NamespaceService{
-		pool: pool,
-	}, nil
-}
+// namespaceKeyColumns represents the columns uniquely identifying a namespace in the order listings get sorted by
+var namespaceKeyColumns = []string{"id"}
 
-// InitializeTable initializes the namespace table
-func (service *NamespaceService) InitializeTable() error {
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id VARCHAR(32) NOT NULL,
-			token VARCHAR(100) NOT NULL,
-			active BOOLEAN NOT NULL,
-			PRIMARY KEY (id)
-		)
-    `, tableNamespaces)
-	_, err := service.pool.Exec(context.Background(), query)
-	return err
+// NamespaceService represents the postgres namespace service
+type NamespaceService struct {
+	db querier
 }
 
 // Namespace searches for a namespace by its ID
 func (service *NamespaceService) Namespace(sourceID string) (*shared.Namespace, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", tableNamespaces)
-	namespace, err := rowToNamespace(service.pool.QueryRow(context.Background(), query, sourceID))
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", namespaceColumns, tableNamespaces)
+	namespace, err := rowToNamespace(service.db.QueryRow(context.Background(), query, sourceID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -55,14 +33,18 @@ func (service *NamespaceService) Namespace(sourceID string) (*shared.Namespace,
 	return namespace, nil
 }
 
-// Namespaces searches for all existent namespaces
-func (service *NamespaceService) Namespaces() ([]*shared.Namespace, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", tableNamespaces)
-	rows, err := service.pool.Query(context.Background(), query)
+// Namespaces searches for the namespaces matching the given query
+func (service *NamespaceService) Namespaces(query *shared.NamespaceQuery) ([]*shared.Namespace, error) {
+	listing := new(listing)
+	listing.prefix("id", query.IDPrefix)
+	listing.createdBetween(query.CreatedAfter, query.CreatedBefore)
+	if query.After != nil {
+		listing.after(query.Sort, namespaceKeyColumns, query.After.CreatedAt, query.After.ID)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s", namespaceColumns, tableNamespaces) + listing.clauses(query.Sort, namespaceKeyColumns, query.Limit)
+	rows, err := service.db.Query(context.Background(), statement, listing.args...)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	defer rows.Close()
@@ -75,32 +57,81 @@ func (service *NamespaceService) Namespaces() ([]*shared.Namespace, error) {
 		}
 		namespaces = append(namespaces, namespace)
 	}
-	return namespaces, nil
+	return namespaces, rows.Err()
 }
 
-// CreateOrReplace creates or replaces a namespace
+// CreateOrReplace creates or replaces a namespace; the creation and access times of an existing namespace are kept
 func (service *NamespaceService) CreateOrReplace(namespace *shared.Namespace) error {
+	namespace.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if namespace.CreatedAt.IsZero() {
+		namespace.CreatedAt = namespace.UpdatedAt
+	}
 	query := fmt.Sprintf(`
-		INSERT INTO %s (id, token, active)
-		VALUES ($1, $2, $3)
+		INSERT INTO %s (id, token, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (id) DO UPDATE
 			SET token = excluded.token,
-				active = excluded.active
+				active = excluded.active,
+				updated_at = excluded.updated_at
     `, tableNamespaces)
-	_, err := service.pool.Exec(context.Background(), query, namespace.ID, namespace.Token, namespace.Active)
+	_, err := service.db.Exec(context.Background(), query, namespace.ID, namespace.Token, namespace.Active, namespace.CreatedAt, namespace.UpdatedAt)
 	return err
 }
 
+// Create creates a namespace unless one with the same ID already exists; it returns false if one does
+func (service *NamespaceService) Create(namespace *shared.Namespace) (bool, error) {
+	namespace.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	namespace.UpdatedAt = namespace.CreatedAt
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, token, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO NOTHING
+    `, tableNamespaces)
+	tag, err := service.db.Exec(context.Background(), query, namespace.ID, namespace.Token, namespace.Active, namespace.CreatedAt, namespace.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 // Delete deletes a namespace
 func (service *NamespaceService) Delete(id string) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", tableNamespaces)
-	_, err := service.pool.Exec(context.Background(), query, id)
+	_, err := service.db.Exec(context.Background(), query, id)
 	return err
 }
 
-// Close closes the postgres namespace service
-func (service *NamespaceService) Close() {
-	service.pool.Close()
+// Touch records the given accesses of elements as accesses of their namespaces unless a later access was already recorded
+func (service *NamespaceService) Touch(accesses []*shared.Access) error {
+	namespaces := make([]string, len(accesses))
+	accessedAt := make([]time.Time, len(accesses))
+	for i, access := range accesses {
+		namespaces[i], accessedAt[i] = access.Namespace, access.AccessedAt
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s AS namespaces
+		SET last_accessed_at = accesses.accessed_at
+		FROM (
+			SELECT namespace, MAX(accessed_at) AS accessed_at
+			FROM UNNEST($1::VARCHAR[], $2::TIMESTAMPTZ[]) AS accesses (namespace, accessed_at)
+			GROUP BY namespace
+		) AS accesses
+		WHERE namespaces.id = accesses.namespace
+			AND (namespaces.last_accessed_at IS NULL OR namespaces.last_accessed_at < accesses.accessed_at)
+    `, tableNamespaces)
+	_, err := service.db.Exec(context.Background(), query, namespaces, accessedAt)
+	return err
+}
+
+// ReplaceToken replaces the token hash of a namespace if it still equals the previous one
+func (service *NamespaceService) ReplaceToken(id, previous, replacement string) (bool, error) {
+	query := fmt.Sprintf("UPDATE %s SET token = $1 WHERE id = $2 AND token = $3", tableNamespaces)
+	tag, err := service.db.Exec(context.Background(), query, replacement, id, previous)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
 }
 
 // rowToNamespace creates a namespace from a postgres row
@@ -108,15 +139,21 @@ func rowToNamespace(row pgx.Row) (*shared.Namespace, error) {
 	var id string
 	var token string
 	var active bool
+	var createdAt time.Time
+	var updatedAt time.Time
+	var lastAccessedAt *time.Time
 
-	err := row.Scan(&id, &token, &active)
+	err := row.Scan(&id, &token, &active, &createdAt, &updatedAt, &lastAccessedAt)
 	if err != nil {
 		return nil, err
 	}
 
 	return &shared.Namespace{
-		ID:     id,
-		Token:  token,
-		Active: active,
+		ID:             id,
+		Token:          token,
+		Active:         active,
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+		LastAccessedAt: lastAccessedAt,
 	}, nil
 }
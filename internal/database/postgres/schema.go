@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/x0tf/server/internal/database/migration"
+)
+
+// migrationLockID represents the advisory lock key used to serialize concurrent migration runs
+const migrationLockID = 0x7830
+
+// Migrations returns every postgres schema migration ordered by its version
+func (store *Store) Migrations() []migration.Migration {
+	return migrations
+}
+
+// SchemaVersion returns the currently applied schema version
+func (store *Store) SchemaVersion() (int, error) {
+	if err := store.initializeVersionTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", tableSchemaVersion)
+	if err := store.pool.QueryRow(context.Background(), query).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Apply atomically applies a single migration and records its version
+func (store *Store) Apply(mig migration.Migration) error {
+	if err := store.initializeVersionTable(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize concurrent migration runs and skip the migration if another instance applied it in the meantime
+	if _, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", tableSchemaVersion)
+	if err = tx.QueryRow(ctx, query).Scan(&version); err != nil {
+		return err
+	}
+	if version >= mig.Version {
+		return nil
+	}
+
+	// Apply the migration and record its version
+	for _, statement := range mig.Statements {
+		if _, err = tx.Exec(ctx, statement); err != nil {
+			return err
+		}
+	}
+	query = fmt.Sprintf("INSERT INTO %s (version, description) VALUES ($1, $2)", tableSchemaVersion)
+	if _, err = tx.Exec(ctx, query, mig.Version, mig.Description); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// initializeVersionTable initializes the schema version table
+func (store *Store) initializeVersionTable() error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version INTEGER NOT NULL,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (version)
+		)
+    `, tableSchemaVersion)
+	_, err := store.pool.Exec(context.Background(), query)
+	return err
+}
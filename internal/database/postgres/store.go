@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/x0tf/server/internal/shared"
+)
+
+// querier represents a postgres connection pool or transaction queries can be executed on
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Store represents the postgres storage backend sharing a single connection pool between all of its services
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// NewStore creates a new postgres storage backend
+func NewStore(dsn string) (*Store, error) {
+	// Open a postgres connection pool
+	pool, err := pgxpool.Connect(context.Background(), dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create and return the store
+	return &Store{
+		pool: pool,
+	}, nil
+}
+
+// Services returns the database services operating directly on the connection pool
+func (store *Store) Services() *shared.Services {
+	return newServices(store.pool)
+}
+
+// Transaction executes the given function using services bound to a single transaction
+func (store *Store) Transaction(fn func(*shared.Services) error) error {
+	ctx := context.Background()
+	tx, err := store.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = fn(newServices(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Ping checks whether the database is reachable
+func (store *Store) Ping(ctx context.Context) error {
+	conn, err := store.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return conn.Conn().Ping(ctx)
+}
+
+// Close closes the postgres storage backend
+func (store *Store) Close() {
+	store.pool.Close()
+}
+
+// Stat returns the current statistics of the connection pool
+func (store *Store) Stat() *pgxpool.Stat {
+	return store.pool.Stat()
+}
+
+// newServices creates the database services operating on the given querier, observing the latencies of its queries
+func newServices(db querier) *shared.Services {
+	db = &instrumentedQuerier{db: db}
+	return &shared.Services{
+		Namespaces:  &NamespaceService{db: db},
+		Elements:    &ElementService{db: db},
+		Invites:     &InviteService{db: db},
+		Hits:        &HitService{db: db},
+		Tokens:      &NamespaceTokenService{db: db},
+		AdminTokens: &AdminTokenService{db: db},
+		Audit:       &AuditService{db: db},
+		Webhooks:    &WebhookService{db: db},
+	}
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/x0tf/server/internal/database/migration"
+	"github.com/x0tf/server/internal/database/storetest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testDSNVariable is the environment variable containing the DSN of the postgres database the tests may use
+const testDSNVariable = "X0_TEST_POSTGRES_DSN"
+
+func TestStore(t *testing.T) {
+	dsn := os.Getenv(testDSNVariable)
+	if dsn == "" {
+		t.Skip(testDSNVariable + " is not set")
+	}
+	admin, err := pgxpool.Connect(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("could not connect to the database: %v", err)
+	}
+	defer admin.Close()
+
+	// Every test gets its own schema so it starts out with an empty database
+	schemas := 0
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		schemas++
+		schema := fmt.Sprintf("x0_test_%d_%d", os.Getpid(), schemas)
+		if _, err := admin.Exec(context.Background(), "CREATE SCHEMA "+schema); err != nil {
+			t.Fatalf("could not create the schema: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+				t.Errorf("could not drop the schema: %v", err)
+			}
+		})
+
+		store, err := NewStore(withSearchPath(dsn, schema))
+		if err != nil {
+			t.Fatalf("could not open the store: %v", err)
+		}
+		t.Cleanup(store.Close)
+		if _, err = migration.Up(store); err != nil {
+			t.Fatalf("could not migrate the store: %v", err)
+		}
+		return store
+	})
+}
+
+// withSearchPath restricts the connections of a DSN in either the URL or the keyword/value format to the given schema
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
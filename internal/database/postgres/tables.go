@@ -1,12 +1,36 @@
 package postgres
 
 var (
+	// tableSchemaVersion represents the schema version table name to use for the postgres database driver
+	tableSchemaVersion = "schema_version"
+
 	// tableNamespaces represents the namespace table name to use for the postgres database driver
 	tableNamespaces = "namespaces"
 
 	// tableElements represents the element table name to use for the postgres database driver
 	tableElements = "elements"
 
+	// tableElementRevisions represents the element revision table name to use for the postgres database driver
+	tableElementRevisions = "element_revisions"
+
+	// tableElementHits represents the element hit table name to use for the postgres database driver
+	tableElementHits = "element_hits"
+
+	// tableNamespaceTokens represents the namespace token table name to use for the postgres database driver
+	tableNamespaceTokens = "namespace_tokens"
+
+	// tableAdminTokens represents the admin token table name to use for the postgres database driver
+	tableAdminTokens = "admin_tokens"
+
+	// tableAuditLog represents the audit log table name to use for the postgres database driver
+	tableAuditLog = "audit_log"
+
+	// tableWebhooks represents the webhook table name to use for the postgres database driver
+	tableWebhooks = "webhooks"
+
+	// tableWebhookDeliveries represents the webhook delivery table name to use for the postgres database driver
+	tableWebhookDeliveries = "webhook_deliveries"
+
 	// tableInvites represents the invite table name to use for the postgres database driver
 	tableInvites = "invites"
 )
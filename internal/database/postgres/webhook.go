@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+	"time"
+)
+
+// webhookColumns represents the ordered webhook columns every webhook query selects
+const webhookColumns = "namespace, id, url, events, secret, active, created_at"
+
+// deliveryColumns represents the ordered webhook delivery columns every webhook delivery query selects
+const deliveryColumns = "id, namespace, webhook, event, payload, status, attempts, response_status, error, next_attempt_at, last_attempt_at, created_at"
+
+// WebhookService represents the postgres webhook service
+type WebhookService struct {
+	db querier
+}
+
+// Webhook searches for a webhook by its ID
+func (service *WebhookService) Webhook(namespace, id string) (*shared.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = $1 AND id = $2", webhookColumns, tableWebhooks)
+	webhook, err := rowToWebhook(service.db.QueryRow(context.Background(), query, namespace, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Webhooks searches for all webhooks of a namespace ordered by their creation time
+func (service *WebhookService) Webhooks(namespace string) ([]*shared.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = $1 ORDER BY created_at, id", webhookColumns, tableWebhooks)
+	rows, err := service.db.Query(context.Background(), query, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*shared.Webhook
+	for rows.Next() {
+		webhook, err := rowToWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// CreateOrReplace creates or replaces a webhook; the creation time of an existing webhook is kept
+func (service *WebhookService) CreateOrReplace(webhook *shared.Webhook) error {
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (namespace, id, url, events, secret, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (namespace, id) DO UPDATE
+			SET url = excluded.url,
+				events = excluded.events,
+				secret = excluded.secret,
+				active = excluded.active
+    `, tableWebhooks)
+	_, err := service.db.Exec(context.Background(), query, webhook.Namespace, webhook.ID, webhook.URL, eventsValue(webhook.Events), webhook.Secret, webhook.Active, webhook.CreatedAt)
+	return err
+}
+
+// Delete deletes a webhook including its deliveries
+func (service *WebhookService) Delete(namespace, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 AND id = $2", tableWebhooks)
+	_, err := service.db.Exec(context.Background(), query, namespace, id)
+	return err
+}
+
+// Enqueue stores new pending deliveries
+func (service *WebhookService) Enqueue(deliveries []*shared.WebhookDelivery) error {
+	ids := make([]string, len(deliveries))
+	namespaces := make([]string, len(deliveries))
+	webhooks := make([]string, len(deliveries))
+	events := make([]string, len(deliveries))
+	payloads := make([]string, len(deliveries))
+	statuses := make([]string, len(deliveries))
+	nextAttemptAt := make([]*time.Time, len(deliveries))
+	createdAt := make([]time.Time, len(deliveries))
+	for i, delivery := range deliveries {
+		if delivery.CreatedAt.IsZero() {
+			delivery.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		}
+		ids[i], namespaces[i], webhooks[i] = delivery.ID, delivery.Namespace, delivery.Webhook
+		events[i], payloads[i], statuses[i] = string(delivery.Event), string(delivery.Payload), string(delivery.Status)
+		nextAttemptAt[i], createdAt[i] = delivery.NextAttemptAt, delivery.CreatedAt
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, namespace, webhook, event, payload, status, next_attempt_at, created_at)
+		SELECT *
+		FROM UNNEST($1::VARCHAR[], $2::VARCHAR[], $3::VARCHAR[], $4::VARCHAR[], $5::TEXT[], $6::VARCHAR[], $7::TIMESTAMPTZ[], $8::TIMESTAMPTZ[])
+    `, tableWebhookDeliveries)
+	_, err := service.db.Exec(context.Background(), query, ids, namespaces, webhooks, events, payloads, statuses, nextAttemptAt, createdAt)
+	return err
+}
+
+// Delivery searches for a single delivery of a webhook
+func (service *WebhookService) Delivery(namespace, webhook, id string) (*shared.WebhookDelivery, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = $1 AND webhook = $2 AND id = $3", deliveryColumns, tableWebhookDeliveries)
+	delivery, err := rowToDelivery(service.db.QueryRow(context.Background(), query, namespace, webhook, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Deliveries searches for the latest deliveries of a webhook, optionally restricted to the given status
+func (service *WebhookService) Deliveries(namespace, webhook string, status shared.DeliveryStatus, limit int) ([]*shared.WebhookDelivery, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = $1 AND webhook = $2 AND ($3 = '' OR status = $3) ORDER BY created_at DESC, id DESC LIMIT $4", deliveryColumns, tableWebhookDeliveries)
+	return service.queryDeliveries(query, namespace, webhook, string(status), limit)
+}
+
+// Claim returns up to the given amount of due pending deliveries and postpones their next attempt by the given lease;
+// deliveries which are locked by a concurrent claim get skipped
+func (service *WebhookService) Claim(limit int, lease time.Duration) ([]*shared.WebhookDelivery, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	query := fmt.Sprintf(`
+		UPDATE %[1]s
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[2]s
+    `, tableWebhookDeliveries, deliveryColumns)
+	return service.queryDeliveries(query, now.Add(lease), string(shared.DeliveryPending), now, limit)
+}
+
+// UpdateDelivery stores the outcome of an attempt of a delivery
+func (service *WebhookService) UpdateDelivery(delivery *shared.WebhookDelivery) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET status = $1, attempts = $2, response_status = $3, error = $4, next_attempt_at = $5, last_attempt_at = $6
+		WHERE id = $7
+    `, tableWebhookDeliveries)
+	_, err := service.db.Exec(context.Background(), query, string(delivery.Status), delivery.Attempts, delivery.ResponseStatus, delivery.Error,
+		delivery.NextAttemptAt, delivery.LastAttemptAt, delivery.ID)
+	return err
+}
+
+// PruneDeliveries deletes every finished delivery created before the given time and returns their amount
+func (service *WebhookService) PruneDeliveries(before time.Time) (int, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE status <> $1 AND created_at < $2", tableWebhookDeliveries)
+	tag, err := service.db.Exec(context.Background(), query, string(shared.DeliveryPending), before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// queryDeliveries executes a query selecting the delivery columns and collects the resulting deliveries
+func (service *WebhookService) queryDeliveries(query string, args ...interface{}) ([]*shared.WebhookDelivery, error) {
+	rows, err := service.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*shared.WebhookDelivery
+	for rows.Next() {
+		delivery, err := rowToDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// rowToWebhook creates a webhook from a postgres row
+func rowToWebhook(row pgx.Row) (*shared.Webhook, error) {
+	var namespace string
+	var id string
+	var url string
+	var events string
+	var secret string
+	var active bool
+	var createdAt time.Time
+
+	err := row.Scan(&namespace, &id, &url, &events, &secret, &active, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.Webhook{
+		Namespace: namespace,
+		ID:        id,
+		URL:       url,
+		Events:    eventsSlice(events),
+		Secret:    secret,
+		Active:    active,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// rowToDelivery creates a webhook delivery from a postgres row
+func rowToDelivery(row pgx.Row) (*shared.WebhookDelivery, error) {
+	var id string
+	var namespace string
+	var webhook string
+	var event string
+	var payload string
+	var status string
+	var attempts int
+	var responseStatus int
+	var deliveryError string
+	var nextAttemptAt *time.Time
+	var lastAttemptAt *time.Time
+	var createdAt time.Time
+
+	err := row.Scan(&id, &namespace, &webhook, &event, &payload, &status, &attempts, &responseStatus, &deliveryError, &nextAttemptAt, &lastAttemptAt, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.WebhookDelivery{
+		ID:             id,
+		Namespace:      namespace,
+		Webhook:        webhook,
+		Event:          shared.WebhookEvent(event),
+		Payload:        []byte(payload),
+		Status:         shared.DeliveryStatus(status),
+		Attempts:       attempts,
+		ResponseStatus: responseStatus,
+		Error:          deliveryError,
+		NextAttemptAt:  nextAttemptAt,
+		LastAttemptAt:  lastAttemptAt,
+		CreatedAt:      createdAt,
+	}, nil
+}
+
+// eventsValue converts webhook events into the space-separated list they get stored as
+func eventsValue(events []shared.WebhookEvent) string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return strings.Join(names, " ")
+}
+
+// eventsSlice converts a stored space-separated list of webhook events back into a slice
+func eventsSlice(value string) []shared.WebhookEvent {
+	events := []shared.WebhookEvent{}
+	for _, name := range strings.Fields(value) {
+		events = append(events, shared.WebhookEvent(name))
+	}
+	return events
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// auditColumns represents the ordered audit log columns every audit log query selects
+const auditColumns = "id, actor, actor_name, action, namespace, target, ip, request_id, created_at"
+
+// auditKeyColumns represents the columns uniquely identifying an audit log entry in the order listings get sorted by
+var auditKeyColumns = []string{"id"}
+
+// AuditService represents the sqlite audit log service
+type AuditService struct {
+	db querier
+}
+
+// Record appends an entry to the audit log and assigns its ID and creation time
+func (service *AuditService) Record(entry *shared.AuditEntry) error {
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	query := fmt.Sprintf(`
+		INSERT INTO %s (actor, actor_name, action, namespace, target, ip, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, tableAuditLog)
+	result, err := service.db.Exec(query, entry.Actor, entry.ActorName, string(entry.Action), entry.Namespace, entry.Target, entry.IP, entry.RequestID, timeValue(&entry.CreatedAt))
+	if err != nil {
+		return err
+	}
+	entry.ID, err = result.LastInsertId()
+	return err
+}
+
+// Entries searches for the audit log entries matching the given query
+func (service *AuditService) Entries(query *shared.AuditQuery) ([]*shared.AuditEntry, error) {
+	order := shared.SortByKey
+	if query.Descending {
+		order = shared.SortByKeyDescending
+	}
+
+	listing := new(listing)
+	if query.Actor != "" {
+		listing.where("actor = " + listing.arg(query.Actor))
+	}
+	if query.Action != "" {
+		listing.where("action = " + listing.arg(string(query.Action)))
+	}
+	if query.Namespace != "" {
+		listing.where("namespace = " + listing.arg(query.Namespace))
+	}
+	listing.createdBetween(query.CreatedAfter, query.CreatedBefore)
+	if query.AfterID > 0 {
+		listing.after(order, auditKeyColumns, time.Time{}, query.AfterID)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s", auditColumns, tableAuditLog) + listing.clauses(order, auditKeyColumns, query.Limit)
+	rows, err := service.db.Query(statement, listing.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*shared.AuditEntry
+	for rows.Next() {
+		entry, err := rowToAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// rowToAuditEntry creates an audit log entry from a sqlite row
+func rowToAuditEntry(row scanner) (*shared.AuditEntry, error) {
+	var id int64
+	var actor string
+	var actorName string
+	var action string
+	var namespace string
+	var target string
+	var ip string
+	var requestID string
+	var createdAt time.Time
+
+	err := row.Scan(&id, &actor, &actorName, &action, &namespace, &target, &ip, &requestID, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.AuditEntry{
+		ID:        id,
+		Actor:     actor,
+		ActorName: actorName,
+		Action:    shared.AuditAction(action),
+		Namespace: namespace,
+		Target:    target,
+		IP:        ip,
+		RequestID: requestID,
+		CreatedAt: createdAt,
+	}, nil
+}
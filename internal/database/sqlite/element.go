@@ -0,0 +1,413 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// elementColumns represents the ordered element columns every element query selects
+const elementColumns = "namespace, key, type, data, expires_at, remaining_views, blob, content_type, filename, size, language, encryption, password_hash, revision, created_at, updated_at, last_accessed_at"
+
+// elementKeyColumns represents the columns uniquely identifying an element in the order listings get sorted by
+var elementKeyColumns = []string{"namespace", "key"}
+
+// revisionContentColumns represents the element columns which get recorded in every revision
+const revisionContentColumns = "namespace, key, revision, type, data, blob, content_type, filename, size, language, encryption"
+
+// ElementService represents the sqlite element service
+type ElementService struct {
+	db querier
+}
+
+// Element searches for a single element with a specific key in a specific namespace
+func (service *ElementService) Element(sourceNamespace, sourceKey string) (*shared.Element, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? AND key = ?", elementColumns, tableElements)
+	element, err := rowToElement(service.db.QueryRow(query, sourceNamespace, sourceKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return element, nil
+}
+
+// Elements searches for the elements matching the given query
+func (service *ElementService) Elements(query *shared.ElementQuery) ([]*shared.Element, error) {
+	listing := new(listing)
+	if query.Namespace != "" {
+		listing.where("namespace = " + listing.arg(query.Namespace))
+	}
+	if query.Type != nil {
+		listing.where("type = " + listing.arg(*query.Type))
+	}
+	listing.prefix("key", query.KeyPrefix)
+	listing.createdBetween(query.CreatedAfter, query.CreatedBefore)
+
+	if query.After != nil {
+		listing.after(query.Sort, elementKeyColumns, query.After.CreatedAt, query.After.Namespace, query.After.Key)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s", elementColumns, tableElements) + listing.clauses(query.Sort, elementKeyColumns, query.Limit)
+	return service.queryElements(statement, listing.args...)
+}
+
+// CreateOrReplace creates or replaces an element and records its content as a new revision
+func (service *ElementService) CreateOrReplace(element *shared.Element) error {
+	element.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	element.UpdatedAt = element.CreatedAt
+	encryption, err := encryptionValue(element.Encryption)
+	if err != nil {
+		return err
+	}
+
+	return atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s (namespace, key, type, data, expires_at, remaining_views, blob, content_type, filename, size, language, encryption, password_hash, created_at, updated_at, last_accessed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (namespace, key) DO UPDATE
+				SET type = excluded.type,
+					data = excluded.data,
+					expires_at = excluded.expires_at,
+					remaining_views = excluded.remaining_views,
+					blob = excluded.blob,
+					content_type = excluded.content_type,
+					filename = excluded.filename,
+					size = excluded.size,
+					language = excluded.language,
+					encryption = excluded.encryption,
+					password_hash = excluded.password_hash,
+					created_at = excluded.created_at,
+					updated_at = excluded.updated_at,
+					last_accessed_at = excluded.last_accessed_at,
+					revision = %[1]s.revision + 1
+		`, tableElements)
+		_, err := db.Exec(query, element.Namespace, element.Key, element.Type, element.Data, timeValue(element.ExpiresAt), element.RemainingViews, element.Blob, element.ContentType, element.Filename, element.Size, element.Language, encryption, element.PasswordHash, timeValue(&element.CreatedAt), timeValue(&element.UpdatedAt), timeValue(element.LastAccessedAt))
+		if err != nil {
+			return err
+		}
+		return recordRevision(db, element)
+	})
+}
+
+// Update replaces an existing element if its revision still matches the expected one, increments the revision and records the new content;
+// it returns false if the element does not exist anymore or was modified concurrently
+func (service *ElementService) Update(element *shared.Element, expectedRevision int) (bool, error) {
+	element.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	encryption, err := encryptionValue(element.Encryption)
+	if err != nil {
+		return false, err
+	}
+
+	updated := false
+	err = atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf(`
+			UPDATE %s
+			SET type = ?,
+				data = ?,
+				expires_at = ?,
+				remaining_views = ?,
+				blob = ?,
+				content_type = ?,
+				filename = ?,
+				size = ?,
+				language = ?,
+				encryption = ?,
+				password_hash = ?,
+				updated_at = ?,
+				revision = revision + 1
+			WHERE namespace = ? AND key = ? AND revision = ?
+		`, tableElements)
+		result, err := db.Exec(query, element.Type, element.Data, timeValue(element.ExpiresAt), element.RemainingViews, element.Blob, element.ContentType, element.Filename, element.Size, element.Language, encryption, element.PasswordHash, timeValue(&element.UpdatedAt), element.Namespace, element.Key, expectedRevision)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected == 0 {
+			return err
+		}
+		updated = true
+		return recordRevision(db, element)
+	})
+	return updated, err
+}
+
+// recordRevision records the content of a just written element as a new revision and reads back its revision number;
+// the bundled sqlite version does not support RETURNING clauses yet
+func recordRevision(db querier, element *shared.Element) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[3]s, created_at, actor)
+		SELECT %[3]s, ?, ? FROM %[2]s WHERE namespace = ? AND key = ?
+	`, tableElementRevisions, tableElements, revisionContentColumns)
+	if _, err := db.Exec(query, time.Now().UTC(), element.Actor, element.Namespace, element.Key); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf("SELECT revision FROM %s WHERE namespace = ? AND key = ?", tableElements)
+	return db.QueryRow(query, element.Namespace, element.Key).Scan(&element.Revision)
+}
+
+// Delete deletes an element
+func (service *ElementService) Delete(namespace, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND key = ?", tableElements)
+	_, err := service.db.Exec(query, namespace, key)
+	return err
+}
+
+// DeleteInNamespace deletes every element in a namespace and returns the deleted elements
+func (service *ElementService) DeleteInNamespace(namespace string) ([]*shared.Element, error) {
+	var elements []*shared.Element
+	err := atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ?", elementColumns, tableElements)
+		found, err := (&ElementService{db: db}).queryElements(query, namespace)
+		if err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf("DELETE FROM %s WHERE namespace = ?", tableElements)
+		if _, err = db.Exec(query, namespace); err != nil {
+			return err
+		}
+		elements = found
+		return nil
+	})
+	return elements, err
+}
+
+// DeleteExpired deletes every element which reached its expiration time or has no views left and returns the deleted elements
+func (service *ElementService) DeleteExpired() ([]*shared.Element, error) {
+	now := time.Now()
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE expires_at <= ? OR remaining_views <= 0", elementColumns, tableElements)
+	elements, err := service.queryElements(query, timeValue(&now))
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND key = ? AND (expires_at <= ? OR remaining_views <= 0)", tableElements)
+	for _, element := range elements {
+		if _, err = service.db.Exec(query, element.Namespace, element.Key, timeValue(&now)); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// ConsumeView atomically decrements the remaining views of a view-limited element and returns the updated element;
+// it returns nil if the element does not exist, is not view-limited or has no views left
+func (service *ElementService) ConsumeView(namespace, key string) (*shared.Element, error) {
+	// Read the updated element within the same transaction so no concurrent view gets consumed in between
+	var element *shared.Element
+	err := atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf(`
+			UPDATE %s
+			SET remaining_views = remaining_views - 1
+			WHERE namespace = ? AND key = ? AND remaining_views > 0
+		`, tableElements)
+		result, err := db.Exec(query, namespace, key)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected == 0 {
+			return err
+		}
+		element, err = (&ElementService{db: db}).Element(namespace, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return element, nil
+}
+
+// Touch records the given accesses of elements unless a later access was already recorded and returns the ones which were the first accesses of their elements
+func (service *ElementService) Touch(accesses []*shared.Access) ([]*shared.Access, error) {
+	var first []*shared.Access
+	err := atomic(service.db, func(db querier) error {
+		first = nil
+		firstQuery := fmt.Sprintf("UPDATE %s SET last_accessed_at = ? WHERE namespace = ? AND key = ? AND last_accessed_at IS NULL", tableElements)
+		laterQuery := fmt.Sprintf("UPDATE %s SET last_accessed_at = ? WHERE namespace = ? AND key = ? AND last_accessed_at < ?", tableElements)
+		for _, access := range accesses {
+			accessedAt := timeValue(&access.AccessedAt)
+			result, err := db.Exec(firstQuery, accessedAt, access.Namespace, access.Key)
+			if err != nil {
+				return err
+			}
+			if affected, err := result.RowsAffected(); err != nil {
+				return err
+			} else if affected > 0 {
+				first = append(first, access)
+				continue
+			}
+			if _, err := db.Exec(laterQuery, accessedAt, access.Namespace, access.Key, accessedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return first, nil
+}
+
+// Revisions searches for all revisions of an element ordered by their number
+func (service *ElementService) Revisions(namespace, key string) ([]*shared.Revision, error) {
+	query := fmt.Sprintf("SELECT %s, created_at, actor FROM %s WHERE namespace = ? AND key = ? ORDER BY revision", revisionContentColumns, tableElementRevisions)
+	rows, err := service.db.Query(query, namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*shared.Revision
+	for rows.Next() {
+		revision, err := rowToRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// Revision searches for a single revision of an element
+func (service *ElementService) Revision(namespace, key string, number int) (*shared.Revision, error) {
+	query := fmt.Sprintf("SELECT %s, created_at, actor FROM %s WHERE namespace = ? AND key = ? AND revision = ?", revisionContentColumns, tableElementRevisions)
+	revision, err := rowToRevision(service.db.QueryRow(query, namespace, key, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ReferencedBlobs searches for the IDs of all blobs referenced by elements or their revisions
+func (service *ElementService) ReferencedBlobs() ([]string, error) {
+	query := fmt.Sprintf("SELECT blob FROM %s WHERE blob <> '' UNION SELECT blob FROM %s WHERE blob <> ''", tableElements, tableElementRevisions)
+	rows, err := service.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// queryElements executes the given query and creates an element out of every resulting row
+func (service *ElementService) queryElements(query string, args ...interface{}) ([]*shared.Element, error) {
+	rows, err := service.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var elements []*shared.Element
+	for rows.Next() {
+		element, err := rowToElement(rows)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, rows.Err()
+}
+
+// rowToElement creates an element from a sqlite row
+func rowToElement(row scanner) (*shared.Element, error) {
+	var namespace string
+	var key string
+	var typ shared.ElementType
+	var data string
+	var expiresAt sql.NullTime
+	var remainingViews *int
+	var blob string
+	var contentType string
+	var filename string
+	var size int64
+	var language string
+	var rawEncryption string
+	var passwordHash string
+	var revision int
+	var createdAt time.Time
+	var updatedAt time.Time
+	var lastAccessedAt sql.NullTime
+
+	err := row.Scan(&namespace, &key, &typ, &data, &expiresAt, &remainingViews, &blob, &contentType, &filename, &size, &language, &rawEncryption, &passwordHash, &revision, &createdAt, &updatedAt, &lastAccessedAt)
+	if err != nil {
+		return nil, err
+	}
+	encryption, err := encryptionPointer(rawEncryption)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.Element{
+		Namespace:      namespace,
+		Key:            key,
+		Type:           typ,
+		Data:           data,
+		ExpiresAt:      timePointer(expiresAt),
+		RemainingViews: remainingViews,
+		Blob:           blob,
+		ContentType:    contentType,
+		Filename:       filename,
+		Size:           size,
+		Language:       language,
+		Encryption:     encryption,
+		PasswordHash:   passwordHash,
+		Revision:       revision,
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+		LastAccessedAt: timePointer(lastAccessedAt),
+	}, nil
+}
+
+// encryptionValue encodes optional encryption metadata into its JSON column representation
+func encryptionValue(encryption *shared.Encryption) (string, error) {
+	if encryption == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(encryption)
+	return string(encoded), err
+}
+
+// encryptionPointer decodes the JSON column representation of optional encryption metadata
+func encryptionPointer(value string) (*shared.Encryption, error) {
+	if value == "" {
+		return nil, nil
+	}
+	encryption := new(shared.Encryption)
+	if err := json.Unmarshal([]byte(value), encryption); err != nil {
+		return nil, err
+	}
+	return encryption, nil
+}
+
+// rowToRevision creates a revision from a sqlite row
+func rowToRevision(row scanner) (*shared.Revision, error) {
+	revision := new(shared.Revision)
+	var rawEncryption string
+	err := row.Scan(&revision.Namespace, &revision.Key, &revision.Revision, &revision.Type, &revision.Data, &revision.Blob, &revision.ContentType, &revision.Filename, &revision.Size, &revision.Language, &rawEncryption, &revision.CreatedAt, &revision.Actor)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Encryption, err = encryptionPointer(rawEncryption); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// HitService represents the sqlite hit service
+type HitService struct {
+	db querier
+}
+
+// Record stores the given hits; hits of elements which do not exist anymore are dropped
+func (service *HitService) Record(hits []*shared.Hit) error {
+	return atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf(`
+			INSERT INTO %[1]s (namespace, key, hit_at, referrer_host, user_agent_class, country)
+			SELECT ?, ?, ?, ?, ?, ?
+			WHERE EXISTS (SELECT 1 FROM %[2]s WHERE namespace = ? AND key = ?)
+		`, tableElementHits, tableElements)
+		for _, hit := range hits {
+			_, err := db.Exec(query, hit.Namespace, hit.Key, timeValue(&hit.HitAt), hit.ReferrerHost, string(hit.UserAgentClass), hit.Country, hit.Namespace, hit.Key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Stats aggregates the hits of an element since the given time
+func (service *HitService) Stats(namespace, key string, since time.Time) (*shared.HitStats, error) {
+	stats := new(shared.HitStats)
+	var err error
+	if stats.Days, err = service.count("SUBSTR(hit_at, 1, 10)", "value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	for _, day := range stats.Days {
+		stats.Total += day.Hits
+	}
+	if stats.Referrers, err = service.count("referrer_host", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	if stats.UserAgents, err = service.count("user_agent_class", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	if stats.Countries, err = service.count("country", "hits DESC, value", namespace, key, since); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// count counts the hits of an element since the given time grouped by the value of the given expression;
+// hit times are stored as UTC timestamps, so their first ten characters represent the day
+func (service *HitService) count(expression, order, namespace, key string, since time.Time) ([]*shared.HitCount, error) {
+	query := fmt.Sprintf(`
+		SELECT %s AS value, COUNT(*) AS hits
+		FROM %s
+		WHERE namespace = ? AND key = ? AND hit_at >= ?
+		GROUP BY value
+		ORDER BY %s
+	`, expression, tableElementHits, order)
+	rows, err := service.db.Query(query, namespace, key, timeValue(&since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*shared.HitCount{}
+	for rows.Next() {
+		count := new(shared.HitCount)
+		if err := rows.Scan(&count.Value, &count.Hits); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+)
+
+// InviteService represents the sqlite invite service
+type InviteService struct {
+	db querier
+}
+
+// IsValid searches for a single invite with a specific token
+func (service *InviteService) IsValid(token string) (bool, error) {
+	query := fmt.Sprintf("SELECT token FROM %s WHERE token = ?", tableInvites)
+	var found string
+	if err := service.db.QueryRow(query, token).Scan(&found); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Invites searches for all invites
+func (service *InviteService) Invites() ([]shared.Invite, error) {
+	query := fmt.Sprintf("SELECT token FROM %s", tableInvites)
+	rows, err := service.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []shared.Invite
+	for rows.Next() {
+		var invite shared.Invite
+		if err = rows.Scan(&invite); err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// Create creates an invite
+func (service *InviteService) Create(invite shared.Invite) error {
+	query := fmt.Sprintf("INSERT OR IGNORE INTO %s (token) VALUES (?)", tableInvites)
+	_, err := service.db.Exec(query, invite)
+	return err
+}
+
+// Delete deletes an invite
+func (service *InviteService) Delete(token string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE token = ?", tableInvites)
+	_, err := service.db.Exec(query, token)
+	return err
+}
+
+// Consume deletes an invite and reports whether it existed
+func (service *InviteService) Consume(token string) (bool, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE token = ?", tableInvites)
+	result, err := service.db.Exec(query, token)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"time"
+)
+
+// namespaceColumns represents the ordered namespace columns every namespace query selects
+const namespaceColumns = "id, token, active, created_at, updated_at, last_accessed_at"
+
+// namespaceKeyColumns represents the columns uniquely identifying a namespace in the order listings get sorted by
+var namespaceKeyColumns = []string{"id"}
+
+// NamespaceService represents the sqlite namespace service
+type NamespaceService struct {
+	db querier
+}
+
+// Namespace searches for a namespace by its ID
+func (service *NamespaceService) Namespace(sourceID string) (*shared.Namespace, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", namespaceColumns, tableNamespaces)
+	namespace, err := rowToNamespace(service.db.QueryRow(query, sourceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return namespace, nil
+}
+
+// Namespaces searches for the namespaces matching the given query
+func (service *NamespaceService) Namespaces(query *shared.NamespaceQuery) ([]*shared.Namespace, error) {
+	listing := new(listing)
+	listing.prefix("id", query.IDPrefix)
+	listing.createdBetween(query.CreatedAfter, query.CreatedBefore)
+	if query.After != nil {
+		listing.after(query.Sort, namespaceKeyColumns, query.After.CreatedAt, query.After.ID)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s", namespaceColumns, tableNamespaces) + listing.clauses(query.Sort, namespaceKeyColumns, query.Limit)
+	rows, err := service.db.Query(statement, listing.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var namespaces []*shared.Namespace
+	for rows.Next() {
+		namespace, err := rowToNamespace(rows)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, rows.Err()
+}
+
+// CreateOrReplace creates or replaces a namespace; the creation and access times of an existing namespace are kept
+func (service *NamespaceService) CreateOrReplace(namespace *shared.Namespace) error {
+	namespace.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if namespace.CreatedAt.IsZero() {
+		namespace.CreatedAt = namespace.UpdatedAt
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, token, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+			SET token = excluded.token,
+				active = excluded.active,
+				updated_at = excluded.updated_at
+    `, tableNamespaces)
+	_, err := service.db.Exec(query, namespace.ID, namespace.Token, namespace.Active, timeValue(&namespace.CreatedAt), timeValue(&namespace.UpdatedAt))
+	return err
+}
+
+// Create creates a namespace unless one with the same ID already exists; it returns false if one does
+func (service *NamespaceService) Create(namespace *shared.Namespace) (bool, error) {
+	namespace.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	namespace.UpdatedAt = namespace.CreatedAt
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, token, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING
+    `, tableNamespaces)
+	result, err := service.db.Exec(query, namespace.ID, namespace.Token, namespace.Active, timeValue(&namespace.CreatedAt), timeValue(&namespace.UpdatedAt))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Delete deletes a namespace
+func (service *NamespaceService) Delete(id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", tableNamespaces)
+	_, err := service.db.Exec(query, id)
+	return err
+}
+
+// Touch records the given accesses of elements as accesses of their namespaces unless a later access was already recorded
+func (service *NamespaceService) Touch(accesses []*shared.Access) error {
+	return atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf(`
+			UPDATE %s
+			SET last_accessed_at = ?
+			WHERE id = ? AND (last_accessed_at IS NULL OR last_accessed_at < ?)
+		`, tableNamespaces)
+		for _, access := range accesses {
+			accessedAt := timeValue(&access.AccessedAt)
+			if _, err := db.Exec(query, accessedAt, access.Namespace, accessedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceToken replaces the token hash of a namespace if it still equals the previous one
+func (service *NamespaceService) ReplaceToken(id, previous, replacement string) (bool, error) {
+	query := fmt.Sprintf("UPDATE %s SET token = ? WHERE id = ? AND token = ?", tableNamespaces)
+	result, err := service.db.Exec(query, replacement, id, previous)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// rowToNamespace creates a namespace from a sqlite row
+func rowToNamespace(row scanner) (*shared.Namespace, error) {
+	var id string
+	var token string
+	var active bool
+	var createdAt time.Time
+	var updatedAt time.Time
+	var lastAccessedAt sql.NullTime
+
+	err := row.Scan(&id, &token, &active, &createdAt, &updatedAt, &lastAccessedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.Namespace{
+		ID:             id,
+		Token:          token,
+		Active:         active,
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+		LastAccessedAt: timePointer(lastAccessedAt),
+	}, nil
+}
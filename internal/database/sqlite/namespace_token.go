@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+	"time"
+)
+
+// tokenColumns represents the ordered namespace token columns every namespace token query selects
+const tokenColumns = "namespace, id, name, hash, scopes, expires_at, created_at"
+
+// NamespaceTokenService represents the sqlite namespace token service
+type NamespaceTokenService struct {
+	db querier
+}
+
+// Token searches for a namespace token by its ID
+func (service *NamespaceTokenService) Token(namespace, id string) (*shared.NamespaceToken, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? AND id = ?", tokenColumns, tableNamespaceTokens)
+	token, err := rowToToken(service.db.QueryRow(query, namespace, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// Tokens searches for all tokens of a namespace ordered by their creation time
+func (service *NamespaceTokenService) Tokens(namespace string) ([]*shared.NamespaceToken, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? ORDER BY created_at, id", tokenColumns, tableNamespaceTokens)
+	rows, err := service.db.Query(query, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*shared.NamespaceToken
+	for rows.Next() {
+		token, err := rowToToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// CreateOrReplace creates or replaces a namespace token; the creation time of an existing token is kept
+func (service *NamespaceTokenService) CreateOrReplace(token *shared.NamespaceToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (namespace, id, name, hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (namespace, id) DO UPDATE
+			SET name = excluded.name,
+				hash = excluded.hash,
+				scopes = excluded.scopes,
+				expires_at = excluded.expires_at
+    `, tableNamespaceTokens)
+	_, err := service.db.Exec(query, token.Namespace, token.ID, token.Name, token.Hash, scopesValue(token.Scopes), timeValue(token.ExpiresAt), timeValue(&token.CreatedAt))
+	return err
+}
+
+// Delete deletes a namespace token
+func (service *NamespaceTokenService) Delete(namespace, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND id = ?", tableNamespaceTokens)
+	_, err := service.db.Exec(query, namespace, id)
+	return err
+}
+
+// ReplaceHash replaces the hash of a namespace token if it still equals the previous one
+func (service *NamespaceTokenService) ReplaceHash(namespace, id, previous, replacement string) (bool, error) {
+	query := fmt.Sprintf("UPDATE %s SET hash = ? WHERE namespace = ? AND id = ? AND hash = ?", tableNamespaceTokens)
+	result, err := service.db.Exec(query, replacement, namespace, id, previous)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// rowToToken creates a namespace token from a sqlite row
+func rowToToken(row scanner) (*shared.NamespaceToken, error) {
+	var namespace string
+	var id string
+	var name string
+	var hash string
+	var scopes string
+	var expiresAt sql.NullTime
+	var createdAt time.Time
+
+	err := row.Scan(&namespace, &id, &name, &hash, &scopes, &expiresAt, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.NamespaceToken{
+		Namespace: namespace,
+		ID:        id,
+		Name:      name,
+		Hash:      hash,
+		Scopes:    scopesSlice(scopes),
+		ExpiresAt: timePointer(expiresAt),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// scopesValue converts token scopes into the space-separated list they get stored as
+func scopesValue(scopes []shared.TokenScope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, " ")
+}
+
+// scopesSlice converts a stored space-separated list of token scopes back into a slice
+func scopesSlice(value string) []shared.TokenScope {
+	scopes := []shared.TokenScope{}
+	for _, name := range strings.Fields(value) {
+		scopes = append(scopes, shared.TokenScope(name))
+	}
+	return scopes
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/database/migration"
+)
+
+// Migrations returns every sqlite schema migration ordered by its version
+func (store *Store) Migrations() []migration.Migration {
+	return migrations
+}
+
+// SchemaVersion returns the currently applied schema version
+func (store *Store) SchemaVersion() (int, error) {
+	if err := store.initializeVersionTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", tableSchemaVersion)
+	if err := store.db.QueryRow(query).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Apply atomically applies a single migration and records its version
+func (store *Store) Apply(mig migration.Migration) error {
+	if err := store.initializeVersionTable(); err != nil {
+		return err
+	}
+
+	// Begin an immediate transaction to serialize concurrent migration runs
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Skip the migration if another process applied it in the meantime
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", tableSchemaVersion)
+	if err = tx.QueryRow(query).Scan(&version); err != nil {
+		return err
+	}
+	if version >= mig.Version {
+		return nil
+	}
+
+	// Apply the migration and record its version
+	for _, statement := range mig.Statements {
+		if _, err = tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	query = fmt.Sprintf("INSERT INTO %s (version, description) VALUES (?, ?)", tableSchemaVersion)
+	if _, err = tx.Exec(query, mig.Version, mig.Description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// initializeVersionTable initializes the schema version table
+func (store *Store) initializeVersionTable() error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version INTEGER NOT NULL,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version)
+		)
+    `, tableSchemaVersion)
+	_, err := store.db.Exec(query)
+	return err
+}
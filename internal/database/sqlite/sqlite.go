@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+	"time"
+)
+
+// open opens a sqlite database located at the given path
+func open(path string) (*sql.DB, error) {
+	dsn := "file:" + path
+	if strings.Contains(path, "?") {
+		dsn += "&"
+	} else {
+		dsn += "?"
+	}
+	dsn += "_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate&_foreign_keys=1"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// scanner represents a single sqlite row which is ready to be scanned
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// timeValue converts an optional time into a value sqlite is able to compare lexicographically
+func timeValue(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.UTC()
+}
+
+// timePointer converts a nullable sqlite time into an optional time
+func timePointer(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/x0tf/server/internal/shared"
+)
+
+// querier represents a sqlite database handle or transaction queries can be executed on
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Store represents the sqlite storage backend sharing a single database handle between all of its services
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a new sqlite storage backend
+func NewStore(path string) (*Store, error) {
+	// Open the sqlite database
+	db, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create and return the store
+	return &Store{
+		db: db,
+	}, nil
+}
+
+// Services returns the database services operating directly on the database handle
+func (store *Store) Services() *shared.Services {
+	return newServices(store.db)
+}
+
+// Transaction executes the given function using services bound to a single transaction
+func (store *Store) Transaction(fn func(*shared.Services) error) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(newServices(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ping checks whether the database is reachable
+func (store *Store) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// Close closes the sqlite storage backend
+func (store *Store) Close() {
+	store.db.Close()
+}
+
+// atomic executes the given function inside of a transaction unless the querier already is one
+func atomic(db querier, fn func(querier) error) error {
+	handle, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := handle.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// newServices creates the database services operating on the given querier
+func newServices(db querier) *shared.Services {
+	return &shared.Services{
+		Namespaces:  &NamespaceService{db: db},
+		Elements:    &ElementService{db: db},
+		Invites:     &InviteService{db: db},
+		Hits:        &HitService{db: db},
+		Tokens:      &NamespaceTokenService{db: db},
+		AdminTokens: &AdminTokenService{db: db},
+		Audit:       &AuditService{db: db},
+		Webhooks:    &WebhookService{db: db},
+	}
+}
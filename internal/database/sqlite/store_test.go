@@ -0,0 +1,22 @@
+package sqlite
+
+import (
+	"github.com/x0tf/server/internal/database/migration"
+	"github.com/x0tf/server/internal/database/storetest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		store, err := NewStore(filepath.Join(t.TempDir(), "x0.db"))
+		if err != nil {
+			t.Fatalf("could not open the store: %v", err)
+		}
+		t.Cleanup(store.Close)
+		if _, err = migration.Up(store); err != nil {
+			t.Fatalf("could not migrate the store: %v", err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,263 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+	"time"
+)
+
+// webhookColumns represents the ordered webhook columns every webhook query selects
+const webhookColumns = "namespace, id, url, events, secret, active, created_at"
+
+// deliveryColumns represents the ordered webhook delivery columns every webhook delivery query selects
+const deliveryColumns = "id, namespace, webhook, event, payload, status, attempts, response_status, error, next_attempt_at, last_attempt_at, created_at"
+
+// WebhookService represents the sqlite webhook service
+type WebhookService struct {
+	db querier
+}
+
+// Webhook searches for a webhook by its ID
+func (service *WebhookService) Webhook(namespace, id string) (*shared.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? AND id = ?", webhookColumns, tableWebhooks)
+	webhook, err := rowToWebhook(service.db.QueryRow(query, namespace, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Webhooks searches for all webhooks of a namespace ordered by their creation time
+func (service *WebhookService) Webhooks(namespace string) ([]*shared.Webhook, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? ORDER BY created_at, id", webhookColumns, tableWebhooks)
+	rows, err := service.db.Query(query, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*shared.Webhook
+	for rows.Next() {
+		webhook, err := rowToWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// CreateOrReplace creates or replaces a webhook; the creation time of an existing webhook is kept
+func (service *WebhookService) CreateOrReplace(webhook *shared.Webhook) error {
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO %s (namespace, id, url, events, secret, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (namespace, id) DO UPDATE
+			SET url = excluded.url,
+				events = excluded.events,
+				secret = excluded.secret,
+				active = excluded.active
+    `, tableWebhooks)
+	_, err := service.db.Exec(query, webhook.Namespace, webhook.ID, webhook.URL, eventsValue(webhook.Events), webhook.Secret, webhook.Active, timeValue(&webhook.CreatedAt))
+	return err
+}
+
+// Delete deletes a webhook including its deliveries
+func (service *WebhookService) Delete(namespace, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = ? AND id = ?", tableWebhooks)
+	_, err := service.db.Exec(query, namespace, id)
+	return err
+}
+
+// Enqueue stores new pending deliveries
+func (service *WebhookService) Enqueue(deliveries []*shared.WebhookDelivery) error {
+	return atomic(service.db, func(db querier) error {
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", tableWebhookDeliveries, deliveryColumns)
+		for _, delivery := range deliveries {
+			if delivery.CreatedAt.IsZero() {
+				delivery.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+			}
+			_, err := db.Exec(query, delivery.ID, delivery.Namespace, delivery.Webhook, string(delivery.Event), string(delivery.Payload), string(delivery.Status),
+				delivery.Attempts, delivery.ResponseStatus, delivery.Error, timeValue(delivery.NextAttemptAt), timeValue(delivery.LastAttemptAt), timeValue(&delivery.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delivery searches for a single delivery of a webhook
+func (service *WebhookService) Delivery(namespace, webhook, id string) (*shared.WebhookDelivery, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? AND webhook = ? AND id = ?", deliveryColumns, tableWebhookDeliveries)
+	delivery, err := rowToDelivery(service.db.QueryRow(query, namespace, webhook, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Deliveries searches for the latest deliveries of a webhook, optionally restricted to the given status
+func (service *WebhookService) Deliveries(namespace, webhook string, status shared.DeliveryStatus, limit int) ([]*shared.WebhookDelivery, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE namespace = ? AND webhook = ? AND (? = '' OR status = ?) ORDER BY created_at DESC, id DESC LIMIT ?", deliveryColumns, tableWebhookDeliveries)
+	return service.queryDeliveries(service.db, query, namespace, webhook, string(status), string(status), limit)
+}
+
+// Claim returns up to the given amount of due pending deliveries and postpones their next attempt by the given lease
+func (service *WebhookService) Claim(limit int, lease time.Duration) ([]*shared.WebhookDelivery, error) {
+	var claimed []*shared.WebhookDelivery
+	err := atomic(service.db, func(db querier) error {
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?", deliveryColumns, tableWebhookDeliveries)
+		deliveries, err := service.queryDeliveries(db, query, string(shared.DeliveryPending), timeValue(&now), limit)
+		if err != nil {
+			return err
+		}
+
+		leasedUntil := now.Add(lease)
+		query = fmt.Sprintf("UPDATE %s SET next_attempt_at = ? WHERE id = ?", tableWebhookDeliveries)
+		for _, delivery := range deliveries {
+			if _, err := db.Exec(query, timeValue(&leasedUntil), delivery.ID); err != nil {
+				return err
+			}
+			delivery.NextAttemptAt = &leasedUntil
+		}
+		claimed = deliveries
+		return nil
+	})
+	return claimed, err
+}
+
+// UpdateDelivery stores the outcome of an attempt of a delivery
+func (service *WebhookService) UpdateDelivery(delivery *shared.WebhookDelivery) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET status = ?, attempts = ?, response_status = ?, error = ?, next_attempt_at = ?, last_attempt_at = ?
+		WHERE id = ?
+	`, tableWebhookDeliveries)
+	_, err := service.db.Exec(query, string(delivery.Status), delivery.Attempts, delivery.ResponseStatus, delivery.Error,
+		timeValue(delivery.NextAttemptAt), timeValue(delivery.LastAttemptAt), delivery.ID)
+	return err
+}
+
+// PruneDeliveries deletes every finished delivery created before the given time and returns their amount
+func (service *WebhookService) PruneDeliveries(before time.Time) (int, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE status <> ? AND created_at < ?", tableWebhookDeliveries)
+	result, err := service.db.Exec(query, string(shared.DeliveryPending), timeValue(&before))
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// queryDeliveries executes a query selecting the delivery columns and collects the resulting deliveries
+func (service *WebhookService) queryDeliveries(db querier, query string, args ...interface{}) ([]*shared.WebhookDelivery, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*shared.WebhookDelivery
+	for rows.Next() {
+		delivery, err := rowToDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// rowToWebhook creates a webhook from a sqlite row
+func rowToWebhook(row scanner) (*shared.Webhook, error) {
+	var namespace string
+	var id string
+	var url string
+	var events string
+	var secret string
+	var active bool
+	var createdAt time.Time
+
+	err := row.Scan(&namespace, &id, &url, &events, &secret, &active, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.Webhook{
+		Namespace: namespace,
+		ID:        id,
+		URL:       url,
+		Events:    eventsSlice(events),
+		Secret:    secret,
+		Active:    active,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// rowToDelivery creates a webhook delivery from a sqlite row
+func rowToDelivery(row scanner) (*shared.WebhookDelivery, error) {
+	var id string
+	var namespace string
+	var webhook string
+	var event string
+	var payload string
+	var status string
+	var attempts int
+	var responseStatus int
+	var deliveryError string
+	var nextAttemptAt sql.NullTime
+	var lastAttemptAt sql.NullTime
+	var createdAt time.Time
+
+	err := row.Scan(&id, &namespace, &webhook, &event, &payload, &status, &attempts, &responseStatus, &deliveryError, &nextAttemptAt, &lastAttemptAt, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shared.WebhookDelivery{
+		ID:             id,
+		Namespace:      namespace,
+		Webhook:        webhook,
+		Event:          shared.WebhookEvent(event),
+		Payload:        []byte(payload),
+		Status:         shared.DeliveryStatus(status),
+		Attempts:       attempts,
+		ResponseStatus: responseStatus,
+		Error:          deliveryError,
+		NextAttemptAt:  timePointer(nextAttemptAt),
+		LastAttemptAt:  timePointer(lastAttemptAt),
+		CreatedAt:      createdAt,
+	}, nil
+}
+
+// eventsValue converts webhook events into the space-separated list they get stored as
+func eventsValue(events []shared.WebhookEvent) string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return strings.Join(names, " ")
+}
+
+// eventsSlice converts a stored space-separated list of webhook events back into a slice
+func eventsSlice(value string) []shared.WebhookEvent {
+	events := []shared.WebhookEvent{}
+	for _, name := range strings.Fields(value) {
+		events = append(events, shared.WebhookEvent(name))
+	}
+	return events
+}
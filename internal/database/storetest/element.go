@@ -0,0 +1,255 @@
+package storetest
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// testConsumeView checks that concurrent resolvers consume every view exactly once and only a single one of them sees the last view
+func testConsumeView(t *testing.T, open Open) {
+	tests := []struct {
+		name      string
+		views     *int
+		resolvers int
+		consumed  int
+		last      int
+	}{
+		{name: "unlimited", views: nil, resolvers: 8, consumed: 0, last: 0},
+		{name: "burn after read", views: intPointer(1), resolvers: 16, consumed: 1, last: 1},
+		{name: "fewer views than resolvers", views: intPointer(5), resolvers: 16, consumed: 5, last: 1},
+		{name: "more views than resolvers", views: intPointer(20), resolvers: 16, consumed: 16, last: 0},
+		{name: "no views left", views: intPointer(0), resolvers: 8, consumed: 0, last: 0},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			services := open(t).Services()
+			createNamespace(t, services, "ns")
+			createElement(t, services, "ns", "key", test.views)
+
+			var mu sync.Mutex
+			var wg sync.WaitGroup
+			consumed, last := 0, 0
+			start := make(chan struct{})
+			for i := 0; i < test.resolvers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					element, err := services.Elements.ConsumeView("ns", "key")
+					if err != nil {
+						t.Errorf("could not consume a view: %v", err)
+						return
+					}
+					if element == nil {
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					consumed++
+					if element.Exhausted() {
+						last++
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			if consumed != test.consumed {
+				t.Errorf("consumed %d views, expected %d", consumed, test.consumed)
+			}
+			if last != test.last {
+				t.Errorf("%d resolvers saw the last view, expected %d", last, test.last)
+			}
+			element, err := services.Elements.Element("ns", "key")
+			if err != nil {
+				t.Fatalf("could not retrieve the element: %v", err)
+			}
+			if test.views != nil && *element.RemainingViews != *test.views-test.consumed {
+				t.Errorf("%d views are left, expected %d", *element.RemainingViews, *test.views-test.consumed)
+			}
+		})
+	}
+}
+
+// testUpdate checks that elements are only updated if their revision still matches the expected one
+func testUpdate(t *testing.T, open Open) {
+	tests := []struct {
+		name     string
+		key      string
+		expected int
+		updated  bool
+		revision int
+	}{
+		{name: "current revision", key: "key", expected: 1, updated: true, revision: 2},
+		{name: "outdated revision", key: "key", expected: 0, updated: false, revision: 1},
+		{name: "future revision", key: "key", expected: 2, updated: false, revision: 1},
+		{name: "missing element", key: "missing", expected: 1, updated: false, revision: 0},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			services := open(t).Services()
+			createNamespace(t, services, "ns")
+			element := createElement(t, services, "ns", "key", nil)
+
+			update := *element
+			update.Key = test.key
+			update.Data = "updated"
+			updated, err := services.Elements.Update(&update, test.expected)
+			if err != nil {
+				t.Fatalf("could not update the element: %v", err)
+			}
+			if updated != test.updated {
+				t.Errorf("reported the element to be updated: %t, expected %t", updated, test.updated)
+			}
+
+			stored, err := services.Elements.Element("ns", test.key)
+			if err != nil {
+				t.Fatalf("could not retrieve the element: %v", err)
+			}
+			if stored == nil {
+				if test.revision != 0 {
+					t.Fatalf("the element does not exist, expected revision %d", test.revision)
+				}
+				return
+			}
+			if stored.Revision != test.revision {
+				t.Errorf("the element is at revision %d, expected %d", stored.Revision, test.revision)
+			}
+			if (stored.Data == "updated") != test.updated {
+				t.Errorf("the element holds the data %q", stored.Data)
+			}
+		})
+	}
+
+	t.Run("concurrent updates", func(t *testing.T) {
+		services := open(t).Services()
+		createNamespace(t, services, "ns")
+		element := createElement(t, services, "ns", "key", nil)
+
+		var wg sync.WaitGroup
+		results := make(chan bool, 16)
+		start := make(chan struct{})
+		for i := 0; i < cap(results); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				update := *element
+				update.Data = "update " + strconv.Itoa(i)
+				updated, err := services.Elements.Update(&update, element.Revision)
+				if err != nil {
+					t.Errorf("could not update the element: %v", err)
+				}
+				results <- updated
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		close(results)
+
+		updates := 0
+		for updated := range results {
+			if updated {
+				updates++
+			}
+		}
+		if updates != 1 {
+			t.Errorf("%d concurrent updates of the same revision succeeded, expected 1", updates)
+		}
+		revisions, err := services.Elements.Revisions("ns", "key")
+		if err != nil {
+			t.Fatalf("could not retrieve the revisions: %v", err)
+		}
+		if len(revisions) != 2 {
+			t.Errorf("%d revisions were recorded, expected 2", len(revisions))
+		}
+	})
+}
+
+// testDeleteRevisions checks that the revisions of an element get deleted along with it, whichever way it gets deleted,
+// so that an element created under the same key later on starts with a fresh history
+func testDeleteRevisions(t *testing.T, open Open) {
+	tests := []struct {
+		name   string
+		views  *int
+		delete func(services *shared.Services) error
+	}{
+		{
+			name: "delete",
+			delete: func(services *shared.Services) error {
+				return services.Elements.Delete("ns", "key")
+			},
+		},
+		{
+			name: "delete in namespace",
+			delete: func(services *shared.Services) error {
+				_, err := services.Elements.DeleteInNamespace("ns")
+				return err
+			},
+		},
+		{
+			name:  "delete expired",
+			views: intPointer(0),
+			delete: func(services *shared.Services) error {
+				_, err := services.Elements.DeleteExpired()
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			services := open(t).Services()
+			createNamespace(t, services, "ns")
+			element := createElement(t, services, "ns", "key", test.views)
+			update := *element
+			update.Data = "updated"
+			if updated, err := services.Elements.Update(&update, element.Revision); err != nil || !updated {
+				t.Fatalf("could not update the element (%v)", err)
+			}
+			if revisions, err := services.Elements.Revisions("ns", "key"); err != nil || len(revisions) != 2 {
+				t.Fatalf("recorded %d revisions (%v), expected 2", len(revisions), err)
+			}
+
+			if err := test.delete(services); err != nil {
+				t.Fatalf("could not delete the element: %v", err)
+			}
+			revisions, err := services.Elements.Revisions("ns", "key")
+			if err != nil {
+				t.Fatalf("could not retrieve the revisions: %v", err)
+			}
+			if len(revisions) != 0 {
+				t.Errorf("%d revisions outlived their element, expected none", len(revisions))
+			}
+			revision, err := services.Elements.Revision("ns", "key", 1)
+			if err != nil {
+				t.Fatalf("could not retrieve the revision: %v", err)
+			}
+			if revision != nil {
+				t.Error("the first revision outlived its element")
+			}
+
+			// An element created under the same key starts with a fresh history
+			recreated := createElement(t, services, "ns", "key", nil)
+			if recreated.Revision != 1 {
+				t.Errorf("the recreated element is at revision %d, expected 1", recreated.Revision)
+			}
+			revisions, err = services.Elements.Revisions("ns", "key")
+			if err != nil {
+				t.Fatalf("could not retrieve the revisions: %v", err)
+			}
+			if len(revisions) != 1 || revisions[0].Data != recreated.Data {
+				t.Errorf("the recreated element has %d revisions, expected only its own", len(revisions))
+			}
+		})
+	}
+}
+
+// intPointer returns a pointer to the given integer
+func intPointer(value int) *int {
+	return &value
+}
@@ -0,0 +1,99 @@
+package storetest
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"testing"
+)
+
+// testElementCursor checks that paging through elements lists every element existing from the start exactly once and in order
+// while new elements get created between the pages
+func testElementCursor(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		sort shared.SortOrder
+	}{
+		{name: "by key", sort: shared.SortByKey},
+		{name: "by key descending", sort: shared.SortByKeyDescending},
+		{name: "by creation", sort: shared.SortByCreation},
+		{name: "by creation descending", sort: shared.SortByCreationDescending},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			services := open(t).Services()
+			createNamespace(t, services, "ns")
+			createNamespace(t, services, "other")
+
+			// Create the elements in an order differing from the one of their keys
+			const initial, insertingPages = 25, 5
+			existing := make(map[string]bool, initial)
+			for i := 0; i < initial; i++ {
+				key := fmt.Sprintf("k%02d", i*7%initial)
+				createElement(t, services, "ns", key, nil)
+				createElement(t, services, "other", key, nil)
+				existing[key] = true
+			}
+
+			seen := make(map[string]int)
+			var previous *shared.Element
+			query := &shared.ElementQuery{Namespace: "ns", Sort: test.sort, Limit: 4}
+			for page := 0; ; page++ {
+				if page > initial+2*insertingPages {
+					t.Fatal("the listing does not end")
+				}
+				elements, err := services.Elements.Elements(query)
+				if err != nil {
+					t.Fatalf("could not list the elements: %v", err)
+				}
+				if len(elements) == 0 {
+					break
+				}
+				for _, element := range elements {
+					if element.Namespace != "ns" {
+						t.Errorf("listed element %q of namespace %q", element.Key, element.Namespace)
+					}
+					if previous != nil && !listedBefore(previous, element, test.sort) {
+						t.Errorf("listed element %q after %q", element.Key, previous.Key)
+					}
+					seen[element.Key]++
+					previous = element
+				}
+				query.After = previous
+
+				// Create elements sorting in front of, between and behind the already listed ones during the first pages;
+				// ascending by creation they are always listed later on, so creating them on every page would never end the listing
+				if page < insertingPages {
+					for _, suffix := range []string{"a", "z"} {
+						createElement(t, services, "ns", fmt.Sprintf("k%02d%s", page, suffix), nil)
+					}
+				}
+			}
+
+			for key, count := range seen {
+				if count > 1 {
+					t.Errorf("listed element %q %d times", key, count)
+				}
+			}
+			for key := range existing {
+				if seen[key] == 0 {
+					t.Errorf("did not list element %q", key)
+				}
+			}
+		})
+	}
+}
+
+// listedBefore checks whether an element is listed in front of another one using the given sort order
+func listedBefore(first, second *shared.Element, order shared.SortOrder) bool {
+	switch order {
+	case shared.SortByKeyDescending:
+		return first.Key > second.Key
+	case shared.SortByCreation:
+		return first.CreatedAt.Before(second.CreatedAt) || (first.CreatedAt.Equal(second.CreatedAt) && first.Key < second.Key)
+	case shared.SortByCreationDescending:
+		return first.CreatedAt.After(second.CreatedAt) || (first.CreatedAt.Equal(second.CreatedAt) && first.Key > second.Key)
+	default:
+		return first.Key < second.Key
+	}
+}
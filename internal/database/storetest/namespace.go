@@ -0,0 +1,49 @@
+package storetest
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// testCreateNamespace checks that concurrent creations of the same namespace let exactly one of them win without overwriting it
+func testCreateNamespace(t *testing.T, open Open) {
+	services := open(t).Services()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var winners []string
+	start := make(chan struct{})
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			token := "token " + strconv.Itoa(i)
+			created, err := services.Namespaces.Create(&shared.Namespace{ID: "ns", Token: token, Active: true})
+			if err != nil {
+				t.Errorf("could not create the namespace: %v", err)
+				return
+			}
+			if created {
+				mu.Lock()
+				winners = append(winners, token)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("%d concurrent creations of the same namespace succeeded, expected 1", len(winners))
+	}
+	namespace, err := services.Namespaces.Namespace("ns")
+	if err != nil {
+		t.Fatalf("could not retrieve the namespace: %v", err)
+	}
+	if namespace.Token != winners[0] {
+		t.Errorf("the namespace holds the token %q, expected the one of its creator %q", namespace.Token, winners[0])
+	}
+}
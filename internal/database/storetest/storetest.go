@@ -0,0 +1,58 @@
+// Package storetest contains the conformance tests every storage backend has to pass; the backends run them from their own tests
+package storetest
+
+import (
+	"github.com/x0tf/server/internal/shared"
+	"testing"
+)
+
+// Store represents the storage backend under test
+type Store interface {
+	Services() *shared.Services
+}
+
+// Open represents a function opening a new and empty storage backend for a single test
+type Open func(t *testing.T) Store
+
+// Run runs every conformance test against storage backends opened by the given function
+func Run(t *testing.T, open Open) {
+	t.Run("CreateNamespace", func(t *testing.T) {
+		testCreateNamespace(t, open)
+	})
+	t.Run("ConsumeView", func(t *testing.T) {
+		testConsumeView(t, open)
+	})
+	t.Run("Update", func(t *testing.T) {
+		testUpdate(t, open)
+	})
+	t.Run("DeleteRevisions", func(t *testing.T) {
+		testDeleteRevisions(t, open)
+	})
+	t.Run("ElementCursor", func(t *testing.T) {
+		testElementCursor(t, open)
+	})
+}
+
+// createNamespace creates an active namespace with the given ID
+func createNamespace(t *testing.T, services *shared.Services, id string) {
+	t.Helper()
+	if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: id, Token: "token", Active: true}); err != nil {
+		t.Fatalf("could not create namespace %q: %v", id, err)
+	}
+}
+
+// createElement creates a paste element with the given key
+func createElement(t *testing.T, services *shared.Services, namespace, key string, remainingViews *int) *shared.Element {
+	t.Helper()
+	element := &shared.Element{
+		Namespace:      namespace,
+		Key:            key,
+		Type:           shared.ElementTypePaste,
+		Data:           "data of " + key,
+		RemainingViews: remainingViews,
+	}
+	if err := services.Elements.CreateOrReplace(element); err != nil {
+		t.Fatalf("could not create element %q: %v", key, err)
+	}
+	return element
+}
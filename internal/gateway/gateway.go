@@ -2,46 +2,98 @@ package gateway
 
 import (
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	recov "github.com/gofiber/fiber/v2/middleware/recover"
 	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/access"
+	"github.com/x0tf/server/internal/analytics"
+	"github.com/x0tf/server/internal/health"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/metrics"
 	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"html/template"
+	"time"
 )
 
 // Gateway represents the element-exposing gateway
 type Gateway struct {
-	app          *fiber.App
-	Address      string
-	Production   bool
-	Namespaces   shared.NamespaceService
-	Elements     shared.ElementService
-	RootRedirect string
+	app              *fiber.App
+	Address          string
+	Production       bool
+	Namespaces       shared.NamespaceService
+	Elements         shared.ElementService
+	Blobs            shared.BlobStore
+	Accesses         *access.Tracker
+	Hits             *analytics.Recorder
+	Notifier         *webhook.Notifier
+	MarkdownTemplate *template.Template
+	RootRedirect     string
+	Health           *health.Checker
+	TrustedProxies   logging.TrustedProxies
 }
 
 // Serve serves the gateway
 func (gateway *Gateway) Serve() error {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: gateway.Production,
+		Immutable:             true,
+		ErrorHandler:          errorHandler,
 	})
 
+	// Log and observe every handled request
+	app.Use(logging.Middleware("gateway", gateway.TrustedProxies))
+	app.Use(metrics.Middleware("gateway"))
+
 	// Enable panic recovering
 	app.Use(recov.New())
 
 	// Inject debug middlewares if the application runs in development mode
 	if !gateway.Production {
-		app.Use(logger.New())
 		app.Use(pprof.New())
 	}
 
+	// Allow five failed password attempts per element within 15 minutes and four concurrent password verifications
+	passwordAttempts := newAttemptLimiter(5, 15*time.Minute, 4)
+
 	// Inject the application data
 	app.Use(func(ctx *fiber.Ctx) error {
 		ctx.Locals("__namespaces", gateway.Namespaces)
 		ctx.Locals("__elements", gateway.Elements)
+		ctx.Locals("__password_attempts", passwordAttempts)
+		if gateway.Blobs != nil {
+			ctx.Locals("__blobs", gateway.Blobs)
+		}
+		if gateway.Accesses != nil {
+			ctx.Locals("__accesses", gateway.Accesses)
+		}
+		if gateway.Hits != nil {
+			ctx.Locals("__hits", gateway.Hits)
+		}
+		if gateway.Notifier != nil {
+			ctx.Locals("__notifier", gateway.Notifier)
+		}
+		if gateway.MarkdownTemplate != nil {
+			ctx.Locals("__markdown_template", gateway.MarkdownTemplate)
+		}
 		return ctx.Next()
 	})
 
+	// Register the health endpoints in front of the element routes; their paths are reserved namespace IDs
+	if gateway.Health != nil {
+		app.Get("/healthz", health.Liveness)
+		app.Get("/readyz", gateway.Health.Readiness)
+	}
+
+	// Accept POST requests as well to receive submitted password forms
+	rawHandler := func(ctx *fiber.Ctx) error {
+		ctx.Locals("_raw", true)
+		return baseHandler(ctx)
+	}
+	app.Get("/:namespace/:key/raw", rawHandler)
+	app.Post("/:namespace/:key/raw", rawHandler)
 	app.Get("/:namespace/:key?", baseHandler)
+	app.Post("/:namespace/:key?", baseHandler)
 
 	// Define the root redirect
 	if gateway.RootRedirect != "" {
@@ -60,3 +112,15 @@ func (gateway *Gateway) Shutdown() error {
 	log.Info("Shutting down the gateway")
 	return gateway.app.Shutdown()
 }
+
+// errorHandler logs internal errors before delegating them to the default error handler
+func errorHandler(ctx *fiber.Ctx, err error) error {
+	code := fiber.StatusInternalServerError
+	if fiberError, ok := err.(*fiber.Error); ok {
+		code = fiberError.Code
+	}
+	if code >= fiber.StatusInternalServerError {
+		logging.WithRequest(ctx).WithError(err).Error("Could not handle a request")
+	}
+	return fiber.DefaultErrorHandler(ctx, err)
+}
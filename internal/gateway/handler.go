@@ -2,7 +2,20 @@ package gateway
 
 import (
 	"github.com/gofiber/fiber/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/access"
+	"github.com/x0tf/server/internal/analytics"
+	"github.com/x0tf/server/internal/blob"
+	"github.com/x0tf/server/internal/logging"
+	"github.com/x0tf/server/internal/metrics"
+	"github.com/x0tf/server/internal/render"
 	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"html/template"
+	"io"
+	"mime"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -25,13 +38,91 @@ func baseHandler(ctx *fiber.Ctx) error {
 	if elementKey == "" {
 		elementKey = "@"
 	}
+
+	// Split off the number of a requested revision ('key@3') unless an element was created with that literal key before it got reserved
 	element, err := elements.Element(namespace.ID, elementKey)
 	if err != nil {
 		return err
 	}
+	revisionNumber := 0
+	if index := strings.LastIndexByte(elementKey, '@'); element == nil && index > 0 {
+		if number, err := strconv.Atoi(elementKey[index+1:]); err == nil && number > 0 {
+			elementKey, revisionNumber = elementKey[:index], number
+			if element, err = elements.Element(namespace.ID, elementKey); err != nil {
+				return err
+			}
+		}
+	}
 	if element == nil {
 		return fiber.NewError(fiber.StatusNotFound, "the requested element does not exist")
 	}
+	if element.Expired() {
+		return fiber.NewError(fiber.StatusGone, "the requested element has expired")
+	}
+
+	// Require the password of protected elements before resolving them
+	if element.Protected() {
+		if ok, err := authorize(ctx, element); !ok || err != nil {
+			return err
+		}
+	}
+
+	// Resolve a requested revision instead of the current content; view-limited elements only expose their current content
+	if revisionNumber > 0 {
+		if element.RemainingViews != nil {
+			return fiber.NewError(fiber.StatusNotFound, "the revisions of view-limited elements are not accessible")
+		}
+		revision, err := elements.Revision(namespace.ID, elementKey, revisionNumber)
+		if err != nil {
+			return err
+		}
+		if revision == nil {
+			return fiber.NewError(fiber.StatusNotFound, "the requested revision does not exist")
+		}
+		resolved := *element
+		revision.ApplyTo(&resolved)
+		resolved.Key = element.Key + "@" + strconv.Itoa(revisionNumber)
+		element = &resolved
+	}
+
+	// Consume a view if the element may only be viewed a limited amount of times
+	if element.RemainingViews != nil {
+		element, err = elements.ConsumeView(namespace.ID, elementKey)
+		if err != nil {
+			return err
+		}
+		if element == nil {
+			return fiber.NewError(fiber.StatusGone, "the requested element has no views left")
+		}
+
+		// Burn the element after its last view; the reaper purges it later on if this fails
+		if element.Exhausted() {
+			if err := elements.Delete(namespace.ID, element.Key); err != nil {
+				log.WithError(err).WithFields(log.Fields{
+					"namespace": namespace.ID,
+					"key":       element.Key,
+				}).Error("Could not delete an element after its last view")
+			} else {
+				notifyExpiry(ctx, element)
+				if element.Type == shared.ElementTypeFile {
+					ctx.Locals("_burned", true)
+				} else {
+					// The payload of other element types was already loaded, so an offloaded one may be released right away
+					blobs, _ := ctx.Locals("__blobs").(shared.BlobStore)
+					blob.Release(blobs, element)
+				}
+			}
+		}
+	}
+
+	// Note the access of the resolved element and hand the hit over to the analytics and metrics
+	if accesses, ok := ctx.Locals("__accesses").(*access.Tracker); ok {
+		accesses.Record(namespace.ID, elementKey)
+	}
+	if hits, ok := ctx.Locals("__hits").(*analytics.Recorder); ok {
+		hits.Record(namespace.ID, elementKey, logging.ClientIP(ctx), ctx.Get(fiber.HeaderReferer), ctx.Get(fiber.HeaderUserAgent))
+	}
+	metrics.CountResolution(element.Type.String())
 
 	// Inject the element and delegate the request
 	ctx.Locals("_element", element)
@@ -40,17 +131,185 @@ func baseHandler(ctx *fiber.Ctx) error {
 		return pasteHandler(ctx)
 	case shared.ElementTypeRedirect:
 		return redirectHandler(ctx)
+	case shared.ElementTypeFile:
+		return fileHandler(ctx)
+	case shared.ElementTypeMarkdown:
+		return markdownHandler(ctx)
 	default:
 		return fiber.NewError(fiber.StatusNotImplemented, "unimplemented element type")
 	}
 }
 
+// notifyExpiry enqueues the deliveries of the expiry of a burned element to the webhooks of its namespace
+func notifyExpiry(ctx *fiber.Ctx, element *shared.Element) {
+	notifier, _ := ctx.Locals("__notifier").(*webhook.Notifier)
+	if err := notifier.Notify(element.Namespace, shared.WebhookElementExpired, webhook.ElementData(element)); err != nil {
+		logging.WithRequest(ctx).WithError(err).Error("Could not enqueue the deliveries of a webhook event")
+	}
+}
+
 // pasteHandler handles paste elements
 func pasteHandler(ctx *fiber.Ctx) error {
+	element := ctx.Locals("_element").(*shared.Element)
+	if element.Encryption != nil {
+		return encryptedPasteHandler(ctx)
+	}
+	if servesRaw(ctx) {
+		return rawHandler(ctx)
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	ctx.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'")
+	return render.RenderPaste(ctx, &render.Paste{
+		Title:    element.Namespace + "/" + element.Key,
+		Language: element.Language,
+		Content:  element.Data,
+		RawURL:   rawURL(element),
+	})
+}
+
+// encryptedPasteHandler handles client-side encrypted paste elements; the server is unable to decrypt them,
+// so it serves the envelope as JSON to non-browser clients and a page decrypting it in the browser otherwise
+func encryptedPasteHandler(ctx *fiber.Ctx) error {
+	element := ctx.Locals("_element").(*shared.Element)
+	envelope := &render.EncryptedEnvelope{
+		Encryption: element.Encryption,
+		Ciphertext: element.Data,
+	}
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	if servesRaw(ctx) {
+		return ctx.JSON(envelope)
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	ctx.Set(fiber.HeaderContentSecurityPolicy, render.EncryptedPasteContentSecurityPolicy)
+	ctx.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	return render.RenderEncryptedPaste(ctx, &render.EncryptedPaste{
+		Title:    element.Namespace + "/" + element.Key,
+		Envelope: envelope,
+	})
+}
+
+// markdownHandler handles markdown elements
+func markdownHandler(ctx *fiber.Ctx) error {
+	element := ctx.Locals("_element").(*shared.Element)
+	if servesRaw(ctx) {
+		return rawHandler(ctx)
+	}
+
+	tmpl, _ := ctx.Locals("__markdown_template").(*template.Template)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	ctx.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; img-src * data:")
+	return render.RenderMarkdown(ctx, tmpl, &render.Markdown{
+		Title:  element.Namespace + "/" + element.Key,
+		Source: element.Data,
+		RawURL: rawURL(element),
+	})
+}
+
+// rawHandler serves the plain content of a textual element
+func rawHandler(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
 	return ctx.SendString(ctx.Locals("_element").(*shared.Element).Data)
 }
 
+// servesRaw checks whether the plain content of a textual element should be served instead of an HTML page,
+// which is the case for non-browser clients and if it was requested explicitly using the /raw suffix
+func servesRaw(ctx *fiber.Ctx) bool {
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	ctx.Set(fiber.HeaderVary, fiber.HeaderAccept)
+	raw, _ := ctx.Locals("_raw").(bool)
+	return raw || ctx.Accepts(fiber.MIMETextPlain, fiber.MIMETextHTML) != fiber.MIMETextHTML
+}
+
+// rawURL builds the URL of the plain content of a textual element; it is empty for view-limited elements as following it would consume another view
+// and for password-protected ones as browsers would have to supply the password again
+func rawURL(element *shared.Element) string {
+	if element.RemainingViews != nil || element.Protected() {
+		return ""
+	}
+	return "/" + url.PathEscape(element.Namespace) + "/" + url.PathEscape(element.Key) + "/raw"
+}
+
 // redirectHandler handles paste elements
 func redirectHandler(ctx *fiber.Ctx) error {
-	return ctx.Redirect(ctx.Locals("_element").(*shared.Element).Data, fiber.StatusTemporaryRedirect)
+	// Let browsers follow the redirect using GET after submitting a password form
+	status := fiber.StatusTemporaryRedirect
+	if ctx.Method() == fiber.MethodPost {
+		status = fiber.StatusSeeOther
+	}
+	return ctx.Redirect(ctx.Locals("_element").(*shared.Element).Data, status)
+}
+
+// fileHandler handles file elements
+func fileHandler(ctx *fiber.Ctx) error {
+	element := ctx.Locals("_element").(*shared.Element)
+	blobs, _ := ctx.Locals("__blobs").(shared.BlobStore)
+	if blobs == nil {
+		return fiber.NewError(fiber.StatusNotImplemented, "this gateway does not serve files")
+	}
+
+	// Open the file content
+	reader, err := blobs.Get(element.Blob)
+	if err != nil {
+		return err
+	}
+	if reader == nil {
+		return fiber.NewError(fiber.StatusNotFound, "the content of the requested element does not exist")
+	}
+
+	// Release the blob of a burned element once its content was sent
+	if burned, _ := ctx.Locals("_burned").(bool); burned {
+		reader = &releasingReader{
+			ReadCloser: reader,
+			release: func() {
+				blob.Release(blobs, element)
+			},
+		}
+	}
+
+	ctx.Set(fiber.HeaderContentType, element.ContentType)
+	ctx.Set(fiber.HeaderContentDisposition, contentDisposition(element))
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	ctx.Response().SetBodyStream(reader, int(element.Size))
+	return nil
+}
+
+// inlineContentTypes contains the content types browsers may display inline without being able to execute scripts
+var inlineContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/gif":       true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+// contentDisposition builds the Content-Disposition header value for a file element
+func contentDisposition(element *shared.Element) string {
+	disposition := "attachment"
+	mediaType, _, _ := mime.ParseMediaType(element.ContentType)
+	if inlineContentTypes[mediaType] || strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/") {
+		disposition = "inline"
+	}
+	if element.Filename == "" {
+		return disposition
+	}
+	if formatted := mime.FormatMediaType(disposition, map[string]string{"filename": element.Filename}); formatted != "" {
+		return formatted
+	}
+	return disposition
+}
+
+// releasingReader represents a reader calling a release function once it gets closed
+type releasingReader struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the underlying reader and calls the release function
+func (reader *releasingReader) Close() error {
+	err := reader.ReadCloser.Close()
+	reader.release()
+	return err
 }
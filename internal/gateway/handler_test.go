@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestApp creates an app serving the gateway routes out of a fresh in-memory store
+func newTestApp(t *testing.T, attempts *attemptLimiter) (*fiber.App, *shared.Services) {
+	services := memory.NewStore().Services()
+	if err := services.Namespaces.CreateOrReplace(&shared.Namespace{ID: "ns", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts == nil {
+		attempts = newAttemptLimiter(5, 15*time.Minute, 4)
+	}
+
+	app := fiber.New(fiber.Config{
+		Immutable:    true,
+		ErrorHandler: errorHandler,
+	})
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("__namespaces", services.Namespaces)
+		ctx.Locals("__elements", services.Elements)
+		ctx.Locals("__password_attempts", attempts)
+		return ctx.Next()
+	})
+	rawHandler := func(ctx *fiber.Ctx) error {
+		ctx.Locals("_raw", true)
+		return baseHandler(ctx)
+	}
+	app.Get("/:namespace/:key/raw", rawHandler)
+	app.Get("/:namespace/:key?", baseHandler)
+	app.Post("/:namespace/:key?", baseHandler)
+	return app, services
+}
+
+// resolve requests an element through the gateway and returns the response status and body
+func resolve(t *testing.T, app *fiber.App, request *http.Request) (int, string) {
+	t.Helper()
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(body)
+}
+
+func TestResolveStatus(t *testing.T) {
+	past := time.Now().Add(-time.Minute).UTC()
+	future := time.Now().Add(time.Hour).UTC()
+	views := func(value int) *int {
+		return &value
+	}
+
+	tests := []struct {
+		name     string
+		element  *shared.Element
+		update   bool
+		path     string
+		statuses []int
+	}{
+		{name: "missing namespace", path: "/missing/key", statuses: []int{fiber.StatusNotFound}},
+		{name: "missing element", path: "/ns/missing", statuses: []int{fiber.StatusNotFound}},
+		{
+			name:     "unexpired element",
+			element:  &shared.Element{Key: "key", Data: "data", ExpiresAt: &future},
+			path:     "/ns/key",
+			statuses: []int{fiber.StatusOK, fiber.StatusOK},
+		},
+		{
+			name:     "expired element",
+			element:  &shared.Element{Key: "key", Data: "data", ExpiresAt: &past},
+			path:     "/ns/key",
+			statuses: []int{fiber.StatusGone},
+		},
+		{
+			name:     "burn after read",
+			element:  &shared.Element{Key: "key", Data: "data", RemainingViews: views(1)},
+			path:     "/ns/key",
+			statuses: []int{fiber.StatusOK, fiber.StatusNotFound},
+		},
+		{
+			name:     "view-limited element",
+			element:  &shared.Element{Key: "key", Data: "data", RemainingViews: views(2)},
+			path:     "/ns/key",
+			statuses: []int{fiber.StatusOK, fiber.StatusOK, fiber.StatusNotFound},
+		},
+		{
+			name:     "no views left",
+			element:  &shared.Element{Key: "key", Data: "data", RemainingViews: views(0)},
+			path:     "/ns/key",
+			statuses: []int{fiber.StatusGone},
+		},
+		{
+			name:     "existing revision",
+			element:  &shared.Element{Key: "key", Data: "data"},
+			update:   true,
+			path:     "/ns/key@1",
+			statuses: []int{fiber.StatusOK},
+		},
+		{
+			name:     "missing revision",
+			element:  &shared.Element{Key: "key", Data: "data"},
+			update:   true,
+			path:     "/ns/key@3",
+			statuses: []int{fiber.StatusNotFound},
+		},
+		{
+			name:     "revision of a view-limited element",
+			element:  &shared.Element{Key: "key", Data: "data", RemainingViews: views(5)},
+			update:   true,
+			path:     "/ns/key@1",
+			statuses: []int{fiber.StatusNotFound},
+		},
+		{
+			name:     "revision of an expired element",
+			element:  &shared.Element{Key: "key", Data: "data", ExpiresAt: &past},
+			update:   true,
+			path:     "/ns/key@1",
+			statuses: []int{fiber.StatusGone},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, services := newTestApp(t, nil)
+			if test.element != nil {
+				test.element.Namespace = "ns"
+				test.element.Type = shared.ElementTypePaste
+				if err := services.Elements.CreateOrReplace(test.element); err != nil {
+					t.Fatal(err)
+				}
+				if test.update {
+					update := *test.element
+					update.Data = "updated"
+					if updated, err := services.Elements.Update(&update, test.element.Revision); err != nil || !updated {
+						t.Fatalf("could not update the element (%v)", err)
+					}
+				}
+			}
+
+			for i, expected := range test.statuses {
+				status, body := resolve(t, app, httptest.NewRequest(fiber.MethodGet, test.path, nil))
+				if status != expected {
+					t.Errorf("responded to request %d with status %d (%s), expected %d", i+1, status, body, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownSanitization(t *testing.T) {
+	app, services := newTestApp(t, nil)
+	source := "# Title\n\n" +
+		"<script>alert('script')</script>\n\n" +
+		"<img src=\"x\" onerror=\"alert('image')\">\n\n" +
+		"[link](javascript:alert('link')) and [safe](https://example.com)\n\n" +
+		"![image](javascript:alert('source'))\n"
+	element := &shared.Element{Namespace: "ns", Key: "key", Type: shared.ElementTypeMarkdown, Data: source}
+	if err := services.Elements.CreateOrReplace(element); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(fiber.MethodGet, "/ns/key", nil)
+	request.Header.Set(fiber.HeaderAccept, fiber.MIMETextHTML)
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != fiber.StatusOK {
+		t.Fatalf("responded with status %d, expected %d", response.StatusCode, fiber.StatusOK)
+	}
+	if csp := response.Header.Get(fiber.HeaderContentSecurityPolicy); !strings.HasPrefix(csp, "default-src 'none'") {
+		t.Errorf("responded with the content security policy %q, expected scripts to be forbidden", csp)
+	}
+
+	page := string(body)
+	for _, unsafe := range []string{"<script", "onerror", "javascript:", "alert("} {
+		if strings.Contains(page, unsafe) {
+			t.Errorf("the rendered page contains %q:\n%s", unsafe, page)
+		}
+	}
+	for _, safe := range []string{"<h1 id=\"title\">Title</h1>", "<a href=\"https://example.com\">safe</a>"} {
+		if !strings.Contains(page, safe) {
+			t.Errorf("the rendered page does not contain %q:\n%s", safe, page)
+		}
+	}
+
+	// The raw content stays untouched
+	if status, raw := resolve(t, app, httptest.NewRequest(fiber.MethodGet, "/ns/key/raw", nil)); status != fiber.StatusOK || raw != source {
+		t.Errorf("served the raw content %q with status %d, expected the source", raw, status)
+	}
+}
@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/metrics"
+	"github.com/x0tf/server/internal/render"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headerElementPassword represents the header CLI clients may supply the password of a protected element in
+const headerElementPassword = "X-Element-Password"
+
+// authorize checks the password supplied for a protected element; it returns false if the response was already written
+func authorize(ctx *fiber.Ctx, element *shared.Element) (bool, error) {
+	attempts := ctx.Locals("__password_attempts").(*attemptLimiter)
+	id := element.Namespace + "/" + element.Key
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	// Ask for the password unless too many failed attempts were made recently
+	password, ok := suppliedPassword(ctx)
+	if !ok {
+		if retryAfter := attempts.blocked(id); retryAfter > 0 {
+			return false, rejectAttempt(ctx, retryAfter, "too many failed password attempts for this element")
+		}
+		return false, challenge(ctx, element, false)
+	}
+
+	// Bound the memory spent on concurrent password verifications
+	if !attempts.acquire() {
+		return false, rejectAttempt(ctx, time.Second, "too many password verifications in progress")
+	}
+	defer attempts.release()
+
+	// Count the attempt before verifying it so concurrent guesses cannot exceed the limit; a successful one resets the count
+	if retryAfter := attempts.reserve(id); retryAfter > 0 {
+		return false, rejectAttempt(ctx, retryAfter, "too many failed password attempts for this element")
+	}
+	valid, err := token.Check(element.PasswordHash, password)
+	if err != nil {
+		return false, err
+	}
+	if !valid {
+		return false, challenge(ctx, element, true)
+	}
+	attempts.reset(id)
+	return true, nil
+}
+
+// rejectAttempt rejects a password attempt and tells the client when to try again
+func rejectAttempt(ctx *fiber.Ctx, retryAfter time.Duration, message string) error {
+	metrics.CountLimiterRejection("password")
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+	return fiber.NewError(fiber.StatusTooManyRequests, message)
+}
+
+// suppliedPassword reads the password out of the dedicated header, HTTP Basic credentials or a submitted password form
+func suppliedPassword(ctx *fiber.Ctx) (string, bool) {
+	if password := ctx.Get(headerElementPassword); password != "" {
+		return password, true
+	}
+	if header := ctx.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Basic ") {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic ")); err == nil {
+			if index := strings.IndexByte(string(decoded), ':'); index >= 0 && index < len(decoded)-1 {
+				return string(decoded[index+1:]), true
+			}
+		}
+	}
+	if ctx.Method() == fiber.MethodPost {
+		if password := ctx.FormValue("password"); password != "" {
+			return password, true
+		}
+	}
+	return "", false
+}
+
+// challenge asks the client for the password of a protected element using a form for browsers and HTTP Basic authentication otherwise
+func challenge(ctx *fiber.Ctx, element *shared.Element, failed bool) error {
+	ctx.Set(fiber.HeaderVary, fiber.HeaderAccept)
+	if ctx.Accepts(fiber.MIMETextPlain, fiber.MIMETextHTML) == fiber.MIMETextHTML {
+		ctx.Status(fiber.StatusUnauthorized)
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		ctx.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+		return render.RenderPasswordForm(ctx, &render.PasswordForm{
+			Title:  element.Namespace + "/" + element.Key,
+			Failed: failed,
+		})
+	}
+
+	ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="x0", charset="UTF-8"`)
+	if failed {
+		return fiber.NewError(fiber.StatusUnauthorized, "the supplied password is wrong")
+	}
+	return fiber.NewError(fiber.StatusUnauthorized, "the requested element is password-protected (supply the password using HTTP Basic authentication or the "+headerElementPassword+" header)")
+}
+
+// attemptLimiter represents a limiter blocking further password attempts for an element once too many of them failed within a time window
+// and bounding the amount of concurrent password verifications
+type attemptLimiter struct {
+	mu            sync.Mutex
+	max           int
+	window        time.Duration
+	attempts      map[string]*failedAttempts
+	verifications chan struct{}
+}
+
+// failedAttempts represents the unsuccessful password attempts for an element within the current time window
+type failedAttempts struct {
+	count int
+	start time.Time
+}
+
+// newAttemptLimiter creates a new attempt limiter allowing max failed attempts per element within the given time window
+// and the given amount of concurrent password verifications
+func newAttemptLimiter(max int, window time.Duration, concurrency int) *attemptLimiter {
+	return &attemptLimiter{
+		max:           max,
+		window:        window,
+		attempts:      make(map[string]*failedAttempts),
+		verifications: make(chan struct{}, concurrency),
+	}
+}
+
+// acquire reserves a slot for a password verification and reports whether one was free
+func (limiter *attemptLimiter) acquire() bool {
+	select {
+	case limiter.verifications <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the slot of a finished password verification
+func (limiter *attemptLimiter) release() {
+	<-limiter.verifications
+}
+
+// blocked returns the duration until attempts for the given element are allowed again or zero if they are allowed right now
+func (limiter *attemptLimiter) blocked(id string) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	attempts := limiter.attempts[id]
+	if attempts == nil || attempts.count < limiter.max {
+		return 0
+	}
+	remaining := time.Until(attempts.start.Add(limiter.window))
+	if remaining <= 0 {
+		delete(limiter.attempts, id)
+		return 0
+	}
+	return remaining
+}
+
+// reserve counts an attempt for the given element unless too many were made within the current time window;
+// it returns the duration until attempts are allowed again or zero if this one was counted
+func (limiter *attemptLimiter) reserve(id string) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	now := time.Now()
+
+	// Forget the attempts of windows which already ended so the map does not grow indefinitely
+	for key, attempts := range limiter.attempts {
+		if now.Sub(attempts.start) >= limiter.window {
+			delete(limiter.attempts, key)
+		}
+	}
+
+	attempts := limiter.attempts[id]
+	if attempts == nil {
+		attempts = &failedAttempts{start: now}
+		limiter.attempts[id] = attempts
+	}
+	if attempts.count >= limiter.max {
+		return attempts.start.Add(limiter.window).Sub(now)
+	}
+	attempts.count++
+	return 0
+}
+
+// reset forgets the failed attempts for the given element after a successful one
+func (limiter *attemptLimiter) reset(id string) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	delete(limiter.attempts, id)
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/token"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createProtectedElement creates a paste protected by the given password
+func createProtectedElement(t *testing.T, services *shared.Services, key, password string) {
+	hash, err := token.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	element := &shared.Element{Namespace: "ns", Key: key, Type: shared.ElementTypePaste, Data: "secret data", PasswordHash: hash}
+	if err := services.Elements.CreateOrReplace(element); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// passwordRequest creates a request supplying the given password for an element
+func passwordRequest(key, password string) *http.Request {
+	request := httptest.NewRequest(fiber.MethodGet, "/ns/"+key, nil)
+	if password != "" {
+		request.Header.Set(headerElementPassword, password)
+	}
+	return request
+}
+
+func TestPasswordAttemptLimiting(t *testing.T) {
+	app, services := newTestApp(t, newAttemptLimiter(3, time.Minute, 4))
+	createProtectedElement(t, services, "key", "correct")
+	createProtectedElement(t, services, "other", "correct")
+
+	tests := []struct {
+		name     string
+		key      string
+		password string
+		status   int
+	}{
+		{name: "no password", key: "key", status: fiber.StatusUnauthorized},
+		{name: "first wrong password", key: "key", password: "wrong", status: fiber.StatusUnauthorized},
+		{name: "second wrong password", key: "key", password: "wrong", status: fiber.StatusUnauthorized},
+		{name: "third wrong password", key: "key", password: "wrong", status: fiber.StatusUnauthorized},
+		{name: "fourth wrong password", key: "key", password: "wrong", status: fiber.StatusTooManyRequests},
+		{name: "correct password while blocked", key: "key", password: "correct", status: fiber.StatusTooManyRequests},
+		{name: "no password while blocked", key: "key", status: fiber.StatusTooManyRequests},
+		{name: "other element", key: "other", password: "correct", status: fiber.StatusOK},
+	}
+	for _, test := range tests {
+		response, err := app.Test(passwordRequest(test.key, test.password), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status {
+			t.Errorf("%s: responded with status %d, expected %d", test.name, response.StatusCode, test.status)
+		}
+		if test.status == fiber.StatusTooManyRequests && response.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Errorf("%s: did not tell the client when to try again", test.name)
+		}
+	}
+}
+
+func TestPasswordAttemptReset(t *testing.T) {
+	app, services := newTestApp(t, newAttemptLimiter(3, time.Minute, 4))
+	createProtectedElement(t, services, "key", "correct")
+
+	// A successful attempt forgets the failed ones before it
+	for _, password := range []string{"wrong", "wrong", "correct", "wrong", "wrong", "correct"} {
+		expected := fiber.StatusUnauthorized
+		if password == "correct" {
+			expected = fiber.StatusOK
+		}
+		if status, _ := resolve(t, app, passwordRequest("key", password)); status != expected {
+			t.Errorf("responded to the password %q with status %d, expected %d", password, status, expected)
+		}
+	}
+}
+
+func TestConcurrentPasswordAttempts(t *testing.T) {
+	const maxAttempts = 3
+	app, services := newTestApp(t, newAttemptLimiter(maxAttempts, time.Minute, 16))
+	createProtectedElement(t, services, "key", "correct")
+
+	// Concurrent guesses must not be able to exceed the amount of allowed attempts
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[int]int)
+	start := make(chan struct{})
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			response, err := app.Test(passwordRequest("key", "wrong"), -1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[response.StatusCode]++
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if statuses[fiber.StatusUnauthorized] != maxAttempts {
+		t.Errorf("verified %d concurrent guesses, expected %d (statuses: %v)", statuses[fiber.StatusUnauthorized], maxAttempts, statuses)
+	}
+	if statuses[fiber.StatusUnauthorized]+statuses[fiber.StatusTooManyRequests] != 12 {
+		t.Errorf("responded with unexpected statuses: %v", statuses)
+	}
+}
+
+func TestPasswordVerificationConcurrency(t *testing.T) {
+	attempts := newAttemptLimiter(3, time.Minute, 1)
+	app, services := newTestApp(t, attempts)
+	createProtectedElement(t, services, "key", "correct")
+
+	// Further verifications get rejected while every slot is occupied, without counting as a failed attempt
+	if !attempts.acquire() {
+		t.Fatal("could not occupy the verification slot")
+	}
+	if status, _ := resolve(t, app, passwordRequest("key", "correct")); status != fiber.StatusTooManyRequests {
+		t.Errorf("responded with status %d while every verification slot was occupied, expected %d", status, fiber.StatusTooManyRequests)
+	}
+	attempts.release()
+	if status, _ := resolve(t, app, passwordRequest("key", "correct")); status != fiber.StatusOK {
+		t.Errorf("responded with status %d after the verification slot was released, expected %d", status, fiber.StatusOK)
+	}
+}
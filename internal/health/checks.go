@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/x0tf/server/internal/database/migration"
+)
+
+// Pinger represents a dependency which can be pinged
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck creates a check pinging the given dependency
+func PingCheck(name string, pinger Pinger) *Check {
+	return &Check{
+		Name: name,
+		Run: func(ctx context.Context) (interface{}, error) {
+			return nil, pinger.Ping(ctx)
+		},
+	}
+}
+
+// SchemaCheck creates a check comparing the applied database schema version with the one this application version requires
+func SchemaCheck(driver migration.Driver) *Check {
+	return &Check{
+		Name: "schema",
+		Run: func(_ context.Context) (interface{}, error) {
+			latest := migration.Latest(driver)
+			current, err := migration.Check(driver)
+			details := map[string]int{
+				"current":  current,
+				"expected": latest,
+			}
+			if err != nil {
+				return details, err
+			}
+			if current < latest {
+				return details, fmt.Errorf("%d schema migrations are pending", latest-current)
+			}
+			return details, nil
+		},
+	}
+}
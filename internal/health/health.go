@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// statusOK represents the status of a passed check
+	statusOK = "ok"
+
+	// statusFailing represents the status of a failed check
+	statusFailing = "failing"
+
+	// checkTimeout represents the time a single readiness check may take before it is considered failed
+	checkTimeout = 5 * time.Second
+)
+
+// Check represents a single readiness check
+type Check struct {
+	// Name identifies the check in the readiness report
+	Name string
+
+	// Run executes the check and returns optional details about the checked dependency
+	Run func(ctx context.Context) (interface{}, error)
+}
+
+// Result represents the outcome of a single readiness check
+type Result struct {
+	Status   string      `json:"status"`
+	Duration string      `json:"duration"`
+	Details  interface{} `json:"details,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Checker represents the readiness checker shared by the REST API and the gateway
+type Checker struct {
+	draining int32
+	Checks   []*Check
+}
+
+// Drain marks the application as draining so that it reports not to be ready anymore while it shuts down
+func (checker *Checker) Drain() {
+	atomic.StoreInt32(&checker.draining, 1)
+}
+
+// Draining checks whether the application is draining
+func (checker *Checker) Draining() bool {
+	return atomic.LoadInt32(&checker.draining) == 1
+}
+
+// Run concurrently executes every check and reports whether all of them passed
+func (checker *Checker) Run(ctx context.Context) (map[string]*Result, bool) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	results := make(map[string]*Result, len(checker.Checks)+1)
+	draining := &Result{Status: statusOK, Duration: "0s"}
+	if checker.Draining() {
+		draining.Status = statusFailing
+		draining.Error = "the application is shutting down"
+	}
+	results["draining"] = draining
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checker.Checks {
+		wg.Add(1)
+		go func(check *Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Status != statusOK {
+			return results, false
+		}
+	}
+	return results, true
+}
+
+// run executes a single check and gives up once the context is done
+func run(ctx context.Context, check *Check) *Result {
+	type outcome struct {
+		details interface{}
+		err     error
+	}
+
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		details, err := check.Run(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	status := &Result{
+		Status:   statusOK,
+		Duration: time.Since(start).String(),
+		Details:  result.details,
+	}
+	if result.err != nil {
+		status.Status = statusFailing
+		status.Error = result.err.Error()
+	}
+	return status
+}
+
+// Liveness handles the liveness probe, which only reports that the process is alive
+func Liveness(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"status": statusOK,
+	})
+}
+
+// Readiness handles the readiness probe reporting the result of every check
+func (checker *Checker) Readiness(ctx *fiber.Ctx) error {
+	results, ready := checker.Run(ctx.Context())
+	status, code := statusOK, fiber.StatusOK
+	if !ready {
+		status, code = statusFailing, fiber.StatusServiceUnavailable
+	}
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.Status(code).JSON(fiber.Map{
+		"status": status,
+		"checks": results,
+	})
+}
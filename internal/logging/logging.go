@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"github.com/gofiber/fiber/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/utils"
+	"os"
+	"time"
+)
+
+const (
+	// HeaderRequestID represents the header a request ID gets propagated in
+	HeaderRequestID = "X-Request-ID"
+
+	// maxRequestIDLength represents the maximum length of a propagated request ID
+	maxRequestIDLength = 128
+)
+
+// accessLogger represents the logger writing the JSON access logs
+var accessLogger = &log.Logger{
+	Out:       os.Stdout,
+	Formatter: &log.JSONFormatter{},
+	Hooks:     make(log.LevelHooks),
+	Level:     log.InfoLevel,
+}
+
+// Middleware creates a middleware assigning a request ID to and writing an access log entry for every request handled by the given server
+func Middleware(server string, proxies TrustedProxies) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+
+		// Propagate the request ID of the client or generate a new one
+		requestID := ctx.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = utils.GenerateRequestID()
+		}
+		ctx.Set(HeaderRequestID, requestID)
+		ctx.Locals("_request_id", requestID)
+		ctx.Locals("_client_ip", proxies.resolve(ctx.Context().RemoteIP(), ctx.Get(fiber.HeaderXForwardedFor)))
+
+		err := ctx.Next()
+
+		// Determine the status code the error handler is going to respond with
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fiberError, ok := err.(*fiber.Error); ok {
+				status = fiberError.Code
+			}
+		}
+
+		// Requests which did not match any route only passed the global middlewares
+		route := ctx.Route().Path
+		if route == "/" && ctx.Path() != "/" {
+			route = ""
+		}
+
+		fields := log.Fields{
+			"server":     server,
+			"request_id": requestID,
+			"method":     ctx.Method(),
+			"path":       ctx.Path(),
+			"route":      route,
+			"status":     status,
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip":  ClientIP(ctx),
+			"user_agent": ctx.Get(fiber.HeaderUserAgent),
+		}
+		if namespace := ctx.Params("namespace"); namespace != "" {
+			fields["namespace"] = namespace
+		}
+		if key := ctx.Params("key"); key != "" {
+			fields["key"] = key
+		}
+		accessLogger.WithFields(fields).Info("Handled a request")
+		return err
+	}
+}
+
+// RequestID returns the ID of the current request
+func RequestID(ctx *fiber.Ctx) string {
+	requestID, _ := ctx.Locals("_request_id").(string)
+	return requestID
+}
+
+// ClientIP returns the IP address of the client, honoring the forwarding headers of trusted proxies
+func ClientIP(ctx *fiber.Ctx) string {
+	if ip, ok := ctx.Locals("_client_ip").(string); ok {
+		return ip
+	}
+	return ctx.IP()
+}
+
+// WithRequest creates a log entry carrying the ID of the current request
+func WithRequest(ctx *fiber.Ctx) *log.Entry {
+	return log.WithField("request_id", RequestID(ctx))
+}
+
+// validRequestID checks whether a request ID supplied by a client may be propagated
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, char := range requestID {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+	return true
+}
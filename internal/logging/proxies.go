@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TrustedProxies represents the networks of the reverse proxies whose forwarding headers are trusted
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR ranges
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("the trusted proxy '%s' is no valid IP address or CIDR range", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("the trusted proxy '%s' is no valid IP address or CIDR range", value)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// trusts checks whether the given IP address belongs to a trusted proxy
+func (proxies TrustedProxies) trusts(ip net.IP) bool {
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve determines the IP address of the client out of the address of the peer and its X-Forwarded-For header.
+// The header is only honored if the peer is a trusted proxy; it is then walked from the right and the first address
+// not belonging to a trusted proxy is the client's one.
+func (proxies TrustedProxies) resolve(peer net.IP, forwardedFor string) string {
+	if len(proxies) == 0 || forwardedFor == "" || !proxies.trusts(peer) {
+		return peer.String()
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	client := peer.String()
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !proxies.trusts(ip) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// statementTable matches the first table a SQL statement reads from or writes to
+var statementTable = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+([a-z_]+)`)
+
+// ObserveQuery observes the latency of a database query which started at the given time
+func ObserveQuery(statement string, start time.Time) {
+	operation, table := describeStatement(statement)
+	queryDurations.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+}
+
+// describeStatement derives the operation and table labels of a SQL statement
+func describeStatement(statement string) (string, string) {
+	operation := "unknown"
+	if fields := strings.Fields(statement); len(fields) > 0 {
+		operation = strings.ToLower(fields[0])
+	}
+	table := "unknown"
+	if match := statementTable.FindStringSubmatch(statement); match != nil {
+		table = strings.ToLower(match[1])
+	}
+	return operation, table
+}
+
+// poolCollector represents the collector exporting the statistics of a postgres connection pool
+type poolCollector struct {
+	stat            func() *pgxpool.Stat
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// RegisterPool registers a collector exporting the statistics of a postgres connection pool
+func RegisterPool(stat func() *pgxpool.Stat) {
+	prometheus.MustRegister(&poolCollector{
+		stat:            stat,
+		acquiredConns:   poolDesc("acquired_connections", "The amount of currently acquired connections."),
+		idleConns:       poolDesc("idle_connections", "The amount of currently idle connections."),
+		totalConns:      poolDesc("connections", "The amount of currently open connections."),
+		maxConns:        poolDesc("max_connections", "The maximum amount of connections."),
+		acquires:        poolDesc("acquires_total", "The amount of successful connection acquires."),
+		acquireDuration: poolDesc("acquire_duration_seconds_total", "The total time spent acquiring connections."),
+		emptyAcquires:   poolDesc("empty_acquires_total", "The amount of acquires which had to wait for a connection."),
+		canceledAcquire: poolDesc("canceled_acquires_total", "The amount of acquires which were canceled."),
+	})
+}
+
+// poolDesc describes a connection pool metric
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "database_pool", name), help, nil, nil)
+}
+
+// Describe sends the descriptions of the connection pool metrics
+func (collector *poolCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.acquiredConns
+	descs <- collector.idleConns
+	descs <- collector.totalConns
+	descs <- collector.maxConns
+	descs <- collector.acquires
+	descs <- collector.acquireDuration
+	descs <- collector.emptyAcquires
+	descs <- collector.canceledAcquire
+}
+
+// Collect sends the current statistics of the connection pool
+func (collector *poolCollector) Collect(metrics chan<- prometheus.Metric) {
+	stat := collector.stat()
+	metrics <- prometheus.MustNewConstMetric(collector.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	metrics <- prometheus.MustNewConstMetric(collector.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	metrics <- prometheus.MustNewConstMetric(collector.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	metrics <- prometheus.MustNewConstMetric(collector.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	metrics <- prometheus.MustNewConstMetric(collector.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	metrics <- prometheus.MustNewConstMetric(collector.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	metrics <- prometheus.MustNewConstMetric(collector.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	metrics <- prometheus.MustNewConstMetric(collector.canceledAcquire, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace represents the prefix of every exported metric
+const namespace = "x0"
+
+var (
+	// requests counts the handled HTTP requests per server, route, method and status code
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "The amount of handled HTTP requests.",
+	}, []string{"server", "route", "method", "status"})
+
+	// requestDurations observes the latencies of handled HTTP requests per server, route, method and status code
+	requestDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "The latencies of handled HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"server", "route", "method", "status"})
+
+	// resolutions counts the elements resolved by the gateway per element type
+	resolutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gateway_resolutions_total",
+		Help:      "The amount of elements resolved by the gateway.",
+	}, []string{"type"})
+
+	// queryDurations observes the latencies of database queries per operation and table
+	queryDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "database_query_duration_seconds",
+		Help:      "The latencies of database queries.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// limiterRejections counts the requests rejected by a rate limiter
+	limiterRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "limiter_rejections_total",
+		Help:      "The amount of requests rejected by a rate limiter.",
+	}, []string{"limiter"})
+
+	// webhookAttempts counts the attempted webhook deliveries per resulting delivery status
+	webhookAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempts_total",
+		Help:      "The amount of attempted webhook deliveries.",
+	}, []string{"status"})
+)
+
+// CountResolution counts an element of the given type resolved by the gateway
+func CountResolution(elementType string) {
+	resolutions.WithLabelValues(elementType).Inc()
+}
+
+// CountLimiterRejection counts a request rejected by the given rate limiter
+func CountLimiterRejection(limiter string) {
+	limiterRejections.WithLabelValues(limiter).Inc()
+}
+
+// CountWebhookAttempt counts an attempted webhook delivery which resulted in the given delivery status
+func CountWebhookAttempt(status string) {
+	webhookAttempts.WithLabelValues(status).Inc()
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"time"
+)
+
+// Middleware creates a middleware observing the requests handled by the given server
+func Middleware(server string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+
+		// Determine the status code the error handler is going to respond with
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if fiberError, ok := err.(*fiber.Error); ok {
+				status = fiberError.Code
+			}
+		}
+
+		// Only use route patterns as labels to keep their cardinality bounded;
+		// requests which did not match any route only passed the global middlewares
+		route := ctx.Route().Path
+		if route == "/" && ctx.Path() != "/" {
+			route = "unmatched"
+		}
+
+		labels := []string{server, route, ctx.Method(), strconv.Itoa(status)}
+		requests.WithLabelValues(labels...).Inc()
+		requestDurations.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
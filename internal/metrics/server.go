@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// Server represents the HTTP server exposing the metrics
+type Server struct {
+	server  *http.Server
+	Address string
+}
+
+// Serve serves the metrics on the /metrics endpoint
+func (server *Server) Serve() error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server.server = &http.Server{
+		Addr:    server.Address,
+		Handler: mux,
+	}
+
+	log.WithField("address", server.Address).Info("Serving the metrics")
+	if err := server.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts down the metrics server
+func (server *Server) Shutdown() error {
+	log.Info("Shutting down the metrics server")
+	return server.server.Shutdown(context.Background())
+}
@@ -0,0 +1,110 @@
+package reaper
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/blob"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/webhook"
+	"time"
+)
+
+// sweepGracePeriod represents the minimum age of an unreferenced blob before it gets swept; younger ones may belong to a write which is still in progress
+const sweepGracePeriod = time.Hour
+
+// Reaper represents the background worker periodically purging expired elements and sweeping unreferenced blobs
+type Reaper struct {
+	stop          chan struct{}
+	done          chan struct{}
+	lastSweep     time.Time
+	Interval      time.Duration
+	SweepInterval time.Duration
+	Elements      shared.ElementService
+	Blobs         shared.BlobStore
+	Notifier      *webhook.Notifier
+}
+
+// Start starts the reaper in a background goroutine
+func (reaper *Reaper) Start() {
+	reaper.stop = make(chan struct{})
+	reaper.done = make(chan struct{})
+	go reaper.run()
+	log.WithField("interval", reaper.Interval).Info("Started the element reaper")
+}
+
+// Stop stops the reaper and waits for the current purge to finish
+func (reaper *Reaper) Stop() {
+	log.Info("Stopping the element reaper")
+	close(reaper.stop)
+	<-reaper.done
+}
+
+// run purges expired elements every interval and sweeps blobs every sweep interval until the reaper gets stopped
+func (reaper *Reaper) run() {
+	defer close(reaper.done)
+
+	ticker := time.NewTicker(reaper.Interval)
+	defer ticker.Stop()
+
+	for {
+		reaper.purge()
+		if reaper.Blobs != nil && time.Since(reaper.lastSweep) >= reaper.SweepInterval {
+			reaper.sweep()
+			reaper.lastSweep = time.Now()
+		}
+		select {
+		case <-reaper.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge deletes every expired element
+func (reaper *Reaper) purge() {
+	purged, err := reaper.Elements.DeleteExpired()
+	if err != nil {
+		log.WithError(err).Error("Could not purge expired elements")
+		return
+	}
+	blob.Release(reaper.Blobs, purged...)
+	for _, element := range purged {
+		if err := reaper.Notifier.Notify(element.Namespace, shared.WebhookElementExpired, webhook.ElementData(element)); err != nil {
+			log.WithError(err).WithField("namespace", element.Namespace).Error("Could not enqueue the deliveries of a webhook event")
+		}
+	}
+	if len(purged) > 0 {
+		log.WithField("amount", len(purged)).Info("Purged expired elements")
+	}
+}
+
+// sweep deletes every blob which is neither referenced by an element nor by one of their revisions anymore
+func (reaper *Reaper) sweep() {
+	ids, err := reaper.Elements.ReferencedBlobs()
+	if err != nil {
+		log.WithError(err).Error("Could not look up the referenced blobs")
+		return
+	}
+	referenced := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		referenced[id] = true
+	}
+
+	swept := 0
+	err = reaper.Blobs.Walk(func(info *shared.BlobInfo) error {
+		if referenced[info.ID] || time.Since(info.ModifiedAt) < sweepGracePeriod {
+			return nil
+		}
+		if err := reaper.Blobs.Delete(info.ID); err != nil {
+			log.WithError(err).WithField("blob", info.ID).Error("Could not delete an unreferenced blob")
+			return nil
+		}
+		swept++
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Could not sweep unreferenced blobs")
+	}
+	if swept > 0 {
+		log.WithField("amount", swept).Info("Swept unreferenced blobs")
+	}
+}
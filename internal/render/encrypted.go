@@ -0,0 +1,126 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/x0tf/server/internal/shared"
+	"html/template"
+	"io"
+)
+
+// decryptionScript decrypts an encrypted paste in the browser using the key or passphrase contained in the URL fragment,
+// which browsers never send to the server
+const decryptionScript = `(function () {
+	var envelope = JSON.parse(document.getElementById("envelope").textContent);
+	var output = document.getElementById("content");
+	var status = document.getElementById("status");
+	var form = document.getElementById("passphrase");
+
+	function decode(value) {
+		var binary = atob(value.replace(/-/g, "+").replace(/_/g, "/"));
+		var bytes = new Uint8Array(binary.length);
+		for (var i = 0; i < binary.length; i++) {
+			bytes[i] = binary.charCodeAt(i);
+		}
+		return bytes;
+	}
+
+	function deriveKey(secret) {
+		if (!envelope.kdf) {
+			return crypto.subtle.importKey("raw", decode(secret), "AES-GCM", false, ["decrypt"]);
+		}
+		return crypto.subtle.importKey("raw", new TextEncoder().encode(secret), "PBKDF2", false, ["deriveKey"]).then(function (material) {
+			return crypto.subtle.deriveKey(
+				{name: "PBKDF2", hash: "SHA-256", salt: decode(envelope.kdf.salt), iterations: envelope.kdf.iterations},
+				material, {name: "AES-GCM", length: 256}, false, ["decrypt"]
+			);
+		});
+	}
+
+	function decrypt(secret) {
+		status.textContent = "Decrypting...";
+		Promise.resolve().then(function () {
+			return deriveKey(secret);
+		}).then(function (key) {
+			return crypto.subtle.decrypt({name: "AES-GCM", iv: decode(envelope.nonce)}, key, decode(envelope.ciphertext));
+		}).then(function (plaintext) {
+			output.textContent = new TextDecoder().decode(plaintext);
+			output.hidden = false;
+			form.hidden = true;
+			status.textContent = "";
+		}, function () {
+			status.textContent = "The paste could not be decrypted using the given key.";
+		});
+	}
+
+	form.addEventListener("submit", function (event) {
+		event.preventDefault();
+		decrypt(form.elements.secret.value);
+	});
+
+	var secret = decodeURIComponent(location.hash.slice(1));
+	if (secret) {
+		decrypt(secret);
+	} else if (envelope.kdf) {
+		form.hidden = false;
+		status.textContent = "";
+	} else {
+		status.textContent = "The decryption key is missing from the URL.";
+	}
+})();`
+
+// EncryptedPasteContentSecurityPolicy only allows the decryption script to run on the decryption page
+var EncryptedPasteContentSecurityPolicy = func() string {
+	hash := sha256.Sum256([]byte(decryptionScript))
+	return "default-src 'none'; style-src 'unsafe-inline'; script-src 'sha256-" + base64.StdEncoding.EncodeToString(hash[:]) + "'"
+}()
+
+// encryptedPasteTemplate is the HTML page encrypted pastes get decrypted in
+var encryptedPasteTemplate = template.Must(template.New("encrypted").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<meta name="referrer" content="no-referrer">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #fff; color: #24292e; }
+header { display: flex; justify-content: space-between; padding: .75rem 1rem; border-bottom: 1px solid #e1e4e8; font-size: .9rem; }
+main { padding: 1rem; }
+pre { margin: 0; font-size: .85rem; white-space: pre-wrap; word-break: break-word; }
+</style>
+</head>
+<body>
+<header><span>{{.Title}} &middot; encrypted</span></header>
+<main>
+<p id="status">This paste is encrypted and requires JavaScript to be decrypted in your browser.</p>
+<form id="passphrase" hidden><input type="password" name="secret" placeholder="Passphrase" autofocus> <button type="submit">Decrypt</button></form>
+<pre id="content" hidden></pre>
+</main>
+<script type="application/json" id="envelope">{{.Envelope}}</script>
+<script>{{.Script}}</script>
+</body>
+</html>
+`))
+
+// EncryptedEnvelope represents the ciphertext of an encrypted paste bundled with its encryption metadata
+type EncryptedEnvelope struct {
+	*shared.Encryption
+	Ciphertext string `json:"ciphertext"`
+}
+
+// EncryptedPaste represents an encrypted paste to render into a decryption page
+type EncryptedPaste struct {
+	Title    string
+	Envelope *EncryptedEnvelope
+}
+
+// RenderEncryptedPaste renders the page decrypting an encrypted paste in the browser
+func RenderEncryptedPaste(writer io.Writer, paste *EncryptedPaste) error {
+	return encryptedPasteTemplate.Execute(writer, map[string]interface{}{
+		"Title":    paste.Title,
+		"Envelope": paste.Envelope,
+		"Script":   template.JS(decryptionScript),
+	})
+}
@@ -0,0 +1,74 @@
+package render
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"html/template"
+	"io"
+)
+
+// markdownConverter converts GitHub-flavored markdown into HTML; raw HTML and dangerous link targets are omitted as the unsafe mode stays disabled
+var markdownConverter = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// DefaultMarkdownTemplate is the HTML page markdown elements get rendered into if no custom template is configured
+var DefaultMarkdownTemplate = template.Must(template.New("markdown").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; line-height: 1.5; background: #fff; color: #24292e; }
+header { display: flex; justify-content: space-between; padding: .75rem 1rem; border-bottom: 1px solid #e1e4e8; font-size: .9rem; }
+a { color: #0366d6; text-decoration: none; }
+main { max-width: 50rem; margin: 0 auto; padding: 1rem; }
+pre, code { font-size: .85rem; background: #f6f8fa; border-radius: 3px; }
+pre { padding: 1rem; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #dfe2e5; padding: .4rem .8rem; }
+blockquote { margin: 0; padding: 0 1rem; color: #6a737d; border-left: .25rem solid #dfe2e5; }
+img { max-width: 100%; }
+li input[type=checkbox] { margin-right: .4rem; }
+</style>
+</head>
+<body>
+<header><span>{{.Title}}</span>{{if .RawURL}}<a href="{{.RawURL}}">raw</a>{{end}}</header>
+<main>{{.Content}}</main>
+</body>
+</html>
+`))
+
+// Markdown represents a markdown document to render into an HTML page
+type Markdown struct {
+	Title  string
+	Source string
+	RawURL string
+}
+
+// LoadMarkdownTemplate parses a custom markdown page template; the template receives the fields Title, Content and RawURL
+func LoadMarkdownTemplate(path string) (*template.Template, error) {
+	return template.ParseFiles(path)
+}
+
+// RenderMarkdown renders a markdown document into an HTML page using the given template or the default one if it is nil
+func RenderMarkdown(writer io.Writer, tmpl *template.Template, markdown *Markdown) error {
+	var content bytes.Buffer
+	if err := markdownConverter.Convert([]byte(markdown.Source), &content); err != nil {
+		return err
+	}
+
+	if tmpl == nil {
+		tmpl = DefaultMarkdownTemplate
+	}
+	return tmpl.Execute(writer, map[string]interface{}{
+		"Title":   markdown.Title,
+		"Content": template.HTML(content.String()),
+		"RawURL":  markdown.RawURL,
+	})
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"html/template"
+	"io"
+)
+
+// passwordFormTemplate is the HTML page asking visitors for the password of a protected element
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #fff; color: #24292e; }
+header { padding: .75rem 1rem; border-bottom: 1px solid #e1e4e8; font-size: .9rem; }
+main { padding: 1rem; }
+.error { color: #cb2431; }
+</style>
+</head>
+<body>
+<header>{{.Title}} &middot; password-protected</header>
+<main>
+{{if .Failed}}<p class="error">The password is wrong.</p>{{end}}
+<form method="post"><input type="password" name="password" placeholder="Password" autofocus required> <button type="submit">Open</button></form>
+</main>
+</body>
+</html>
+`))
+
+// PasswordForm represents the password form of a protected element
+type PasswordForm struct {
+	Title  string
+	Failed bool
+}
+
+// RenderPasswordForm renders the page asking for the password of a protected element
+func RenderPasswordForm(writer io.Writer, form *PasswordForm) error {
+	return passwordFormTemplate.Execute(writer, form)
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bytes"
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"html/template"
+	"io"
+)
+
+// pasteFormatter renders highlighted source code including anchorable line numbers
+var pasteFormatter = html.New(
+	html.WithClasses(true),
+	html.WithLineNumbers(true),
+	html.LineNumbersInTable(true),
+	html.LinkableLineNumbers(true, "L"),
+	html.TabWidth(4),
+)
+
+// pasteStyle is the color scheme used to highlight pastes
+var pasteStyle = styles.Get("github")
+
+// pasteTemplate is the HTML page pastes get rendered into
+var pasteTemplate = template.Must(template.New("paste").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #fff; color: #24292e; }
+header { display: flex; justify-content: space-between; padding: .75rem 1rem; border-bottom: 1px solid #e1e4e8; font-size: .9rem; }
+header a { color: #0366d6; text-decoration: none; }
+main { overflow-x: auto; font-size: .85rem; }
+main pre { margin: 0; padding: 0 .5rem; }
+main .lnt a { color: inherit; text-decoration: none; }
+main .line:target, main .lnt:target { background: #fffbdd; }
+{{.CSS}}
+</style>
+</head>
+<body>
+<header><span>{{.Title}}{{if .Language}} &middot; {{.Language}}{{end}}</span>{{if .RawURL}}<a href="{{.RawURL}}">raw</a>{{end}}</header>
+<main>{{.Code}}</main>
+</body>
+</html>
+`))
+
+// pasteCSS contains the style sheet of the paste color scheme
+var pasteCSS = func() template.CSS {
+	var buffer bytes.Buffer
+	if err := pasteFormatter.WriteCSS(&buffer, pasteStyle); err != nil {
+		panic(err)
+	}
+	return template.CSS(buffer.String())
+}()
+
+// Paste represents a paste to render into an HTML page
+type Paste struct {
+	Title    string
+	Language string
+	Content  string
+	RawURL   string
+}
+
+// Language resolves a language name, alias or file extension into the canonical name of the corresponding lexer
+func Language(name string) (string, bool) {
+	lexer := lexers.Get(name)
+	if lexer == nil {
+		return "", false
+	}
+	return lexer.Config().Name, true
+}
+
+// RenderPaste renders a paste into a syntax-highlighted HTML page; the language gets guessed if none is set
+func RenderPaste(writer io.Writer, paste *Paste) error {
+	// Choose the lexer to highlight the paste with
+	var lexer chroma.Lexer
+	if paste.Language != "" {
+		lexer = lexers.Get(paste.Language)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(paste.Content)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	// Highlight the paste content
+	iterator, err := lexer.Tokenise(nil, paste.Content)
+	if err != nil {
+		return err
+	}
+	var code bytes.Buffer
+	if err := pasteFormatter.Format(&code, pasteStyle, iterator); err != nil {
+		return err
+	}
+
+	language := lexer.Config().Name
+	if language == lexers.Fallback.Config().Name {
+		language = ""
+	}
+	return pasteTemplate.Execute(writer, map[string]interface{}{
+		"Title":    paste.Title,
+		"Language": language,
+		"CSS":      pasteCSS,
+		"Code":     template.HTML(code.String()),
+		"RawURL":   paste.RawURL,
+	})
+}
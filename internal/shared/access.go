@@ -0,0 +1,10 @@
+package shared
+
+import "time"
+
+// Access represents the latest resolution of an element through the gateway
+type Access struct {
+	Namespace  string
+	Key        string
+	AccessedAt time.Time
+}
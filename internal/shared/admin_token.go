@@ -0,0 +1,25 @@
+package shared
+
+import "time"
+
+// AdminToken represents a named admin token stored in the database
+type AdminToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Expired checks whether the token has expired
+func (token *AdminToken) Expired() bool {
+	return token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now())
+}
+
+// AdminTokenService represents an admin token database service
+type AdminTokenService interface {
+	Token(id string) (*AdminToken, error)
+	Tokens() ([]*AdminToken, error)
+	CreateOrReplace(*AdminToken) error
+	Delete(id string) error
+}
@@ -0,0 +1,102 @@
+package shared
+
+import "time"
+
+// AuditAction represents the kind of action an audit entry records
+type AuditAction string
+
+const (
+	// AuditNamespaceCreate records the creation of a namespace
+	AuditNamespaceCreate = AuditAction("namespace.create")
+
+	// AuditNamespaceActivate records the activation of a namespace
+	AuditNamespaceActivate = AuditAction("namespace.activate")
+
+	// AuditNamespaceDeactivate records the deactivation of a namespace
+	AuditNamespaceDeactivate = AuditAction("namespace.deactivate")
+
+	// AuditNamespaceDelete records the deletion of a namespace
+	AuditNamespaceDelete = AuditAction("namespace.delete")
+
+	// AuditNamespaceResetToken records the reset of the token of a namespace
+	AuditNamespaceResetToken = AuditAction("namespace.reset_token")
+
+	// AuditTokenCreate records the creation of a named namespace token
+	AuditTokenCreate = AuditAction("token.create")
+
+	// AuditTokenUpdate records the update of a named namespace token
+	AuditTokenUpdate = AuditAction("token.update")
+
+	// AuditTokenDelete records the deletion of a named namespace token
+	AuditTokenDelete = AuditAction("token.delete")
+
+	// AuditElementDelete records the deletion of an element
+	AuditElementDelete = AuditAction("element.delete")
+
+	// AuditAdminTokenCreate records the creation of an admin token
+	AuditAdminTokenCreate = AuditAction("admin_token.create")
+
+	// AuditAdminTokenUpdate records the update of an admin token
+	AuditAdminTokenUpdate = AuditAction("admin_token.update")
+
+	// AuditAdminTokenRotate records the rotation of an admin token
+	AuditAdminTokenRotate = AuditAction("admin_token.rotate")
+
+	// AuditAdminTokenDelete records the deletion of an admin token
+	AuditAdminTokenDelete = AuditAction("admin_token.delete")
+
+	// AuditWebhookCreate records the creation of a webhook
+	AuditWebhookCreate = AuditAction("webhook.create")
+
+	// AuditWebhookUpdate records the update of a webhook
+	AuditWebhookUpdate = AuditAction("webhook.update")
+
+	// AuditWebhookDelete records the deletion of a webhook
+	AuditWebhookDelete = AuditAction("webhook.delete")
+
+	// AuditInviteCreate records the creation of an invite code
+	AuditInviteCreate = AuditAction("invite.create")
+
+	// AuditInviteDelete records the deletion of an invite code
+	AuditInviteDelete = AuditAction("invite.delete")
+)
+
+// AuditEntry represents a single entry of the append-only audit log
+type AuditEntry struct {
+	ID        int64       `json:"id"`
+	Actor     string      `json:"actor"`
+	ActorName string      `json:"actor_name,omitempty"`
+	Action    AuditAction `json:"action"`
+	Namespace string      `json:"namespace,omitempty"`
+	Target    string      `json:"target,omitempty"`
+	IP        string      `json:"ip,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// AuditQuery represents a filtered and paginated audit log listing, which is always ordered by the entry IDs
+type AuditQuery struct {
+	// Actor, Action and Namespace restrict the listing to entries with the given values if set
+	Actor     string
+	Action    AuditAction
+	Namespace string
+
+	// CreatedAfter and CreatedBefore restrict the listing to entries created in the given (exclusive) range
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Descending lists the latest entries first
+	Descending bool
+
+	// AfterID continues the listing behind the entry with the given ID, which was the last one of the previous page
+	AfterID int64
+
+	// Limit restricts the amount of listed entries if positive
+	Limit int
+}
+
+// AuditService represents an audit log database service; entries may only be appended, never changed or removed
+type AuditService interface {
+	Record(*AuditEntry) error
+	Entries(*AuditQuery) ([]*AuditEntry, error)
+}
@@ -0,0 +1,22 @@
+package shared
+
+import (
+	"io"
+	"time"
+)
+
+// BlobInfo represents the metadata of a stored blob
+type BlobInfo struct {
+	ID         string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// BlobStore represents a storage for binary large objects like uploaded files or large pastes
+type BlobStore interface {
+	Put(string, io.Reader, int64) error
+	Get(string) (io.ReadCloser, error)
+	Stat(string) (*BlobInfo, error)
+	Delete(string) error
+	Walk(func(*BlobInfo) error) error
+}
@@ -1,5 +1,10 @@
 package shared
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ElementType represents an element type
 type ElementType int
 
@@ -9,22 +14,114 @@ const (
 
 	// ElementTypeRedirect represents the element type for a redirect
 	ElementTypeRedirect = ElementType(1)
+
+	// ElementTypeFile represents the element type for an uploaded file
+	ElementTypeFile = ElementType(2)
+
+	// ElementTypeMarkdown represents the element type for a markdown document rendered to HTML
+	ElementTypeMarkdown = ElementType(3)
 )
 
+// String returns the name of the element type
+func (typ ElementType) String() string {
+	switch typ {
+	case ElementTypePaste:
+		return "paste"
+	case ElementTypeRedirect:
+		return "redirect"
+	case ElementTypeFile:
+		return "file"
+	case ElementTypeMarkdown:
+		return "markdown"
+	default:
+		return "unknown"
+	}
+}
+
 // Element represents an element published on the service
 type Element struct {
-	Namespace string      `json:"namespace"`
-	Key       string      `json:"key"`
-	Type      ElementType `json:"type"`
-	Data      string      `json:"data"`
+	Namespace      string      `json:"namespace"`
+	Key            string      `json:"key"`
+	Type           ElementType `json:"type"`
+	Data           string      `json:"data"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"`
+	RemainingViews *int        `json:"remaining_views,omitempty"`
+	Blob           string      `json:"-"`
+	ContentType    string      `json:"content_type,omitempty"`
+	Filename       string      `json:"filename,omitempty"`
+	Size           int64       `json:"size,omitempty"`
+	Language       string      `json:"language,omitempty"`
+	Encryption     *Encryption `json:"encryption,omitempty"`
+	PasswordHash   string      `json:"-"`
+	Revision       int         `json:"revision"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	LastAccessedAt *time.Time  `json:"last_accessed_at,omitempty"`
+	Actor          string      `json:"-"`
+}
+
+// Encryption represents the metadata of a client-side encrypted element whose data only contains the ciphertext
+type Encryption struct {
+	Cipher string `json:"cipher"`
+	Nonce  string `json:"nonce"`
+	KDF    *KDF   `json:"kdf,omitempty"`
+}
+
+// KDF represents the parameters of the key derivation function used to derive an encryption key out of a passphrase
+type KDF struct {
+	Name       string `json:"name"`
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+}
+
+// Expired checks whether the element has reached its expiration time
+func (element *Element) Expired() bool {
+	return element.ExpiresAt != nil && !element.ExpiresAt.After(time.Now())
+}
+
+// Protected checks whether the element only resolves after a password was supplied
+func (element *Element) Protected() bool {
+	return element.PasswordHash != ""
+}
+
+// Offloaded checks whether the payload of the element is stored in the blob store and was not loaded, which is the case in listings
+func (element *Element) Offloaded() bool {
+	return element.Type != ElementTypeFile && element.Blob != "" && element.Data == ""
+}
+
+// MarshalJSON includes whether the element is password-protected and whether its payload was not loaded into its JSON representation
+func (element *Element) MarshalJSON() ([]byte, error) {
+	type plainElement Element
+	return json.Marshal(&struct {
+		*plainElement
+		PasswordProtected bool `json:"password_protected,omitempty"`
+		Offloaded         bool `json:"offloaded,omitempty"`
+	}{
+		plainElement:      (*plainElement)(element),
+		PasswordProtected: element.Protected(),
+		Offloaded:         element.Offloaded(),
+	})
+}
+
+// Exhausted checks whether the element has no views left
+func (element *Element) Exhausted() bool {
+	return element.RemainingViews != nil && *element.RemainingViews <= 0
 }
 
-// ElementService represents an element database service
+// ElementService represents an element database service.
+// Deleting an element deletes its revisions as well, so the content of deleted, expired and burned elements does not
+// survive in their history and an element created under the same key later on starts with a fresh one.
 type ElementService interface {
 	Element(string, string) (*Element, error)
-	Elements() ([]*Element, error)
-	ElementsInNamespace(string) ([]*Element, error)
+	Elements(*ElementQuery) ([]*Element, error)
 	CreateOrReplace(*Element) error
+	Update(*Element, int) (bool, error)
 	Delete(string, string) error
-	DeleteInNamespace(string) error
+	DeleteInNamespace(string) ([]*Element, error)
+	DeleteExpired() ([]*Element, error)
+	ConsumeView(string, string) (*Element, error)
+	Touch([]*Access) ([]*Access, error)
+	Revisions(string, string) ([]*Revision, error)
+	Revision(string, string, int) (*Revision, error)
+	ReferencedBlobs() ([]string, error)
 }
@@ -0,0 +1,54 @@
+package shared
+
+import "time"
+
+// UserAgentClass represents the kind of client which resolved an element
+type UserAgentClass string
+
+const (
+	// UserAgentBrowser represents desktop browsers
+	UserAgentBrowser = UserAgentClass("browser")
+
+	// UserAgentMobile represents browsers on mobile devices
+	UserAgentMobile = UserAgentClass("mobile")
+
+	// UserAgentBot represents crawlers, link preview generators and monitoring services
+	UserAgentBot = UserAgentClass("bot")
+
+	// UserAgentCLI represents command line tools and HTTP libraries
+	UserAgentCLI = UserAgentClass("cli")
+
+	// UserAgentOther represents every client which could not be classified
+	UserAgentOther = UserAgentClass("other")
+)
+
+// Hit represents a single resolution of an element through the gateway
+type Hit struct {
+	Namespace      string
+	Key            string
+	HitAt          time.Time
+	ReferrerHost   string
+	UserAgentClass UserAgentClass
+	Country        string
+}
+
+// HitStats represents the aggregated hits of an element
+type HitStats struct {
+	Total      int         `json:"total"`
+	Days       []*HitCount `json:"days"`
+	Referrers  []*HitCount `json:"referrers"`
+	UserAgents []*HitCount `json:"user_agents"`
+	Countries  []*HitCount `json:"countries"`
+}
+
+// HitCount represents the amount of hits sharing a single value, like the day or referrer host
+type HitCount struct {
+	Value string `json:"value"`
+	Hits  int    `json:"hits"`
+}
+
+// HitService represents a hit database service
+type HitService interface {
+	Record([]*Hit) error
+	Stats(string, string, time.Time) (*HitStats, error)
+}
@@ -9,4 +9,5 @@ type InviteService interface {
 	Invites() ([]Invite, error)
 	Create(Invite) error
 	Delete(string) error
+	Consume(string) (bool, error)
 }
@@ -1,16 +1,24 @@
 package shared
 
+import "time"
+
 // Namespace represents a namespace
 type Namespace struct {
-	ID     string `json:"id"`
-	Token  string `json:"token,omitempty"`
-	Active bool   `json:"active"`
+	ID             string     `json:"id"`
+	Token          string     `json:"token,omitempty"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
 }
 
 // NamespaceService represents a namespace database service
 type NamespaceService interface {
 	Namespace(string) (*Namespace, error)
-	Namespaces() ([]*Namespace, error)
+	Namespaces(*NamespaceQuery) ([]*Namespace, error)
 	CreateOrReplace(*Namespace) error
+	Create(*Namespace) (bool, error)
 	Delete(string) error
+	Touch([]*Access) error
+	ReplaceToken(id, previous, replacement string) (bool, error)
 }
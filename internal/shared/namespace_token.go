@@ -0,0 +1,71 @@
+package shared
+
+import "time"
+
+// TokenScope represents a permission a namespace token may be granted
+type TokenScope string
+
+const (
+	// TokenScopeReadElements allows listing the elements of a namespace and reading their revisions and statistics
+	TokenScopeReadElements = TokenScope("elements:read")
+
+	// TokenScopeCreatePastes allows creating and updating pastes, markdown documents and files
+	TokenScopeCreatePastes = TokenScope("pastes:create")
+
+	// TokenScopeCreateRedirects allows creating and updating redirects
+	TokenScopeCreateRedirects = TokenScope("redirects:create")
+
+	// TokenScopeDeleteElements allows deleting elements
+	TokenScopeDeleteElements = TokenScope("elements:delete")
+
+	// TokenScopeManageTokens allows managing the tokens of a namespace, resetting its token and deleting it
+	TokenScopeManageTokens = TokenScope("tokens:manage")
+
+	// TokenScopeManageWebhooks allows managing the webhooks of a namespace and inspecting their deliveries
+	TokenScopeManageWebhooks = TokenScope("webhooks:manage")
+)
+
+// TokenScopes contains every token scope
+var TokenScopes = []TokenScope{
+	TokenScopeReadElements,
+	TokenScopeCreatePastes,
+	TokenScopeCreateRedirects,
+	TokenScopeDeleteElements,
+	TokenScopeManageTokens,
+	TokenScopeManageWebhooks,
+}
+
+// NamespaceToken represents a named API token of a namespace which was granted a set of scopes
+type NamespaceToken struct {
+	Namespace string       `json:"namespace"`
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Hash      string       `json:"-"`
+	Scopes    []TokenScope `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// HasScope checks whether the token was granted the given scope
+func (token *NamespaceToken) HasScope(scope TokenScope) bool {
+	for _, granted := range token.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired checks whether the token has expired
+func (token *NamespaceToken) Expired() bool {
+	return token.ExpiresAt != nil && !token.ExpiresAt.After(time.Now())
+}
+
+// NamespaceTokenService represents a namespace token database service
+type NamespaceTokenService interface {
+	Token(namespace, id string) (*NamespaceToken, error)
+	Tokens(namespace string) ([]*NamespaceToken, error)
+	CreateOrReplace(*NamespaceToken) error
+	Delete(namespace, id string) error
+	ReplaceHash(namespace, id, previous, replacement string) (bool, error)
+}
@@ -0,0 +1,71 @@
+package shared
+
+import "time"
+
+// SortOrder represents the order a listing gets sorted in
+type SortOrder int
+
+const (
+	// SortByKey sorts elements by their namespace and key and namespaces by their ID
+	SortByKey = SortOrder(0)
+
+	// SortByKeyDescending sorts like SortByKey in descending order
+	SortByKeyDescending = SortOrder(1)
+
+	// SortByCreation sorts by the creation time, using the key as a tiebreaker
+	SortByCreation = SortOrder(2)
+
+	// SortByCreationDescending sorts like SortByCreation in descending order
+	SortByCreationDescending = SortOrder(3)
+)
+
+// Descending checks whether the sort order is a descending one
+func (order SortOrder) Descending() bool {
+	return order == SortByKeyDescending || order == SortByCreationDescending
+}
+
+// ElementQuery represents a filtered, sorted and paginated element listing
+type ElementQuery struct {
+	// Namespace restricts the listing to a single namespace if set
+	Namespace string
+
+	// Type restricts the listing to a single element type if set
+	Type *ElementType
+
+	// KeyPrefix restricts the listing to elements whose key starts with it
+	KeyPrefix string
+
+	// CreatedAfter and CreatedBefore restrict the listing to elements created in the given (exclusive) range
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Sort defines the order of the listing
+	Sort SortOrder
+
+	// After continues the listing behind the given element, which was the last one of the previous page;
+	// only the namespace, key and creation time need to be set
+	After *Element
+
+	// Limit restricts the amount of listed elements if positive
+	Limit int
+}
+
+// NamespaceQuery represents a filtered, sorted and paginated namespace listing
+type NamespaceQuery struct {
+	// IDPrefix restricts the listing to namespaces whose ID starts with it
+	IDPrefix string
+
+	// CreatedAfter and CreatedBefore restrict the listing to namespaces created in the given (exclusive) range
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Sort defines the order of the listing
+	Sort SortOrder
+
+	// After continues the listing behind the given namespace, which was the last one of the previous page;
+	// only the ID and creation time need to be set
+	After *Namespace
+
+	// Limit restricts the amount of listed namespaces if positive
+	Limit int
+}
@@ -0,0 +1,51 @@
+package shared
+
+import "time"
+
+// Revision represents a recorded state of the content of an element
+type Revision struct {
+	Namespace   string      `json:"namespace"`
+	Key         string      `json:"key"`
+	Revision    int         `json:"revision"`
+	Type        ElementType `json:"type"`
+	Data        string      `json:"data"`
+	Blob        string      `json:"-"`
+	ContentType string      `json:"content_type,omitempty"`
+	Filename    string      `json:"filename,omitempty"`
+	Size        int64       `json:"size,omitempty"`
+	Language    string      `json:"language,omitempty"`
+	Encryption  *Encryption `json:"encryption,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Actor       string      `json:"actor"`
+}
+
+// NewRevision records the current content of an element
+func NewRevision(element *Element, createdAt time.Time) *Revision {
+	return &Revision{
+		Namespace:   element.Namespace,
+		Key:         element.Key,
+		Revision:    element.Revision,
+		Type:        element.Type,
+		Data:        element.Data,
+		Blob:        element.Blob,
+		ContentType: element.ContentType,
+		Filename:    element.Filename,
+		Size:        element.Size,
+		Language:    element.Language,
+		Encryption:  element.Encryption,
+		CreatedAt:   createdAt,
+		Actor:       element.Actor,
+	}
+}
+
+// ApplyTo replaces the content of an element with the recorded one
+func (revision *Revision) ApplyTo(element *Element) {
+	element.Type = revision.Type
+	element.Data = revision.Data
+	element.Blob = revision.Blob
+	element.ContentType = revision.ContentType
+	element.Filename = revision.Filename
+	element.Size = revision.Size
+	element.Language = revision.Language
+	element.Encryption = revision.Encryption
+}
@@ -0,0 +1,21 @@
+package shared
+
+// Services bundles the database services of a single storage backend
+type Services struct {
+	Namespaces  NamespaceService
+	Elements    ElementService
+	Invites     InviteService
+	Hits        HitService
+	Tokens      NamespaceTokenService
+	AdminTokens AdminTokenService
+	Audit       AuditService
+	Webhooks    WebhookService
+}
+
+// Transactor represents a storage backend which is able to execute a unit of work atomically
+type Transactor interface {
+	// Transaction executes the given function using services bound to a single transaction.
+	// The transaction gets committed if the function returns no error and rolled back otherwise.
+	// Services obtained outside of the function must not be used inside of it.
+	Transaction(func(*Services) error) error
+}
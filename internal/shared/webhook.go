@@ -0,0 +1,123 @@
+package shared
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent represents the kind of event a webhook may be notified about
+type WebhookEvent string
+
+const (
+	// WebhookElementCreated is emitted whenever an element gets created
+	WebhookElementCreated = WebhookEvent("element.created")
+
+	// WebhookElementDeleted is emitted whenever an element gets deleted explicitly
+	WebhookElementDeleted = WebhookEvent("element.deleted")
+
+	// WebhookElementExpired is emitted whenever an element gets purged after it expired or ran out of views
+	WebhookElementExpired = WebhookEvent("element.expired")
+
+	// WebhookElementFirstAccessed is emitted when an element gets resolved through the gateway for the first time
+	WebhookElementFirstAccessed = WebhookEvent("element.first_accessed")
+
+	// WebhookNamespaceActivated is emitted whenever a namespace gets activated
+	WebhookNamespaceActivated = WebhookEvent("namespace.activated")
+
+	// WebhookNamespaceDeactivated is emitted whenever a namespace gets deactivated
+	WebhookNamespaceDeactivated = WebhookEvent("namespace.deactivated")
+
+	// WebhookPing is delivered on request to test a webhook; every webhook receives it regardless of its events
+	WebhookPing = WebhookEvent("ping")
+)
+
+// WebhookEvents contains every event a webhook may subscribe to
+var WebhookEvents = []WebhookEvent{
+	WebhookElementCreated,
+	WebhookElementDeleted,
+	WebhookElementExpired,
+	WebhookElementFirstAccessed,
+	WebhookNamespaceActivated,
+	WebhookNamespaceDeactivated,
+}
+
+// Webhook represents a subscription of a namespace to a set of events which get delivered to an HTTP endpoint
+type Webhook struct {
+	Namespace string         `json:"namespace"`
+	ID        string         `json:"id"`
+	URL       string         `json:"url"`
+	Events    []WebhookEvent `json:"events"`
+	Secret    string         `json:"-"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Subscribed checks whether the webhook is active and subscribed to the given event
+func (webhook *Webhook) Subscribed(event WebhookEvent) bool {
+	if !webhook.Active {
+		return false
+	}
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return event == WebhookPing
+}
+
+// DeliveryStatus represents the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryPending represents a delivery which is waiting for its next attempt
+	DeliveryPending = DeliveryStatus("pending")
+
+	// DeliverySucceeded represents a delivery the receiver acknowledged with a 2xx status
+	DeliverySucceeded = DeliveryStatus("succeeded")
+
+	// DeliveryFailed represents a delivery which was given up on after its last attempt
+	DeliveryFailed = DeliveryStatus("failed")
+)
+
+// WebhookDelivery represents a single queued delivery of an event payload to a webhook
+type WebhookDelivery struct {
+	Namespace      string          `json:"namespace"`
+	Webhook        string          `json:"webhook"`
+	ID             string          `json:"id"`
+	Event          WebhookEvent    `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookService represents a webhook database service which also persists the delivery queue
+type WebhookService interface {
+	Webhook(namespace, id string) (*Webhook, error)
+	Webhooks(namespace string) ([]*Webhook, error)
+	CreateOrReplace(*Webhook) error
+	Delete(namespace, id string) error
+
+	// Enqueue stores new pending deliveries
+	Enqueue([]*WebhookDelivery) error
+
+	// Delivery searches for a single delivery of a webhook
+	Delivery(namespace, webhook, id string) (*WebhookDelivery, error)
+
+	// Deliveries searches for the latest deliveries of a webhook, optionally restricted to the given status
+	Deliveries(namespace, webhook string, status DeliveryStatus, limit int) ([]*WebhookDelivery, error)
+
+	// Claim returns up to the given amount of pending deliveries whose next attempt is due and postpones their next
+	// attempt by the given lease, so that concurrent dispatchers do not attempt the same delivery at the same time
+	Claim(limit int, lease time.Duration) ([]*WebhookDelivery, error)
+
+	// UpdateDelivery stores the outcome of an attempt of a delivery
+	UpdateDelivery(*WebhookDelivery) error
+
+	// PruneDeliveries deletes every finished delivery created before the given time and returns their amount
+	PruneDeliveries(before time.Time) (int, error)
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// digestPrefix marks stored hashes which are keyed digests instead of argon2id hashes
+const digestPrefix = "hmac-sha256$"
+
+// digestKey represents the secret key tokens get digested with
+var digestKey []byte
+
+// SetDigestKey sets the secret key tokens get digested with; it has to be called before any token is digested
+func SetDigestKey(key []byte) {
+	digestKey = key
+}
+
+// Digest creates the keyed digest of a randomly generated token.
+// Unlike Hash it is cheap to compute and thus must not be used for values chosen by users, like passwords.
+func Digest(value string) string {
+	mac := hmac.New(sha256.New, digestKey)
+	mac.Write([]byte(value))
+	return digestPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsDigest checks whether the given stored hash is a digest
+func IsDigest(stored string) bool {
+	return strings.HasPrefix(stored, digestPrefix)
+}
+
+// Verify compares the given token with its stored hash, which is either a digest or a legacy argon2id hash.
+// It additionally reports whether a matching hash is outdated and should be replaced by the digest of the token.
+func Verify(stored, value string) (valid bool, outdated bool, err error) {
+	if IsDigest(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(Digest(value))) == 1, false, nil
+	}
+	valid, err = Check(stored, value)
+	return valid, valid, err
+}
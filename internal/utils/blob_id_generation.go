@@ -0,0 +1,14 @@
+package utils
+
+// blobIDLength represents the length of a blob ID
+var blobIDLength = 32
+
+// blobIDCharacters represents the characters a blob ID may contain
+var blobIDCharacters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var blobIDCharactersRunes = []rune(blobIDCharacters)
+
+// GenerateBlobID generates a new blob ID
+func GenerateBlobID() string {
+	return GenerateRandomString(blobIDLength, blobIDCharactersRunes)
+}
@@ -0,0 +1,14 @@
+package utils
+
+// requestIDLength represents the length of a request ID
+var requestIDLength = 24
+
+// requestIDCharacters represents the characters a request ID may contain
+var requestIDCharacters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var requestIDCharactersRunes = []rune(requestIDCharacters)
+
+// GenerateRequestID generates a new request ID
+func GenerateRequestID() string {
+	return GenerateRandomString(requestIDLength, requestIDCharactersRunes)
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"math/big"
 	"math/rand"
 	"time"
 )
@@ -17,3 +19,17 @@ func GenerateRandomString(length int, allowedCharacters []rune) string {
 	}
 	return string(runes)
 }
+
+// GenerateSecureRandomString generates a cryptographically secure random string with a specific length
+func GenerateSecureRandomString(length int, allowedCharacters []rune) string {
+	max := big.NewInt(int64(len(allowedCharacters)))
+	runes := make([]rune, length)
+	for i := range runes {
+		index, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		runes[i] = allowedCharacters[index.Int64()]
+	}
+	return string(runes)
+}
@@ -8,7 +8,7 @@ var tokenCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ01234
 
 var tokenCharactersRunes = []rune(tokenCharacters)
 
-// GenerateToken generates a new token
+// GenerateToken generates a new token; tokens get digested instead of hashed, so they have to be unpredictable
 func GenerateToken() string {
-	return GenerateRandomString(tokenLength, tokenCharactersRunes)
+	return GenerateSecureRandomString(tokenLength, tokenCharactersRunes)
 }
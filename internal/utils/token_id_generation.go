@@ -0,0 +1,14 @@
+package utils
+
+// tokenIDLength represents the length of a namespace token ID
+var tokenIDLength = 16
+
+// tokenIDCharacters represents the characters a namespace token ID may contain
+var tokenIDCharacters = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var tokenIDCharactersRunes = []rune(tokenIDCharacters)
+
+// GenerateTokenID generates a new namespace token ID
+func GenerateTokenID() string {
+	return GenerateRandomString(tokenIDLength, tokenIDCharactersRunes)
+}
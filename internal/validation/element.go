@@ -0,0 +1,24 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// rootElementKey represents the key of the element the gateway resolves for the root of a namespace
+	rootElementKey = "@"
+)
+
+var (
+	// ErrElementKeyContainsRevisionSeparator is used when an element key contains the separator the gateway splits off revision numbers at
+	ErrElementKeyContainsRevisionSeparator = fmt.Errorf("the given element key contains an '@', which separates revision numbers (only the root key '%s' itself may contain it)", rootElementKey)
+)
+
+// ValidateElementKey validates a requested element key
+func ValidateElementKey(key string) (errors []error) {
+	if key != rootElementKey && strings.ContainsRune(key, '@') {
+		errors = append(errors, ErrElementKeyContainsRevisionSeparator)
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+)
+
+var (
+	// EncryptionCipherAES256GCM represents the only supported cipher, which browsers implement natively
+	EncryptionCipherAES256GCM = "AES-256-GCM"
+
+	// EncryptionKDFPBKDF2SHA256 represents the only supported key derivation function, which browsers implement natively
+	EncryptionKDFPBKDF2SHA256 = "PBKDF2-SHA256"
+
+	// encryptionNonceLength represents the required length of an AES-GCM nonce in bytes
+	encryptionNonceLength = 12
+
+	// encryptionTagLength represents the length of an AES-GCM authentication tag every ciphertext ends with
+	encryptionTagLength = 16
+
+	// encryptionSaltMinimumLength represents the minimum length of a key derivation salt in bytes
+	encryptionSaltMinimumLength = 16
+
+	// encryptionIterationsMinimum represents the minimum amount of key derivation iterations
+	encryptionIterationsMinimum = 100000
+
+	// encryptionIterationsMaximum represents the maximum amount of key derivation iterations
+	encryptionIterationsMaximum = 10000000
+)
+
+var (
+	// ErrEncryptionUnsupportedCipher is used when the cipher of an encrypted element is not supported
+	ErrEncryptionUnsupportedCipher = fmt.Errorf("the given cipher is not supported (expected '%s')", EncryptionCipherAES256GCM)
+
+	// ErrEncryptionInvalidNonce is used when the nonce of an encrypted element is no base64-encoded value of the required length
+	ErrEncryptionInvalidNonce = fmt.Errorf("the given nonce has to be %d base64-encoded bytes", encryptionNonceLength)
+
+	// ErrEncryptionInvalidCiphertext is used when the ciphertext of an encrypted element is no base64-encoded value containing an authentication tag
+	ErrEncryptionInvalidCiphertext = fmt.Errorf("the given ciphertext has to be base64-encoded and contain at least the %d byte authentication tag", encryptionTagLength)
+
+	// ErrEncryptionUnsupportedKDF is used when the key derivation function of an encrypted element is not supported
+	ErrEncryptionUnsupportedKDF = fmt.Errorf("the given key derivation function is not supported (expected '%s')", EncryptionKDFPBKDF2SHA256)
+
+	// ErrEncryptionInvalidSalt is used when the key derivation salt of an encrypted element is too short or not base64-encoded
+	ErrEncryptionInvalidSalt = fmt.Errorf("the given key derivation salt has to be at least %d base64-encoded bytes", encryptionSaltMinimumLength)
+
+	// ErrEncryptionInvalidIterations is used when the amount of key derivation iterations of an encrypted element is out of range
+	ErrEncryptionInvalidIterations = fmt.Errorf("the given amount of key derivation iterations has to be between %d and %d", encryptionIterationsMinimum, encryptionIterationsMaximum)
+)
+
+// ValidateEncryption validates the envelope of a client-side encrypted element consisting of its ciphertext and encryption metadata
+func ValidateEncryption(ciphertext string, encryption *shared.Encryption) (errors []error) {
+	// Validate the cipher and its parameters
+	if encryption.Cipher != EncryptionCipherAES256GCM {
+		errors = append(errors, ErrEncryptionUnsupportedCipher)
+	}
+	if nonce, ok := decodeBase64(encryption.Nonce); !ok || len(nonce) != encryptionNonceLength {
+		errors = append(errors, ErrEncryptionInvalidNonce)
+	}
+	if decoded, ok := decodeBase64(ciphertext); !ok || len(decoded) < encryptionTagLength {
+		errors = append(errors, ErrEncryptionInvalidCiphertext)
+	}
+
+	// Validate the optional key derivation parameters
+	if encryption.KDF != nil {
+		if encryption.KDF.Name != EncryptionKDFPBKDF2SHA256 {
+			errors = append(errors, ErrEncryptionUnsupportedKDF)
+		}
+		if salt, ok := decodeBase64(encryption.KDF.Salt); !ok || len(salt) < encryptionSaltMinimumLength {
+			errors = append(errors, ErrEncryptionInvalidSalt)
+		}
+		if encryption.KDF.Iterations < encryptionIterationsMinimum || encryption.KDF.Iterations > encryptionIterationsMaximum {
+			errors = append(errors, ErrEncryptionInvalidIterations)
+		}
+	}
+	return
+}
+
+// decodeBase64 decodes a value using either the standard or the URL-safe base64 alphabet, with or without padding
+func decodeBase64(value string) ([]byte, bool) {
+	value = strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(value), "=")
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	return decoded, err == nil && value != ""
+}
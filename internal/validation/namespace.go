@@ -15,6 +15,9 @@ var (
 
 	// namespaceIDAllowedCharacters contains all allowed characters for a namespace ID
 	namespaceIDAllowedCharacters = "abcdefghijklmnopqrstuvwxyz0123456789_"
+
+	// reservedNamespaceIDs contains the namespace IDs colliding with the health endpoints of the gateway
+	reservedNamespaceIDs = []string{"healthz", "readyz"}
 )
 
 var (
@@ -26,6 +29,9 @@ var (
 
 	// ErrNamespaceIDTooShort is used when a namespace ID contains at least one illegal character
 	ErrNamespaceIDContainsIllegalCharacter = fmt.Errorf("the given namespace ID contains an illegal character (allowed are '%s')", namespaceIDAllowedCharacters)
+
+	// ErrNamespaceIDReserved is used when a namespace ID collides with a path reserved by the gateway
+	ErrNamespaceIDReserved = fmt.Errorf("the given namespace ID is reserved (reserved are '%s')", strings.Join(reservedNamespaceIDs, "', '"))
 )
 
 // ValidateNamespaceID validates a given namespace ID
@@ -38,6 +44,14 @@ func ValidateNamespaceID(id string) (errors []error) {
 		errors = append(errors, ErrNamespaceIDTooLong)
 	}
 
+	// Validate that the ID does not shadow a reserved gateway path
+	for _, reserved := range reservedNamespaceIDs {
+		if id == reserved {
+			errors = append(errors, ErrNamespaceIDReserved)
+			break
+		}
+	}
+
 	// Validate the strings characters
 	for _, char := range []rune(id) {
 		if !strings.ContainsRune(namespaceIDAllowedCharacters, char) {
@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// tokenNameMaximumLength represents the maximum length of a namespace token name
+	tokenNameMaximumLength = 64
+)
+
+var (
+	// ErrTokenNameEmpty is used when a namespace token name is empty
+	ErrTokenNameEmpty = fmt.Errorf("the given token name is empty")
+
+	// ErrTokenNameTooLong is used when a namespace token name is too long
+	ErrTokenNameTooLong = fmt.Errorf("the given token name is too long (maximum is %d)", tokenNameMaximumLength)
+
+	// ErrTokenScopesEmpty is used when a namespace token was granted no scope
+	ErrTokenScopesEmpty = fmt.Errorf("the given token has to be granted at least one scope")
+
+	// ErrTokenScopeUnknown is used when a namespace token was granted an unknown scope
+	ErrTokenScopeUnknown = fmt.Errorf("the given token scopes contain an unknown one (known are '%s')", joinScopes(shared.TokenScopes))
+)
+
+// ValidateTokenName validates the name of a namespace token
+func ValidateTokenName(name string) (errors []error) {
+	if strings.TrimSpace(name) == "" {
+		errors = append(errors, ErrTokenNameEmpty)
+	} else if utf8.RuneCountInString(name) > tokenNameMaximumLength {
+		errors = append(errors, ErrTokenNameTooLong)
+	}
+	return
+}
+
+// ValidateTokenScopes validates the scopes granted to a namespace token
+func ValidateTokenScopes(scopes []shared.TokenScope) (errors []error) {
+	if len(scopes) == 0 {
+		errors = append(errors, ErrTokenScopesEmpty)
+	}
+	for _, scope := range scopes {
+		known := false
+		for _, knownScope := range shared.TokenScopes {
+			if scope == knownScope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errors = append(errors, ErrTokenScopeUnknown)
+			break
+		}
+	}
+	return
+}
+
+// joinScopes joins the given scopes for an error message
+func joinScopes(scopes []shared.TokenScope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, "', '")
+}
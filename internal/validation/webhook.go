@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/x0tf/server/internal/shared"
+	"net/url"
+	"strings"
+)
+
+var (
+	// webhookURLMaximumLength represents the maximum length of a webhook URL
+	webhookURLMaximumLength = 2048
+
+	// webhookSecretMinimumLength represents the minimum length of a webhook secret chosen by a user
+	webhookSecretMinimumLength = 16
+
+	// webhookSecretMaximumLength represents the maximum length of a webhook secret chosen by a user
+	webhookSecretMaximumLength = 100
+)
+
+var (
+	// ErrWebhookURLInvalid is used when a webhook URL is no absolute HTTP(S) URL
+	ErrWebhookURLInvalid = fmt.Errorf("the given webhook URL has to be an absolute http or https URL")
+
+	// ErrWebhookURLTooLong is used when a webhook URL is too long
+	ErrWebhookURLTooLong = fmt.Errorf("the given webhook URL is too long (maximum is %d)", webhookURLMaximumLength)
+
+	// ErrWebhookEventsEmpty is used when a webhook is subscribed to no event
+	ErrWebhookEventsEmpty = fmt.Errorf("the given webhook has to be subscribed to at least one event")
+
+	// ErrWebhookEventUnknown is used when a webhook is subscribed to an unknown event
+	ErrWebhookEventUnknown = fmt.Errorf("the given webhook events contain an unknown one (known are '%s')", joinEvents(shared.WebhookEvents))
+
+	// ErrWebhookSecretLength is used when a webhook secret is too short or too long
+	ErrWebhookSecretLength = fmt.Errorf("the given webhook secret has to be between %d and %d characters long", webhookSecretMinimumLength, webhookSecretMaximumLength)
+)
+
+// ValidateWebhookURL validates the URL a webhook gets delivered to
+func ValidateWebhookURL(rawURL string) (errors []error) {
+	if len(rawURL) > webhookURLMaximumLength {
+		errors = append(errors, ErrWebhookURLTooLong)
+		return
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		errors = append(errors, ErrWebhookURLInvalid)
+	}
+	return
+}
+
+// ValidateWebhookEvents validates the events a webhook is subscribed to
+func ValidateWebhookEvents(events []shared.WebhookEvent) (errors []error) {
+	if len(events) == 0 {
+		errors = append(errors, ErrWebhookEventsEmpty)
+	}
+	for _, event := range events {
+		known := false
+		for _, knownEvent := range shared.WebhookEvents {
+			if event == knownEvent {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errors = append(errors, ErrWebhookEventUnknown)
+			break
+		}
+	}
+	return
+}
+
+// ValidateWebhookSecret validates a webhook secret chosen by a user
+func ValidateWebhookSecret(secret string) (errors []error) {
+	if len(secret) < webhookSecretMinimumLength || len(secret) > webhookSecretMaximumLength {
+		errors = append(errors, ErrWebhookSecretLength)
+	}
+	return
+}
+
+// joinEvents joins the given webhook events for an error message
+func joinEvents(events []shared.WebhookEvent) string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return strings.Join(names, "', '")
+}
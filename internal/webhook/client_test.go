@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		allowed bool
+	}{
+		{name: "private networks forbidden", allowed: false},
+		{name: "private networks allowed", allowed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := NewClient(5*time.Second, test.allowed).Get(server.URL)
+			if test.allowed {
+				if err != nil {
+					t.Fatalf("could not reach the loopback address: %v", err)
+				}
+				response.Body.Close()
+				return
+			}
+			if err == nil {
+				response.Body.Close()
+				t.Fatal("reached the loopback address")
+			}
+			if !errors.Is(err, ErrPrivateAddress) {
+				t.Errorf("failed with %v, expected %v", err, ErrPrivateAddress)
+			}
+		})
+	}
+}
+
+func TestPrivate(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{ip: "127.0.0.1", private: true},
+		{ip: "10.1.2.3", private: true},
+		{ip: "172.16.0.1", private: true},
+		{ip: "192.168.1.1", private: true},
+		{ip: "169.254.169.254", private: true},
+		{ip: "0.0.0.0", private: true},
+		{ip: "224.0.0.1", private: true},
+		{ip: "::1", private: true},
+		{ip: "fd00::1", private: true},
+		{ip: "fe80::1", private: true},
+		{ip: "::ffff:127.0.0.1", private: true},
+		{ip: "1.1.1.1", private: false},
+		{ip: "2606:4700:4700::1111", private: false},
+	}
+	for _, test := range tests {
+		if private := private(net.ParseIP(test.ip)); private != test.private {
+			t.Errorf("considered %s private: %t, expected %t", test.ip, private, test.private)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"bytes"
+	log "github.com/sirupsen/logrus"
+	"github.com/x0tf/server/internal/metrics"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/static"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// claimBatchSize is the maximum amount of deliveries a dispatcher claims at once
+	claimBatchSize = 32
+
+	// claimConcurrency is the maximum amount of deliveries a dispatcher attempts concurrently
+	claimConcurrency = 8
+
+	// claimLease is the time a claimed delivery is hidden from other dispatchers; it has to exceed the time a whole batch may take
+	claimLease = 5 * time.Minute
+
+	// maxRetryDelay caps the exponentially growing delay between two attempts of a delivery
+	maxRetryDelay = 6 * time.Hour
+
+	// deliveryRetention is the time finished deliveries are kept in the delivery log
+	deliveryRetention = 7 * 24 * time.Hour
+
+	// pruneInterval is the interval finished deliveries older than the retention get pruned in
+	pruneInterval = time.Hour
+
+	// responseBodyLimit is the maximum amount of bytes read out of a response body to be able to reuse the connection
+	responseBodyLimit = 64 * 1024
+
+	// errorLimit is the maximum length of the error message stored with a failed attempt
+	errorLimit = 255
+)
+
+// Dispatcher represents the background worker attempting the due deliveries of the persistent delivery queue;
+// failed attempts are retried with an exponentially growing delay until the maximum amount of attempts is reached
+type Dispatcher struct {
+	stop        chan struct{}
+	done        chan struct{}
+	lastPrune   time.Time
+	Interval    time.Duration
+	MaxAttempts int
+	RetryDelay  time.Duration
+	Client      *http.Client
+	Webhooks    shared.WebhookService
+}
+
+// Start starts the dispatcher in a background goroutine
+func (dispatcher *Dispatcher) Start() {
+	dispatcher.stop = make(chan struct{})
+	dispatcher.done = make(chan struct{})
+	go dispatcher.run()
+	log.WithField("interval", dispatcher.Interval).Info("Started the webhook dispatcher")
+}
+
+// Stop stops the dispatcher and waits for the current attempts to finish
+func (dispatcher *Dispatcher) Stop() {
+	log.Info("Stopping the webhook dispatcher")
+	close(dispatcher.stop)
+	<-dispatcher.done
+}
+
+// run dispatches the due deliveries every interval and prunes old ones every prune interval until the dispatcher gets stopped
+func (dispatcher *Dispatcher) run() {
+	defer close(dispatcher.done)
+
+	ticker := time.NewTicker(dispatcher.Interval)
+	defer ticker.Stop()
+
+	for {
+		dispatcher.dispatch()
+		if time.Since(dispatcher.lastPrune) >= pruneInterval {
+			dispatcher.prune()
+			dispatcher.lastPrune = time.Now()
+		}
+		select {
+		case <-dispatcher.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch claims and attempts due deliveries in batches until none is due anymore or the dispatcher gets stopped
+func (dispatcher *Dispatcher) dispatch() {
+	for {
+		deliveries, err := dispatcher.Webhooks.Claim(claimBatchSize, claimLease)
+		if err != nil {
+			log.WithError(err).Error("Could not claim due webhook deliveries")
+			return
+		}
+
+		// Attempt the claimed deliveries concurrently, but limit the amount of simultaneous requests
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, claimConcurrency)
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(delivery *shared.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				dispatcher.attempt(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < claimBatchSize {
+			return
+		}
+		select {
+		case <-dispatcher.stop:
+			return
+		default:
+		}
+	}
+}
+
+// attempt sends a delivery to its webhook and stores the outcome
+func (dispatcher *Dispatcher) attempt(delivery *shared.WebhookDelivery) {
+	logger := log.WithFields(log.Fields{
+		"namespace": delivery.Namespace,
+		"webhook":   delivery.Webhook,
+		"delivery":  delivery.ID,
+	})
+
+	// Deliveries of deleted webhooks get deleted along with them, so there is nothing left to record
+	webhook, err := dispatcher.Webhooks.Webhook(delivery.Namespace, delivery.Webhook)
+	if err != nil {
+		logger.WithError(err).Error("Could not look up the webhook of a delivery")
+		return
+	}
+	if webhook == nil {
+		return
+	}
+
+	// Give up on deliveries of deactivated webhooks right away; they may be redelivered once it got activated again
+	if !webhook.Active {
+		delivery.Status = shared.DeliveryFailed
+		delivery.Error = "the webhook is deactivated"
+		delivery.NextAttemptAt = nil
+		if err := dispatcher.Webhooks.UpdateDelivery(delivery); err != nil {
+			logger.WithError(err).Error("Could not store the outcome of a webhook delivery")
+		}
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.Error = ""
+	delivery.ResponseStatus, err = dispatcher.send(webhook, delivery)
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if delivery.ResponseStatus < 200 || delivery.ResponseStatus > 299 {
+		delivery.Error = "the receiver responded with status " + strconv.Itoa(delivery.ResponseStatus)
+	}
+	if len(delivery.Error) > errorLimit {
+		delivery.Error = delivery.Error[:errorLimit]
+	}
+
+	// Schedule another attempt using an exponential backoff unless the delivery succeeded or ran out of attempts
+	switch {
+	case delivery.Error == "":
+		delivery.Status = shared.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= dispatcher.MaxAttempts:
+		delivery.Status = shared.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		logger.WithField("error", delivery.Error).Warn("Gave up on a webhook delivery")
+	default:
+		delivery.Status = shared.DeliveryPending
+		next := now.Add(dispatcher.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	metrics.CountWebhookAttempt(string(delivery.Status))
+
+	if err := dispatcher.Webhooks.UpdateDelivery(delivery); err != nil {
+		logger.WithError(err).Error("Could not store the outcome of a webhook delivery")
+	}
+}
+
+// send signs and sends the payload of a delivery to the URL of its webhook and returns the response status
+func (dispatcher *Dispatcher) send(webhook *shared.Webhook, delivery *shared.WebhookDelivery) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "x0-webhooks/"+static.ApplicationVersion)
+	request.Header.Set(HeaderEvent, string(delivery.Event))
+	request.Header.Set(HeaderDelivery, delivery.ID)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := dispatcher.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, responseBodyLimit))
+	return response.StatusCode, nil
+}
+
+// backoff calculates the delay before the next attempt of a delivery which was attempted the given amount of times
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	delay := dispatcher.RetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// prune deletes finished deliveries which are older than the retention
+func (dispatcher *Dispatcher) prune() {
+	pruned, err := dispatcher.Webhooks.PruneDeliveries(time.Now().Add(-deliveryRetention))
+	if err != nil {
+		log.WithError(err).Error("Could not prune old webhook deliveries")
+		return
+	}
+	if pruned > 0 {
+		log.WithField("amount", pruned).Info("Pruned old webhook deliveries")
+	}
+}
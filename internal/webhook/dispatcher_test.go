@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"github.com/x0tf/server/internal/database/memory"
+	"github.com/x0tf/server/internal/shared"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSecret is the secret of the webhooks the tests deliver to
+const testSecret = "secret"
+
+// receiver represents a webhook receiver which verifies the signatures of the requests it receives
+// and responds with the given statuses in order, repeating the last one
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (receiver *receiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		receiver.t.Error(err)
+	}
+	timestamp, err := strconv.ParseInt(request.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		receiver.t.Errorf("received an illegal timestamp: %v", err)
+	}
+	if signature := request.Header.Get(HeaderSignature); signature != Sign(testSecret, timestamp, body) {
+		receiver.t.Errorf("received the signature %q which does not match the body", signature)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.requests = append(receiver.requests, request)
+	receiver.bodies = append(receiver.bodies, body)
+	status := receiver.statuses[len(receiver.statuses)-1]
+	if len(receiver.requests) <= len(receiver.statuses) {
+		status = receiver.statuses[len(receiver.requests)-1]
+	}
+	writer.WriteHeader(status)
+}
+
+// received returns the amount of requests the receiver received
+func (receiver *receiver) received() int {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return len(receiver.requests)
+}
+
+// setupDispatcher starts a receiver responding with the given statuses and creates a dispatcher delivering to it
+// along with a delivery which is due right away
+func setupDispatcher(t *testing.T, statuses ...int) (*Dispatcher, *receiver, *shared.WebhookDelivery) {
+	receiver := &receiver{t: t, statuses: statuses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	webhooks := memory.NewStore().Services().Webhooks
+	hook := &shared.Webhook{
+		Namespace: "ns",
+		ID:        "hook",
+		URL:       server.URL,
+		Events:    []shared.WebhookEvent{shared.WebhookElementCreated},
+		Secret:    testSecret,
+		Active:    true,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := webhooks.CreateOrReplace(hook); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := NewPayload(hook.Namespace, shared.WebhookElementCreated, &Element{Key: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := NewDelivery(hook, shared.WebhookElementCreated, payload)
+	if err := webhooks.Enqueue([]*shared.WebhookDelivery{delivery}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &Dispatcher{
+		MaxAttempts: 3,
+		RetryDelay:  100 * time.Millisecond,
+		Client:      NewClient(5*time.Second, true),
+		Webhooks:    webhooks,
+	}
+	return dispatcher, receiver, delivery
+}
+
+// storedDelivery retrieves the current state of the given delivery
+func storedDelivery(t *testing.T, dispatcher *Dispatcher, delivery *shared.WebhookDelivery) *shared.WebhookDelivery {
+	stored, err := dispatcher.Webhooks.Delivery(delivery.Namespace, delivery.Webhook, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil {
+		t.Fatal("the delivery does not exist anymore")
+	}
+	return stored
+}
+
+func TestDispatcherDelivery(t *testing.T) {
+	dispatcher, receiver, delivery := setupDispatcher(t, http.StatusNoContent)
+	dispatcher.dispatch()
+
+	if received := receiver.received(); received != 1 {
+		t.Fatalf("received %d requests, expected 1", received)
+	}
+	request := receiver.requests[0]
+	if event := request.Header.Get(HeaderEvent); event != string(shared.WebhookElementCreated) {
+		t.Errorf("received the event %q, expected %q", event, shared.WebhookElementCreated)
+	}
+	if id := request.Header.Get(HeaderDelivery); id != delivery.ID {
+		t.Errorf("received the delivery ID %q, expected %q", id, delivery.ID)
+	}
+	if string(receiver.bodies[0]) != string(delivery.Payload) {
+		t.Errorf("received the body %s, expected %s", receiver.bodies[0], delivery.Payload)
+	}
+
+	stored := storedDelivery(t, dispatcher, delivery)
+	if stored.Status != shared.DeliverySucceeded || stored.Attempts != 1 || stored.ResponseStatus != http.StatusNoContent {
+		t.Errorf("stored the delivery as %s after %d attempts with status %d, expected a single successful one",
+			stored.Status, stored.Attempts, stored.ResponseStatus)
+	}
+	if stored.NextAttemptAt != nil {
+		t.Error("scheduled another attempt of a successful delivery")
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	dispatcher, receiver, delivery := setupDispatcher(t, http.StatusInternalServerError, http.StatusOK)
+	dispatcher.dispatch()
+
+	// The failed attempt schedules the next one after the retry delay
+	stored := storedDelivery(t, dispatcher, delivery)
+	if stored.Status != shared.DeliveryPending || stored.Attempts != 1 || stored.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("stored the delivery as %s after %d attempts with status %d, expected a pending one after a failed attempt",
+			stored.Status, stored.Attempts, stored.ResponseStatus)
+	}
+	if stored.Error == "" {
+		t.Error("did not store the error of the failed attempt")
+	}
+	if stored.NextAttemptAt == nil || !stored.NextAttemptAt.Equal(stored.LastAttemptAt.Add(dispatcher.RetryDelay)) {
+		t.Errorf("scheduled the next attempt at %v, expected it one retry delay after %v", stored.NextAttemptAt, stored.LastAttemptAt)
+	}
+
+	// The delivery is not attempted again before it is due
+	dispatcher.dispatch()
+	if received := receiver.received(); received != 1 {
+		t.Fatalf("received %d requests before the next attempt was due, expected 1", received)
+	}
+
+	time.Sleep(time.Until(*stored.NextAttemptAt))
+	dispatcher.dispatch()
+	if received := receiver.received(); received != 2 {
+		t.Fatalf("received %d requests after the next attempt was due, expected 2", received)
+	}
+	stored = storedDelivery(t, dispatcher, delivery)
+	if stored.Status != shared.DeliverySucceeded || stored.Attempts != 2 || stored.Error != "" {
+		t.Errorf("stored the delivery as %s after %d attempts with error %q, expected a successful retry",
+			stored.Status, stored.Attempts, stored.Error)
+	}
+}
+
+func TestDispatcherGiveUp(t *testing.T) {
+	dispatcher, receiver, delivery := setupDispatcher(t, http.StatusInternalServerError)
+	dispatcher.MaxAttempts = 2
+	dispatcher.RetryDelay = time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		dispatcher.dispatch()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if received := receiver.received(); received != 2 {
+		t.Errorf("received %d requests, expected 2", received)
+	}
+	stored := storedDelivery(t, dispatcher, delivery)
+	if stored.Status != shared.DeliveryFailed || stored.NextAttemptAt != nil {
+		t.Errorf("stored the delivery as %s, expected it to be given up on", stored.Status)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	dispatcher := &Dispatcher{RetryDelay: time.Minute}
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{attempts: 1, delay: time.Minute},
+		{attempts: 2, delay: 2 * time.Minute},
+		{attempts: 3, delay: 4 * time.Minute},
+		{attempts: 20, delay: maxRetryDelay},
+	}
+	for _, test := range tests {
+		if delay := dispatcher.backoff(test.attempts); delay != test.delay {
+			t.Errorf("delayed the attempt after %d attempts by %v, expected %v", test.attempts, delay, test.delay)
+		}
+	}
+}
+
+func TestDispatcherLeaseExpiry(t *testing.T) {
+	dispatcher, receiver, delivery := setupDispatcher(t, http.StatusOK)
+
+	// Claim the delivery as a dispatcher would which crashes before attempting it
+	lease := 100 * time.Millisecond
+	claimed, err := dispatcher.Webhooks.Claim(claimBatchSize, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d deliveries, expected 1", len(claimed))
+	}
+
+	// The delivery stays hidden from other dispatchers while it is leased
+	dispatcher.dispatch()
+	if received := receiver.received(); received != 0 {
+		t.Fatalf("received %d requests while the delivery was leased, expected none", received)
+	}
+
+	// Another dispatcher re-claims the delivery once the lease expired
+	time.Sleep(time.Until(*claimed[0].NextAttemptAt))
+	dispatcher.dispatch()
+	if received := receiver.received(); received != 1 {
+		t.Fatalf("received %d requests after the lease expired, expected 1", received)
+	}
+	stored := storedDelivery(t, dispatcher, delivery)
+	if stored.Status != shared.DeliverySucceeded || stored.Attempts != 1 {
+		t.Errorf("stored the delivery as %s after %d attempts, expected a single successful one", stored.Status, stored.Attempts)
+	}
+}
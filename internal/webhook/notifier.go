@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/x0tf/server/internal/shared"
+	"github.com/x0tf/server/internal/utils"
+	"time"
+)
+
+// Payload represents the JSON body every delivery of an event sends; its ID identifies the event, so it stays the same
+// across the deliveries to multiple webhooks and redeliveries
+type Payload struct {
+	ID        string              `json:"id"`
+	Event     shared.WebhookEvent `json:"event"`
+	Namespace string              `json:"namespace"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      interface{}         `json:"data"`
+}
+
+// Element represents the data of element events; the content of the element is left out as it may be large or confidential
+type Element struct {
+	Key            string     `json:"key"`
+	Type           string     `json:"type"`
+	ContentType    string     `json:"content_type,omitempty"`
+	Filename       string     `json:"filename,omitempty"`
+	Size           int64      `json:"size,omitempty"`
+	Protected      bool       `json:"protected"`
+	Encrypted      bool       `json:"encrypted"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RemainingViews *int       `json:"remaining_views,omitempty"`
+	Revision       int        `json:"revision"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Access represents the data of the element.first_accessed event
+type Access struct {
+	Key        string    `json:"key"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// Namespace represents the data of namespace events
+type Namespace struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+}
+
+// ElementData creates the data of an element event
+func ElementData(element *shared.Element) *Element {
+	return &Element{
+		Key:            element.Key,
+		Type:           element.Type.String(),
+		ContentType:    element.ContentType,
+		Filename:       element.Filename,
+		Size:           element.Size,
+		Protected:      element.Protected(),
+		Encrypted:      element.Encryption != nil,
+		ExpiresAt:      element.ExpiresAt,
+		RemainingViews: element.RemainingViews,
+		Revision:       element.Revision,
+		CreatedAt:      element.CreatedAt,
+	}
+}
+
+// Notifier enqueues deliveries of events to the webhooks subscribed to them; a nil notifier drops every event
+type Notifier struct {
+	Webhooks shared.WebhookService
+}
+
+// Notify enqueues a delivery of an event to every webhook of the given namespace which is subscribed to it
+func (notifier *Notifier) Notify(namespace string, event shared.WebhookEvent, data interface{}) error {
+	if notifier == nil {
+		return nil
+	}
+	webhooks, err := notifier.Webhooks.Webhooks(namespace)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	var deliveries []*shared.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = NewPayload(namespace, event, data); err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, NewDelivery(webhook, event, payload))
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return notifier.Webhooks.Enqueue(deliveries)
+}
+
+// NewPayload encodes the payload of a new event
+func NewPayload(namespace string, event shared.WebhookEvent, data interface{}) ([]byte, error) {
+	return json.Marshal(&Payload{
+		ID:        utils.GenerateTokenID(),
+		Event:     event,
+		Namespace: namespace,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Data:      data,
+	})
+}
+
+// NewDelivery creates a pending delivery of an encoded event payload to a webhook which is due right away
+func NewDelivery(webhook *shared.Webhook, event shared.WebhookEvent, payload []byte) *shared.WebhookDelivery {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return &shared.WebhookDelivery{
+		Namespace:     webhook.Namespace,
+		Webhook:       webhook.ID,
+		ID:            utils.GenerateTokenID(),
+		Event:         event,
+		Payload:       payload,
+		Status:        shared.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	// HeaderEvent is the request header containing the event of a delivery
+	HeaderEvent = "X-X0-Event"
+
+	// HeaderDelivery is the request header containing the ID of a delivery
+	HeaderDelivery = "X-X0-Delivery"
+
+	// HeaderTimestamp is the request header containing the Unix time an attempt of a delivery was signed at
+	HeaderTimestamp = "X-X0-Timestamp"
+
+	// HeaderSignature is the request header containing the signature of an attempt of a delivery ('sha256=<hex>')
+	HeaderSignature = "X-X0-Signature"
+)
+
+// Sign computes the signature of a request body sent at the given Unix time: the hex-encoded HMAC-SHA256 of
+// '<timestamp>.<body>' keyed with the secret of the webhook; covering the timestamp lets receivers reject replays
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}